@@ -1,16 +1,26 @@
 package cli
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Broderick-Westrope/flower/internal/flowtime"
+	"github.com/Broderick-Westrope/flower/internal/ical"
+	"github.com/Broderick-Westrope/flower/internal/paginate"
 	"github.com/Broderick-Westrope/flower/internal/storage"
+	"github.com/charmbracelet/x/term"
 )
 
 // Context holds shared dependencies for CLI commands.
 type Context struct {
+	Profile     string // name of the selected profile
+	Output      string // output format of read commands: OutputText, OutputJSON, OutputCSV or OutputTSV
 	Store       storage.Store
 	RunTUI      func(store storage.Store) error // injected by main.go to avoid circular import
 	LocateStore func() (string, error)          // returns state file path
@@ -18,22 +28,122 @@ type Context struct {
 
 // CLI is the top-level Kong command structure.
 type CLI struct {
-	Start  StartCmd  `cmd:"" help:"Start flow, creating a new session if needed."`
-	Break  BreakCmd  `cmd:"" help:"End flow, start break."`
-	Resume ResumeCmd `cmd:"" help:"End break, resume the current or previous session."`
-	Stop   StopCmd   `cmd:"" help:"End current session."`
-	Cancel CancelCmd `cmd:"" help:"Cancel the current session without recording it."`
-	Status StatusCmd `cmd:"" help:"Show current state."`
-	Log    LogCmd    `cmd:"" help:"Show recent sessions."`
-	Delete DeleteCmd `cmd:"" help:"Delete a completed session by index."`
-	Clear  ClearCmd  `cmd:"" help:"Delete all completed sessions."`
-	Locate LocateCmd `cmd:"" help:"Show the state file path."`
+	Store     string `enum:"json,sqlite" default:"json" env:"FLOWER_STORE" help:"Storage backend (${enum})."`
+	Profile   string `default:"default" env:"FLOWER_PROFILE" help:"Profile to use; each profile keeps a separate history."`
+	StateFile string `name:"state-file" type:"path" env:"FLOWER_STATE_FILE" help:"Use this state file instead of the profile's."`
+	Output    string `short:"o" enum:"text,json,csv,tsv" default:"text" env:"FLOWER_OUTPUT" help:"Output format of status, log, report, trash and locate (${enum})."`
+
+	TUI       TUICmd       `cmd:"" default:"1" hidden:"" help:"Launch the interactive TUI."`
+	Start     StartCmd     `cmd:"" help:"Start flow, creating a new session if needed."`
+	Break     BreakCmd     `cmd:"" help:"End flow, start break."`
+	Resume    ResumeCmd    `cmd:"" help:"End break, resume the current or previous session."`
+	Stop      StopCmd      `cmd:"" help:"End current session."`
+	Switch    SwitchCmd    `cmd:"" help:"End current session and start a new one with another task, without a break."`
+	Note      NoteCmd      `cmd:"" help:"Attach a note to the current session."`
+	Interrupt InterruptCmd `cmd:"" help:"Record an interruption of the current flow."`
+	Cancel    CancelCmd    `cmd:"" help:"Cancel the current session without recording it."`
+	Status    StatusCmd    `cmd:"" help:"Show current state."`
+	Log       LogCmd       `cmd:"" help:"Show recent sessions."`
+	Report    ReportCmd    `cmd:"" help:"Show flow and break totals by day, week, month or task."`
+	Export    ExportCmd    `cmd:"" help:"Export completed sessions for other apps, such as calendars."`
+	Show      ShowCmd      `cmd:"" help:"Show a completed session and its flow and break intervals."`
+	Add       AddCmd       `cmd:"" help:"Record a session that was not timed."`
+	Edit      EditCmd      `cmd:"" help:"Change the task, durations or completion time of a completed session."`
+	Delete    DeleteCmd    `cmd:"" help:"Delete a completed session by ID or index."`
+	Clear     ClearCmd     `cmd:"" help:"Delete all completed sessions."`
+	Trash     TrashCmd     `cmd:"" help:"Show deleted sessions."`
+	Restore   RestoreCmd   `cmd:"" help:"Restore deleted sessions."`
+	Purge     PurgeCmd     `cmd:"" help:"Permanently remove deleted sessions."`
+	Locate    LocateCmd    `cmd:"" help:"Show the state file path."`
+	History   HistoryCmd   `cmd:"" help:"Show the log of state transitions."`
+	Undo      UndoCmd      `cmd:"" help:"Undo the last state transition."`
+	Redo      RedoCmd      `cmd:"" help:"Redo the last undone state transition."`
+	Backup    BackupCmd    `cmd:"" help:"List, restore and prune state backups (JSON store only)."`
+	Profiles  ProfileCmd   `cmd:"" name:"profile" help:"Work with profiles."`
+	Doctor    DoctorCmd    `cmd:"" help:"Check the state for problems and optionally repair them."`
+}
+
+// TimeFlags let a transition be recorded as having happened earlier than now.
+type TimeFlags struct {
+	At  string `xor:"at" help:"When it happened, e.g. \"14:30\", \"yesterday 17:00\" or \"10m ago\"."`
+	Ago string `xor:"at" help:"How long ago it happened, e.g. \"10m\"."`
+}
+
+// time returns the time given by the flags and whether one was given.
+func (f TimeFlags) time(now time.Time) (time.Time, bool, error) {
+	switch {
+	case f.At != "":
+		at, err := flowtime.ParseTime(f.At, now)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("parsing --at: %w", err)
+		}
+		return at, true, nil
+	case f.Ago != "":
+		ago, err := flowtime.ParseDuration(f.Ago)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("parsing --ago: %w", err)
+		}
+		return now.Add(-ago), true, nil
+	}
+	return now, false, nil
+}
+
+// FilterFlags select the completed sessions a command works on.
+type FilterFlags struct {
+	Since   string   `help:"Only include sessions that started at or after this time, e.g. \"2025-06-01\", \"yesterday\", \"last week\" or \"3d\"."`
+	Until   string   `help:"Only include sessions that started before this time."`
+	Task    string   `help:"Only include sessions whose task contains this text, ignoring case, or matches a /regex/."`
+	MinFlow string   `name:"min-flow" help:"Only include sessions with at least this much flow, e.g. \"25m\"."`
+	MaxFlow string   `name:"max-flow" help:"Only include sessions with at most this much flow."`
+	Project string   `help:"Only include sessions in this project."`
+	Tag     []string `help:"Only include sessions with this tag (repeatable; all must match)."`
+}
+
+// filter returns the session filter given by the flags.
+func (f FilterFlags) filter(now time.Time) (flowtime.SessionFilter, error) {
+	filter := flowtime.SessionFilter{Project: f.Project, Tags: f.Tag}
+	var err error
+	if f.Since != "" {
+		if filter.Since, err = flowtime.ParseTime(f.Since, now); err != nil {
+			return filter, fmt.Errorf("parsing --since: %w", err)
+		}
+	}
+	if f.Until != "" {
+		if filter.Until, err = flowtime.ParseTime(f.Until, now); err != nil {
+			return filter, fmt.Errorf("parsing --until: %w", err)
+		}
+	}
+	if f.Task != "" {
+		if filter.Task, err = flowtime.TaskPattern(f.Task); err != nil {
+			return filter, fmt.Errorf("parsing --task: %w", err)
+		}
+	}
+	if f.MinFlow != "" {
+		if filter.MinFlow, err = flowtime.ParseDuration(f.MinFlow); err != nil {
+			return filter, fmt.Errorf("parsing --min-flow: %w", err)
+		}
+	}
+	if f.MaxFlow != "" {
+		if filter.MaxFlow, err = flowtime.ParseDuration(f.MaxFlow); err != nil {
+			return filter, fmt.Errorf("parsing --max-flow: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// TUICmd launches the interactive TUI. It runs when no command is given.
+type TUICmd struct{}
+
+func (cmd *TUICmd) Run(ctx *Context) error {
+	return ctx.RunTUI(ctx.Store)
 }
 
 // StartCmd begins a new flow session.
 type StartCmd struct {
 	Task string `arg:"" help:"Task description"`
 
+	TimeFlags `embed:""`
+
 	Detach bool `short:"d"`
 }
 
@@ -43,7 +153,16 @@ func (cmd *StartCmd) Run(ctx *Context) error {
 		return fmt.Errorf("loading state: %w", err)
 	}
 
-	if err := state.StartSession(cmd.Task); err != nil {
+	at, backdated, err := cmd.time(time.Now())
+	if err != nil {
+		return err
+	}
+	if backdated {
+		err = state.StartSessionAt(cmd.Task, at)
+	} else {
+		err = state.StartSession(cmd.Task)
+	}
+	if err != nil {
 		return fmt.Errorf("starting session: %w", err)
 	}
 
@@ -52,7 +171,12 @@ func (cmd *StartCmd) Run(ctx *Context) error {
 	}
 
 	if cmd.Detach {
-		fmt.Printf("Started: %s at %s\n", cmd.Task, state.CurrentSession.StartTime.Format("15:04"))
+		session := state.CurrentSession
+		task := session.Task
+		if labels := flowtime.FormatLabels(session.Project, session.Tags); labels != "" {
+			task += " " + labels
+		}
+		fmt.Printf("Started: %s at %s\n", task, session.StartTime.Format("15:04"))
 		return nil
 	}
 
@@ -61,6 +185,8 @@ func (cmd *StartCmd) Run(ctx *Context) error {
 
 // BreakCmd ends the current flow and starts a break.
 type BreakCmd struct {
+	TimeFlags `embed:""`
+
 	Detach bool `short:"d"`
 }
 
@@ -70,7 +196,16 @@ func (cmd *BreakCmd) Run(ctx *Context) error {
 		return fmt.Errorf("loading state: %w", err)
 	}
 
-	if err := state.TakeBreak(); err != nil {
+	at, backdated, err := cmd.time(time.Now())
+	if err != nil {
+		return err
+	}
+	if backdated {
+		err = state.TakeBreakAt(at)
+	} else {
+		err = state.TakeBreak()
+	}
+	if err != nil {
 		return fmt.Errorf("taking break: %w", err)
 	}
 
@@ -79,16 +214,22 @@ func (cmd *BreakCmd) Run(ctx *Context) error {
 	}
 
 	if cmd.Detach {
-		fmt.Printf("Flow ended. Starting %s break.\n",
-			flowtime.FormatDuration(state.CurrentBreak.SuggestedDuration))
+		kind := "break"
+		if state.OnLongBreak() {
+			kind = "long break"
+		}
+		fmt.Printf("Flow ended. Starting %s %s.\n",
+			flowtime.FormatDuration(state.CurrentBreak.SuggestedDuration), kind)
 		return nil
 	}
 
 	return ctx.RunTUI(ctx.Store)
 }
 
-// ResumeCmd ends a break and resumes the current or previous session.
+// ResumeCmd ends a break and continues the current session, or resumes the previous one.
 type ResumeCmd struct {
+	TimeFlags `embed:""`
+
 	Detach bool `short:"d"`
 }
 
@@ -106,7 +247,16 @@ func (cmd *ResumeCmd) Run(ctx *Context) error {
 		return ctx.RunTUI(ctx.Store)
 	}
 
-	resumedCurrent, err := state.Resume()
+	at, backdated, err := cmd.time(time.Now())
+	if err != nil {
+		return err
+	}
+	var resumedCurrent bool
+	if backdated {
+		resumedCurrent, err = state.ResumeAt(at)
+	} else {
+		resumedCurrent, err = state.Resume()
+	}
 	if err != nil {
 		return fmt.Errorf("resuming session: %w", err)
 	}
@@ -128,15 +278,39 @@ func (cmd *ResumeCmd) Run(ctx *Context) error {
 }
 
 // StopCmd ends the current session.
-type StopCmd struct{}
+type StopCmd struct {
+	Note   string `short:"n" xor:"outcome" help:"Outcome to record on the session; \"-\" prompts for it, as happens by default in a terminal."`
+	NoNote bool   `name:"no-note" xor:"outcome" help:"Don't prompt for an outcome."`
+
+	TimeFlags `embed:""`
+}
 
 func (cmd *StopCmd) Run(ctx *Context) error {
 	state, err := ctx.Store.Load()
 	if err != nil {
 		return fmt.Errorf("loading state: %w", err)
 	}
+	if state.CurrentSession == nil {
+		return fmt.Errorf("stopping session: %w", flowtime.ErrNoActiveSession)
+	}
+	at, backdated, err := cmd.time(time.Now())
+	if err != nil {
+		return err
+	}
+
+	note := cmd.Note
+	if note == "-" || (note == "" && !cmd.NoNote && stdinIsTerminal()) {
+		if note, err = prompt("Outcome (optional):"); err != nil {
+			return fmt.Errorf("reading outcome: %w", err)
+		}
+	}
 
-	if _, err := state.Stop(); err != nil {
+	if backdated {
+		_, err = state.StopWithNoteAt(note, at)
+	} else {
+		_, err = state.StopWithNote(note)
+	}
+	if err != nil {
 		return fmt.Errorf("stopping session: %w", err)
 	}
 
@@ -148,8 +322,102 @@ func (cmd *StopCmd) Run(ctx *Context) error {
 	return nil
 }
 
+// SwitchCmd ends the current session and immediately starts another.
+type SwitchCmd struct {
+	Task string `arg:"" help:"Task description for the new session."`
+
+	TimeFlags `embed:""`
+}
+
+func (cmd *SwitchCmd) Run(ctx *Context) error {
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+	at, backdated, err := cmd.time(time.Now())
+	if err != nil {
+		return err
+	}
+
+	var completed *flowtime.CompletedSession
+	if backdated {
+		completed, err = state.SwitchAt(cmd.Task, at)
+	} else {
+		completed, err = state.Switch(cmd.Task)
+	}
+	if err != nil {
+		return fmt.Errorf("switching task: %w", err)
+	}
+
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	fmt.Printf("Ended %q after %s; now flowing on %q.\n",
+		completed.Task, flowtime.FormatDuration(completed.FlowDuration), state.CurrentSession.Task)
+	return nil
+}
+
+// NoteCmd attaches a note to the current session.
+type NoteCmd struct {
+	Text string `arg:"" help:"Note text."`
+}
+
+func (cmd *NoteCmd) Run(ctx *Context) error {
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	if err := state.AddNote(cmd.Text); err != nil {
+		return fmt.Errorf("adding note: %w", err)
+	}
+
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	fmt.Printf("Noted on %q.\n", state.CurrentSession.Task)
+	return nil
+}
+
+// InterruptCmd records an interruption of the current flow.
+type InterruptCmd struct {
+	Reason string `arg:"" optional:"" help:"What interrupted you."`
+
+	Internal bool `short:"i" xor:"kind" help:"The interruption came from within, e.g. the urge to check email."`
+	External bool `short:"e" xor:"kind" help:"The interruption came from someone or something else."`
+}
+
+func (cmd *InterruptCmd) Run(ctx *Context) error {
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	var kind flowtime.InterruptionKind
+	switch {
+	case cmd.Internal:
+		kind = flowtime.InterruptionInternal
+	case cmd.External:
+		kind = flowtime.InterruptionExternal
+	}
+	if err := state.Interrupt(kind, cmd.Reason); err != nil {
+		return fmt.Errorf("recording interruption: %w", err)
+	}
+
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	fmt.Printf("Interruption recorded (%d this session).\n", len(state.CurrentSession.Interruptions))
+	return nil
+}
+
 // StatusCmd shows the current flow state.
-type StatusCmd struct{}
+type StatusCmd struct {
+	Format string `help:"Print the status with a Go template, or a preset for status bars: tmux, waybar, polybar or i3blocks."`
+}
 
 func (cmd *StatusCmd) Run(ctx *Context) error {
 	state, err := ctx.Store.Load()
@@ -157,7 +425,22 @@ func (cmd *StatusCmd) Run(ctx *Context) error {
 		return fmt.Errorf("loading state: %w", err)
 	}
 
-	PrintStatus(state, time.Now())
+	now := time.Now()
+	if cmd.Format != "" {
+		if ctx.Output != OutputText {
+			return errors.New("--format cannot be combined with --output")
+		}
+		tmpl, err := parseStatusFormat(cmd.Format)
+		if err != nil {
+			return err
+		}
+		return printStatusFormat(os.Stdout, tmpl, state, now)
+	}
+	if ctx.Output != OutputText {
+		return writeRecord(os.Stdout, ctx.Output, statusColumns, newStatusRecord(state, now))
+	}
+
+	PrintStatus(state, now)
 	return nil
 }
 
@@ -165,6 +448,8 @@ func (cmd *StatusCmd) Run(ctx *Context) error {
 type LogCmd struct {
 	Count int `default:"10" help:"Entries per page"`
 	Page  int `default:"1" help:"Page to display"`
+
+	FilterFlags `embed:""`
 }
 
 func (cmd *LogCmd) Run(ctx *Context) error {
@@ -175,15 +460,241 @@ func (cmd *LogCmd) Run(ctx *Context) error {
 		return errors.New("page must be greater than zero")
 	}
 
+	now := time.Now()
+	filter, err := cmd.filter(now)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := activeSessions(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if ctx.Output != OutputText {
+		return writeSessionRecords(os.Stdout, ctx.Output, paginate.ReversePaginate(sessions, cmd.Page, cmd.Count))
+	}
+	if len(sessions) == 0 && !filter.IsZero() {
+		fmt.Println("No sessions match the filter")
+		return nil
+	}
+
+	PrintLog(sessions, cmd.Page, cmd.Count, now)
+	return nil
+}
+
+// ReportCmd totals completed sessions by period or task.
+type ReportCmd struct {
+	By string `enum:"day,week,month,task" default:"day" help:"Group sessions by day, ISO week, month or task."`
+
+	FilterFlags `embed:""`
+}
+
+func (cmd *ReportCmd) Run(ctx *Context) error {
+	now := time.Now()
+	filter, err := cmd.filter(now)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := activeSessions(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	by := flowtime.ReportGrouping(cmd.By)
+	rows, err := flowtime.Report(sessions, by, now.Location())
+	if err != nil {
+		return err
+	}
+
+	if ctx.Output != OutputText {
+		records := make([]reportRecord, len(rows))
+		for i, r := range rows {
+			records[i] = newReportRecord(r)
+		}
+		return writeRecords(os.Stdout, ctx.Output, reportColumns, records)
+	}
+
+	PrintReport(rows, by)
+	return nil
+}
+
+// ExportCmd writes completed sessions to stdout in a format for other apps.
+type ExportCmd struct {
+	Format string `enum:"ics" default:"ics" help:"Export format: ics, an iCalendar file of events for calendar apps."`
+	Breaks bool   `help:"Add each session's break as an event of its own."`
+
+	FilterFlags `embed:""`
+}
+
+func (cmd *ExportCmd) Run(ctx *Context) error {
+	now := time.Now()
+	filter, err := cmd.filter(now)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := activeSessions(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return ical.WriteSessions(os.Stdout, sessions, ical.Options{Breaks: cmd.Breaks, Stamp: now})
+}
+
+// activeSessions returns the active completed sessions matching filter, letting
+// stores that can select them do so without loading the whole state.
+func activeSessions(ctx *Context, filter flowtime.SessionFilter) ([]flowtime.CompletedSession, error) {
+	if querier, ok := ctx.Store.(storage.SessionQuerier); ok {
+		sessions, err := querier.QuerySessions(filter)
+		if err != nil {
+			return nil, fmt.Errorf("loading sessions: %w", err)
+		}
+		return sessions, nil
+	}
+
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading state: %w", err)
+	}
+	return filter.Apply(state.ActiveSessions()), nil
+}
+
+// ShowCmd prints a completed session, including deleted ones, with its intervals.
+type ShowCmd struct {
+	Target string `arg:"" name:"id|index" help:"Session ID (or a unique prefix of one), or session number (1 = most recent)."`
+}
+
+func (cmd *ShowCmd) Run(ctx *Context) error {
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	fullIndex, err := resolveSession(state, state.ActiveSessions(), cmd.Target)
+	if err != nil {
+		return err
+	}
+
+	PrintSession(state.CompletedSessions[fullIndex], time.Now())
+	return nil
+}
+
+// AddCmd records a session that was never timed.
+type AddCmd struct {
+	Task  string `arg:"" help:"Task description, with optional +project and @tag labels."`
+	From  string `required:"" help:"When the session started, e.g. \"09:00\" or \"yesterday 14:00\"."`
+	To    string `required:"" help:"When the session ended."`
+	Break string `help:"Length of the break taken at the end of the session, e.g. \"10m\"."`
+}
+
+func (cmd *AddCmd) Run(ctx *Context) error {
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	now := time.Now()
+	from, err := flowtime.ParseTime(cmd.From, now)
+	if err != nil {
+		return fmt.Errorf("parsing --from: %w", err)
+	}
+	to, err := flowtime.ParseTime(cmd.To, now)
+	if err != nil {
+		return fmt.Errorf("parsing --to: %w", err)
+	}
+	var breakDuration time.Duration
+	if cmd.Break != "" {
+		if breakDuration, err = flowtime.ParseDuration(cmd.Break); err != nil {
+			return fmt.Errorf("parsing --break: %w", err)
+		}
+	}
+
+	added, err := state.AddSession(cmd.Task, from, to, breakDuration)
+	if err != nil {
+		return fmt.Errorf("adding session: %w", err)
+	}
+
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	fmt.Printf("Added session %s: %s.\n", added.ID, sessionSummary(*added))
+	return nil
+}
+
+// EditCmd changes a completed session. Only the given fields are changed.
+type EditCmd struct {
+	Target string `arg:"" name:"id|index" help:"Session ID (or a unique prefix of one), or session number (1 = most recent)."`
+	Task   string `help:"New task description; +project and @tag labels replace the session's if given."`
+	Flow   string `help:"New flow duration, e.g. \"45m\"."`
+	Break  string `help:"New break duration; 0 removes the break."`
+	At     string `help:"New completion time; the whole session moves with it."`
+}
+
+func (cmd *EditCmd) Run(ctx *Context) error {
 	state, err := ctx.Store.Load()
 	if err != nil {
 		return fmt.Errorf("loading state: %w", err)
 	}
 
-	PrintLog(state.ActiveSessions(), cmd.Page, cmd.Count, time.Now())
+	fullIndex, err := resolveSession(state, state.ActiveSessions(), cmd.Target)
+	if err != nil {
+		return err
+	}
+
+	var edit flowtime.SessionEdit
+	if cmd.Task != "" {
+		edit.Task = &cmd.Task
+	}
+	if cmd.Flow != "" {
+		flow, err := flowtime.ParseDuration(cmd.Flow)
+		if err != nil {
+			return fmt.Errorf("parsing --flow: %w", err)
+		}
+		edit.Flow = &flow
+	}
+	if cmd.Break != "" {
+		breakDuration, err := flowtime.ParseDuration(cmd.Break)
+		if err != nil {
+			return fmt.Errorf("parsing --break: %w", err)
+		}
+		edit.Break = &breakDuration
+	}
+	if cmd.At != "" {
+		at, err := flowtime.ParseTime(cmd.At, time.Now())
+		if err != nil {
+			return fmt.Errorf("parsing --at: %w", err)
+		}
+		edit.CompletedAt = &at
+	}
+	if edit == (flowtime.SessionEdit{}) {
+		return errors.New("nothing to edit: give at least one of --task, --flow, --break or --at")
+	}
+
+	edited, err := state.EditSession(fullIndex, edit)
+	if err != nil {
+		return fmt.Errorf("editing session: %w", err)
+	}
+
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	fmt.Printf("Edited session %s: %s.\n", edited.ID, sessionSummary(*edited))
 	return nil
 }
 
+// sessionSummary describes a completed session in one line, e.g.
+// "write docs, 2025-06-15 09:00 to 10:15 (1h 5m flow, 10m break)".
+func sessionSummary(cs flowtime.CompletedSession) string {
+	s := fmt.Sprintf("%s, %s to %s (%s flow", cs.Task,
+		cs.Start().Format("2006-01-02 15:04"), cs.CompletedAt.Format("15:04"), flowtime.FormatDuration(cs.FlowDuration))
+	if cs.BreakDuration != nil {
+		s += ", " + flowtime.FormatDuration(*cs.BreakDuration) + " break"
+	}
+	return s + ")"
+}
+
 // CancelCmd discards the current session without recording it.
 type CancelCmd struct {
 	Yes bool `short:"y" help:"Skip confirmation prompt."`
@@ -222,53 +733,30 @@ func (cmd *CancelCmd) Run(ctx *Context) error {
 	return nil
 }
 
-// DeleteCmd soft-deletes a completed session by its display index (1-based, newest first).
+// DeleteCmd soft-deletes a completed session by its ID or display index (1-based, newest first).
 type DeleteCmd struct {
-	Index int  `arg:"" help:"Session number (1 = most recent)."`
-	Yes   bool `short:"y" help:"Skip confirmation prompt."`
+	Target string `arg:"" name:"id|index" help:"Session ID (or a unique prefix of one), or session number (1 = most recent)."`
+	Yes    bool   `short:"y" help:"Skip confirmation prompt."`
 }
 
 func (cmd *DeleteCmd) Run(ctx *Context) error {
-	if cmd.Index <= 0 {
-		return errors.New("index must be a positive number")
-	}
-
 	state, err := ctx.Store.Load()
 	if err != nil {
 		return fmt.Errorf("loading state: %w", err)
 	}
 
-	active := state.ActiveSessions()
-	if len(active) == 0 {
-		return errors.New("no sessions to delete")
-	}
-
-	// Convert 1-based newest-first display index to the underlying slice index.
-	displayIndex := cmd.Index - 1
-	if displayIndex >= len(active) {
-		return fmt.Errorf("index %d out of range (have %d sessions)", cmd.Index, len(active))
-	}
-
-	// Map from active-list index (newest-first) to full CompletedSessions index.
-	// Active list preserves chronological order; display index 0 = last active session.
-	activeReversed := len(active) - 1 - displayIndex
-	target := active[activeReversed]
-
-	// Find the matching entry in the full slice.
-	fullIndex := -1
-	for i, cs := range state.CompletedSessions {
-		if cs.CompletedAt.Equal(target.CompletedAt) && cs.Task == target.Task && cs.DeletedAt == nil {
-			fullIndex = i
-			break
-		}
+	fullIndex, err := resolveSession(state, state.ActiveSessions(), cmd.Target)
+	if err != nil {
+		return err
 	}
-	if fullIndex == -1 {
-		return errors.New("session not found")
+	target := state.CompletedSessions[fullIndex]
+	if target.DeletedAt != nil {
+		return fmt.Errorf("session %s is already deleted", target.ID)
 	}
 
 	if !cmd.Yes {
-		ok, err := confirm(fmt.Sprintf("Delete session %q (%s)?",
-			target.Task, flowtime.FormatDuration(target.FlowDuration)))
+		ok, err := confirm(fmt.Sprintf("Delete session %s %q (%s)?",
+			target.ID, target.Task, flowtime.FormatDuration(target.FlowDuration)))
 		if err != nil {
 			return err
 		}
@@ -290,6 +778,29 @@ func (cmd *DeleteCmd) Run(ctx *Context) error {
 	return nil
 }
 
+// resolveSession returns the CompletedSessions index of the session identified
+// by target: a 1-based display index into listed (newest first), or otherwise a
+// session ID or unique prefix of one. Numbers beyond the number of listed
+// sessions are looked up as ID prefixes, since a prefix may be all digits.
+func resolveSession(state *flowtime.FlowState, listed []flowtime.CompletedSession, target string) (int, error) {
+	n, err := strconv.Atoi(target)
+	if err != nil {
+		return state.SessionIndex(target)
+	}
+	if n >= 1 && n <= len(listed) {
+		// Listed sessions are in chronological order; display index 1 = last one.
+		return state.SessionIndex(listed[len(listed)-n].ID)
+	}
+	if index, err := state.SessionIndex(target); err == nil {
+		return index, nil
+	}
+
+	if n <= 0 {
+		return -1, errors.New("index must be a positive number")
+	}
+	return -1, fmt.Errorf("index %d out of range (have %d sessions)", n, len(listed))
+}
+
 // ClearCmd soft-deletes all completed sessions.
 type ClearCmd struct {
 	Yes bool `short:"y" help:"Skip confirmation prompt."`
@@ -329,6 +840,140 @@ func (cmd *ClearCmd) Run(ctx *Context) error {
 	return nil
 }
 
+// TrashCmd lists soft-deleted sessions.
+type TrashCmd struct {
+	Count int `default:"10" help:"Entries per page"`
+	Page  int `default:"1" help:"Page to display"`
+}
+
+func (cmd *TrashCmd) Run(ctx *Context) error {
+	if cmd.Count <= 0 {
+		return errors.New("count must be greater than zero")
+	}
+	if cmd.Page <= 0 {
+		return errors.New("page must be greater than zero")
+	}
+
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	if ctx.Output != OutputText {
+		return writeSessionRecords(os.Stdout, ctx.Output, paginate.ReversePaginate(state.DeletedSessions(), cmd.Page, cmd.Count))
+	}
+
+	PrintTrash(state.DeletedSessions(), cmd.Page, cmd.Count, time.Now())
+	return nil
+}
+
+// RestoreCmd undeletes a session by its ID or trash index, or every session
+// deleted since a given time.
+type RestoreCmd struct {
+	Target   string `arg:"" optional:"" name:"id|index" help:"Session ID (or a unique prefix of one), or number in the trash (1 = most recent)."`
+	AllSince string `name:"all-since" placeholder:"TIME" help:"Restore every session deleted at or after TIME (e.g. \"15:04\", \"2006-01-02\" or \"2h ago\")."`
+}
+
+func (cmd *RestoreCmd) Run(ctx *Context) error {
+	if (cmd.Target == "") == (cmd.AllSince == "") {
+		return errors.New("specify either a session or --all-since")
+	}
+
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	if cmd.AllSince != "" {
+		since, err := flowtime.ParseTime(cmd.AllSince, time.Now())
+		if err != nil {
+			return err
+		}
+		restored, err := state.RestoreSessionsSince(since)
+		if err != nil {
+			return fmt.Errorf("restoring sessions: %w", err)
+		}
+		if err := ctx.Store.Save(state); err != nil {
+			return fmt.Errorf("saving state: %w", err)
+		}
+		fmt.Printf("Restored %d sessions.\n", restored)
+		return nil
+	}
+
+	fullIndex, err := resolveSession(state, state.DeletedSessions(), cmd.Target)
+	if err != nil {
+		return err
+	}
+	if err := state.RestoreSession(fullIndex); err != nil {
+		return fmt.Errorf("restoring session: %w", err)
+	}
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	restored := state.CompletedSessions[fullIndex]
+	fmt.Printf("Restored session %s %q.\n", restored.ID, restored.Task)
+	return nil
+}
+
+// PurgeCmd permanently removes soft-deleted sessions.
+type PurgeCmd struct {
+	OlderThan string `name:"older-than" placeholder:"DURATION" help:"Only purge sessions deleted longer ago than this (e.g. \"30d\" or \"12h\")."`
+	DryRun    bool   `name:"dry-run" help:"Show what would be purged without changing anything."`
+	Yes       bool   `short:"y" help:"Skip confirmation prompt."`
+}
+
+func (cmd *PurgeCmd) Run(ctx *Context) error {
+	var olderThan time.Duration
+	if cmd.OlderThan != "" {
+		var err error
+		if olderThan, err = flowtime.ParseDuration(cmd.OlderThan); err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+	}
+
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-olderThan)
+	purgeable := state.PurgeableSessions(cutoff)
+	if len(purgeable) == 0 {
+		fmt.Println("Nothing to purge.")
+		return nil
+	}
+
+	PrintPurge(purgeable, cmd.DryRun, now)
+	if cmd.DryRun {
+		return nil
+	}
+
+	if !cmd.Yes {
+		ok, err := confirm(fmt.Sprintf("Permanently remove %d sessions? This cannot be undone.", len(purgeable)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	purged, err := state.PurgeDeleted(cutoff)
+	if err != nil {
+		return fmt.Errorf("purging sessions: %w", err)
+	}
+
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	fmt.Printf("Purged %d sessions.\n", purged)
+	return nil
+}
+
 // confirm prompts the user with the given message and reads y/n from stdin.
 func confirm(prompt string) (bool, error) {
 	fmt.Printf("%s [y/N] ", prompt)
@@ -340,7 +985,23 @@ func confirm(prompt string) (bool, error) {
 	return response == "y" || response == "Y", nil
 }
 
-// LocateCmd shows the state file path.
+// prompt asks the user for a line of free text on stdin. EOF is an empty answer.
+func prompt(message string) (string, error) {
+	fmt.Printf("%s ", message)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal, so
+// commands can prompt without blocking scripts that pipe input or none.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(os.Stdin.Fd())
+}
+
+// LocateCmd shows the state file path of the selected profile.
 type LocateCmd struct{}
 
 func (cmd *LocateCmd) Run(ctx *Context) error {
@@ -349,6 +1010,304 @@ func (cmd *LocateCmd) Run(ctx *Context) error {
 		return fmt.Errorf("getting state file path: %w", err)
 	}
 
+	if ctx.Output != OutputText {
+		return writeRecord(os.Stdout, ctx.Output, locationColumns, locationRecord{Profile: ctx.Profile, Path: fp})
+	}
+
 	fmt.Println(fp)
 	return nil
 }
+
+// UndoCmd reverts the most recent state transition.
+type UndoCmd struct{}
+
+func (cmd *UndoCmd) Run(ctx *Context) error {
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	undone, err := state.Undo()
+	if err != nil {
+		return fmt.Errorf("undoing: %w", err)
+	}
+
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	fmt.Printf("Undid %s.\n", describeEvent(undone, time.Now()))
+	return nil
+}
+
+// RedoCmd reapplies the most recently undone state transition.
+type RedoCmd struct{}
+
+func (cmd *RedoCmd) Run(ctx *Context) error {
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	redone, err := state.Redo()
+	if err != nil {
+		return fmt.Errorf("redoing: %w", err)
+	}
+
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	fmt.Printf("Redid %s.\n", describeEvent(redone, time.Now()))
+	return nil
+}
+
+// HistoryCmd shows recorded state transitions from the store's event log.
+type HistoryCmd struct {
+	Count int `default:"20" help:"Entries per page"`
+	Page  int `default:"1" help:"Page to display"`
+}
+
+func (cmd *HistoryCmd) Run(ctx *Context) error {
+	if cmd.Count <= 0 {
+		return errors.New("count must be greater than zero")
+	}
+	if cmd.Page <= 0 {
+		return errors.New("page must be greater than zero")
+	}
+
+	eventLog, ok := ctx.Store.(storage.EventLog)
+	if !ok {
+		return errors.New("store does not keep an event log")
+	}
+
+	events, err := eventLog.Events()
+	if err != nil {
+		return fmt.Errorf("loading events: %w", err)
+	}
+
+	PrintHistory(events, cmd.Page, cmd.Count, time.Now())
+	return nil
+}
+
+// BackupCmd groups the commands for managing automatic state backups.
+type BackupCmd struct {
+	List    BackupListCmd    `cmd:"" help:"List available backups."`
+	Restore BackupRestoreCmd `cmd:"" help:"Restore the state from a backup."`
+	Prune   BackupPruneCmd   `cmd:"" help:"Delete old backups."`
+}
+
+// backupStore returns the context's store as a BackupStore, if it supports backups.
+func backupStore(ctx *Context) (storage.BackupStore, error) {
+	bs, ok := ctx.Store.(storage.BackupStore)
+	if !ok {
+		return nil, errors.New("backups are only kept by the JSON store; use --store json")
+	}
+	return bs, nil
+}
+
+// BackupListCmd lists the available backups.
+type BackupListCmd struct{}
+
+func (cmd *BackupListCmd) Run(ctx *Context) error {
+	bs, err := backupStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	backups, err := bs.ListBackups()
+	if err != nil {
+		return fmt.Errorf("listing backups: %w", err)
+	}
+
+	PrintBackups(backups, time.Now())
+	return nil
+}
+
+// BackupRestoreCmd replaces the current state with the contents of a backup.
+// The restore is itself recorded, so the state it replaces is backed up too.
+type BackupRestoreCmd struct {
+	ID  string `arg:"" help:"Backup ID (or a unique prefix of it)."`
+	Yes bool   `short:"y" help:"Skip confirmation prompt."`
+}
+
+func (cmd *BackupRestoreCmd) Run(ctx *Context) error {
+	bs, err := backupStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	backup, err := bs.LoadBackup(cmd.ID)
+	if err != nil {
+		return fmt.Errorf("loading backup: %w", err)
+	}
+
+	state, err := ctx.Store.Load()
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	if !cmd.Yes {
+		ok, err := confirm(fmt.Sprintf("Replace current state (%d sessions) with backup %s (%d sessions)?",
+			len(state.ActiveSessions()), cmd.ID, len(backup.ActiveSessions())))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	// Automatic backups are taken at most hourly, so make sure the state being
+	// replaced can itself be restored.
+	if _, err := bs.CreateBackup(state); err != nil {
+		return fmt.Errorf("backing up current state: %w", err)
+	}
+	if err := state.Replace(backup); err != nil {
+		return fmt.Errorf("restoring backup: %w", err)
+	}
+
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	fmt.Println("Backup restored.")
+	return nil
+}
+
+// BackupPruneCmd deletes backups outside the retention window.
+type BackupPruneCmd struct {
+	Keep int `default:"10" help:"Number of most recent backups to keep."`
+	Days int `default:"7" help:"Also keep the newest backup from each of this many days."`
+}
+
+func (cmd *BackupPruneCmd) Run(ctx *Context) error {
+	if cmd.Keep < 0 || cmd.Days < 0 {
+		return errors.New("keep and days cannot be negative")
+	}
+
+	bs, err := backupStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	pruned, err := bs.PruneBackups(cmd.Keep, cmd.Days)
+	if err != nil {
+		return fmt.Errorf("pruning backups: %w", err)
+	}
+
+	fmt.Printf("Deleted %d backups.\n", len(pruned))
+	return nil
+}
+
+// ProfileCmd groups the commands for working with profiles.
+type ProfileCmd struct {
+	List ProfileListCmd `cmd:"" help:"List profiles."`
+}
+
+// ProfileListCmd lists the known profiles, marking the selected one.
+type ProfileListCmd struct{}
+
+func (cmd *ProfileListCmd) Run(ctx *Context) error {
+	profiles, err := storage.ListProfiles()
+	if err != nil {
+		return fmt.Errorf("listing profiles: %w", err)
+	}
+
+	PrintProfiles(profiles, ctx.Profile)
+	return nil
+}
+
+// DoctorCmd checks the state for violated invariants and can repair the safely
+// repairable ones after backing up the state.
+type DoctorCmd struct {
+	Fix bool `help:"Repair fixable problems, backing up the state first."`
+}
+
+func (cmd *DoctorCmd) Run(ctx *Context) error {
+	state, problems, err := inspectState(ctx.Store)
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	now := time.Now()
+	problems = append(problems, state.Check(now)...)
+	if len(problems) == 0 {
+		fmt.Println("No problems found.")
+		return nil
+	}
+	PrintProblems(problems)
+
+	fixable := 0
+	replayable := true
+	for _, p := range problems {
+		if p.Fixable {
+			fixable++
+		}
+		if p.Code == flowtime.ProblemReplayFailed {
+			replayable = false
+		}
+	}
+	if !cmd.Fix || fixable == 0 {
+		if fixable > 0 {
+			fmt.Println("Run `flower doctor --fix` to repair the fixable problems.")
+		}
+		return fmt.Errorf("found %d problems", len(problems))
+	}
+	if !replayable {
+		// A repair is saved as a new event, which would never be reached.
+		return errors.New("the event log must be repaired by hand before the other problems can be fixed")
+	}
+
+	backup, err := backupBeforeRepair(ctx, state, now)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backed up the state to %s\n", backup)
+
+	if err := state.Replace(state.Repair(now)); err != nil {
+		return fmt.Errorf("repairing state: %w", err)
+	}
+	if err := ctx.Store.Save(state); err != nil {
+		return fmt.Errorf("saving state: %w", err)
+	}
+
+	fmt.Printf("Fixed %d problems.\n", fixable)
+	if remaining := len(problems) - fixable; remaining > 0 {
+		return fmt.Errorf("%d problems need fixing by hand", remaining)
+	}
+	return nil
+}
+
+// inspectState returns the state as stored, and any problems reading it, from
+// stores that can return it without normalizing it, or else the loaded state.
+func inspectState(store storage.Store) (*flowtime.FlowState, []flowtime.Problem, error) {
+	if inspector, ok := store.(storage.Inspector); ok {
+		return inspector.Inspect()
+	}
+	state, err := store.Load()
+	return state, nil, err
+}
+
+// backupBeforeRepair backs up state using the store's backups if it keeps them,
+// or else by copying the store file, and returns where the backup was written.
+func backupBeforeRepair(ctx *Context, state *flowtime.FlowState, now time.Time) (string, error) {
+	if backups, ok := ctx.Store.(storage.BackupStore); ok {
+		backup, err := backups.CreateBackup(state)
+		if err != nil {
+			return "", fmt.Errorf("backing up state: %w", err)
+		}
+		return backup.Path, nil
+	}
+
+	path, err := ctx.LocateStore()
+	if err != nil {
+		return "", fmt.Errorf("getting state file path: %w", err)
+	}
+	backup, err := storage.CopyStateFile(path, "doctor", now)
+	if err != nil {
+		return "", fmt.Errorf("backing up state: %w", err)
+	}
+	return backup, nil
+}
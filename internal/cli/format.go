@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+// statusPresets are the built-in templates for `status --format`, for status
+// bars that rerun the command every second or so. They print nothing (or, for
+// waybar, empty text) when idle so the bar can hide the block.
+var statusPresets = map[string]string{
+	// tmux: status-right '#(flower status --format tmux)'
+	"tmux": `{{if eq .State "flow"}}#[fg=cyan]● {{tmux .Task}} {{clock .Elapsed}}#[default]` +
+		`{{else if eq .State "break"}}{{if .Overtime}}#[fg=red]☕ +{{clock .Overtime}}{{else}}#[fg=green]☕ {{clock .Remaining}}{{end}}#[default]{{end}}`,
+
+	// waybar custom module with "return-type": "json". The class is the state,
+	// plus "overtime" once a break runs past its suggestion.
+	"waybar": `{"text": {{json (statusText .)}}, "alt": {{json .State}}, ` +
+		`"class": [{{json .State}}{{if .Overtime}}, "overtime"{{end}}], "tooltip": {{json (statusTooltip .)}}}`,
+
+	// polybar custom/script module with format tags for colour.
+	"polybar": `{{if eq .State "flow"}}%{F#8be9fd}{{polybar (statusText .)}}%{F-}` +
+		`{{else if eq .State "break"}}{{if .Overtime}}%{F#ff5555}{{else}}%{F#50fa7b}{{end}}{{polybar (statusText .)}}%{F-}{{end}}`,
+
+	// i3blocks: full text, short text and colour on separate lines.
+	"i3blocks": `{{if ne .State "idle"}}{{statusText .}}
+{{if eq .State "flow"}}{{clock .Elapsed}}{{else}}☕ {{if .Overtime}}+{{clock .Overtime}}{{else}}{{clock .Remaining}}{{end}}{{end}}
+{{if eq .State "flow"}}#8be9fd{{else if .Overtime}}#ff5555{{else}}#50fa7b{{end}}{{end}}`,
+}
+
+// statusFields is the data available to `status --format` templates.
+type statusFields struct {
+	State          string        // "idle", "flow" or "break"
+	Task           string        // empty when idle
+	Project        string        // empty when idle or unset
+	Tags           []string      // empty when idle or unset
+	Labels         string        // project and tags as written in a task, e.g. "+auth @review"
+	Elapsed        time.Duration // time in the current flow or break
+	SuggestedBreak time.Duration // length of the current break's suggestion
+	Remaining      time.Duration // left of the suggested break, zero once it is over
+	Overtime       time.Duration // taken beyond the suggested break
+	LongBreak      bool          // whether the current break is a long one
+	Cycle          int           // position in the flow/break rhythm
+	CycleLength    int           // breaks per long break, zero when long breaks are off
+	Interruptions  int           // interruptions recorded in the current session
+}
+
+func newStatusFields(state *flowtime.FlowState, now time.Time) statusFields {
+	f := statusFields{State: "idle", Cycle: state.Cycle(now), CycleLength: state.LongBreakRule().Every}
+	session := state.CurrentSession
+	if session == nil {
+		return f
+	}
+
+	f.State = "flow"
+	f.Task = session.Task
+	f.Project = session.Project
+	f.Tags = session.Tags
+	f.Labels = flowtime.FormatLabels(session.Project, session.Tags)
+	f.Interruptions = len(session.Interruptions)
+	f.Elapsed = now.Sub(session.FlowStart())
+	if brk := state.CurrentBreak; brk != nil {
+		f.State = "break"
+		f.Elapsed = now.Sub(brk.StartTime)
+		f.SuggestedBreak = brk.SuggestedDuration
+		f.LongBreak = state.OnLongBreak()
+		if f.Elapsed < brk.SuggestedDuration {
+			f.Remaining = brk.SuggestedDuration - f.Elapsed
+		} else {
+			f.Overtime = f.Elapsed - brk.SuggestedDuration
+		}
+	}
+	return f
+}
+
+// statusFuncs are the functions available to `status --format` templates, on
+// top of text/template's built-ins.
+var statusFuncs = template.FuncMap{
+	"duration":      flowtime.FormatDuration,
+	"clock":         formatClock,
+	"join":          strings.Join,
+	"statusText":    statusText,
+	"statusTooltip": statusTooltip,
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// tmux escapes "#" so a task cannot inject tmux formats.
+	"tmux": func(s string) string { return strings.ReplaceAll(s, "#", "##") },
+	// polybar escapes "%" so a task cannot inject polybar format tags, such as
+	// click actions that run commands.
+	"polybar": func(s string) string { return strings.ReplaceAll(s, "%", "%%") },
+}
+
+// parseStatusFormat returns the template for a preset name or, failing that,
+// parses format as a template.
+func parseStatusFormat(format string) (*template.Template, error) {
+	text, ok := statusPresets[format]
+	if !ok {
+		text = format
+	}
+	tmpl, err := template.New("status").Funcs(statusFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing --format: %w", err)
+	}
+	return tmpl, nil
+}
+
+// printStatusFormat writes the state to w using tmpl, followed by a newline.
+func printStatusFormat(w io.Writer, tmpl *template.Template, state *flowtime.FlowState, now time.Time) error {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, newStatusFields(state, now)); err != nil {
+		return fmt.Errorf("formatting status: %w", err)
+	}
+	_, err := fmt.Fprintln(w, b.String())
+	return err
+}
+
+// statusText is the one-line summary used by the presets, e.g. "Fix login 25:03"
+// in flow or "Break 4:57 left" on a break.
+func statusText(f statusFields) string {
+	switch f.State {
+	case "flow":
+		return f.Task + " " + formatClock(f.Elapsed)
+	case "break":
+		kind := "Break"
+		if f.LongBreak {
+			kind = "Long break"
+		}
+		if f.Overtime > 0 {
+			return kind + " +" + formatClock(f.Overtime)
+		}
+		return kind + " " + formatClock(f.Remaining) + " left"
+	}
+	return ""
+}
+
+// statusTooltip is the longer description used by the presets that show one.
+func statusTooltip(f statusFields) string {
+	if f.State == "idle" {
+		return "No active session"
+	}
+	lines := []string{f.Task}
+	if f.Labels != "" {
+		lines[0] += " " + f.Labels
+	}
+	if f.State == "break" {
+		lines = append(lines, "Suggested break: "+flowtime.FormatDuration(f.SuggestedBreak))
+	}
+	lines = append(lines, flowtime.FormatCycle(f.Cycle, f.CycleLength))
+	if f.Interruptions > 0 {
+		lines = append(lines, fmt.Sprintf("Interruptions: %d", f.Interruptions))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatClock formats d like a stopwatch: "4:05", or "1:04:05" from an hour up.
+func formatClock(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+// These tests pin the `status --format` presets, which status bars parse and
+// which must not let a task inject the bar's own markup.
+
+// statusAt returns a state in the given phase and the time to render it at:
+// "flow" 25 minutes into task, "break" 3:30 into an 8-minute break after
+// 50 minutes of flow, or "overtime" 2 minutes past that break's suggestion.
+func statusAt(t *testing.T, phase, task string) (*flowtime.FlowState, time.Time) {
+	t.Helper()
+	clock := &testClock{now: time.Date(2025, 6, 15, 9, 0, 0, 0, time.UTC)}
+	state := flowtime.NewFlowState(clock)
+	if phase == "idle" {
+		return state, clock.now
+	}
+	if err := state.StartSession(task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if phase == "flow" {
+		return state, clock.now.Add(25*time.Minute + 3*time.Second)
+	}
+	clock.now = clock.now.Add(50 * time.Minute)
+	if err := state.TakeBreak(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if phase == "overtime" {
+		return state, clock.now.Add(10 * time.Minute)
+	}
+	return state, clock.now.Add(3*time.Minute + 30*time.Second)
+}
+
+// formatStatus renders the state with format, as `status --format` does.
+func formatStatus(t *testing.T, format string, state *flowtime.FlowState, now time.Time) string {
+	t.Helper()
+	tmpl, err := parseStatusFormat(format)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var b strings.Builder
+	if err := printStatusFormat(&b, tmpl, state, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return b.String()
+}
+
+func TestFormatClock(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0:00"},
+		{5 * time.Second, "0:05"},
+		{4*time.Minute + 5*time.Second + 900*time.Millisecond, "4:05"},
+		{59*time.Minute + 59*time.Second, "59:59"},
+		{time.Hour, "1:00:00"},
+		{time.Hour + 4*time.Minute + 5*time.Second, "1:04:05"},
+		{26 * time.Hour, "26:00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatClock(tt.d); got != tt.want {
+				t.Errorf("formatClock(%v) = %q, want %q", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusText(t *testing.T) {
+	tests := []struct {
+		name string
+		f    statusFields
+		want string
+	}{
+		{"idle", statusFields{State: "idle"}, ""},
+		{"flow", statusFields{State: "flow", Task: "Fix login", Elapsed: 25*time.Minute + 3*time.Second}, "Fix login 25:03"},
+		{"break", statusFields{State: "break", Task: "Fix login", Remaining: 4*time.Minute + 57*time.Second}, "Break 4:57 left"},
+		{"long break", statusFields{State: "break", LongBreak: true, Remaining: 20 * time.Minute}, "Long break 20:00 left"},
+		{"overtime", statusFields{State: "break", Overtime: 2 * time.Minute}, "Break +2:00"},
+		{"long overtime", statusFields{State: "break", LongBreak: true, Overtime: time.Hour}, "Long break +1:00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusText(tt.f); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusPresets(t *testing.T) {
+	const task = "Fix login +auth @review"
+	tests := []struct {
+		format string
+		phase  string
+		want   string
+	}{
+		{"tmux", "idle", "\n"},
+		{"tmux", "flow", "#[fg=cyan]● Fix login 25:03#[default]\n"},
+		{"tmux", "break", "#[fg=green]☕ 4:30#[default]\n"},
+		{"tmux", "overtime", "#[fg=red]☕ +2:00#[default]\n"},
+
+		{"waybar", "idle", `{"text": "", "alt": "idle", "class": ["idle"], "tooltip": "No active session"}` + "\n"},
+		{"waybar", "flow", `{"text": "Fix login 25:03", "alt": "flow", "class": ["flow"], "tooltip": "Fix login +auth @review\ncycle 1"}` + "\n"},
+		{"waybar", "break", `{"text": "Break 4:30 left", "alt": "break", "class": ["break"], "tooltip": "Fix login +auth @review\nSuggested break: 8m\ncycle 1"}` + "\n"},
+		{"waybar", "overtime", `{"text": "Break +2:00", "alt": "break", "class": ["break", "overtime"], "tooltip": "Fix login +auth @review\nSuggested break: 8m\ncycle 1"}` + "\n"},
+
+		{"polybar", "idle", "\n"},
+		{"polybar", "flow", "%{F#8be9fd}Fix login 25:03%{F-}\n"},
+		{"polybar", "break", "%{F#50fa7b}Break 4:30 left%{F-}\n"},
+		{"polybar", "overtime", "%{F#ff5555}Break +2:00%{F-}\n"},
+
+		{"i3blocks", "idle", "\n"},
+		{"i3blocks", "flow", "Fix login 25:03\n25:03\n#8be9fd\n"},
+		{"i3blocks", "break", "Break 4:30 left\n☕ 4:30\n#50fa7b\n"},
+		{"i3blocks", "overtime", "Break +2:00\n☕ +2:00\n#ff5555\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.phase, func(t *testing.T) {
+			state, now := statusAt(t, tt.phase, task)
+			if got := formatStatus(t, tt.format, state, now); got != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusFormatFields(t *testing.T) {
+	const task = "Fix login +auth @review @urgent"
+	tests := []struct {
+		name   string
+		format string
+		phase  string
+		want   string
+	}{
+		{"state", "{{.State}}", "idle", "idle"},
+		{"task", "{{.Task}}", "flow", "Fix login"},
+		{"project", "{{.Project}}", "flow", "auth"},
+		{"tags", `{{join .Tags ","}}`, "flow", "review,urgent"},
+		{"labels", "{{.Labels}}", "flow", "+auth @review @urgent"},
+		{"elapsed", "{{clock .Elapsed}} {{duration .Elapsed}}", "flow", "25:03 25m 3s"},
+		{"suggested break", "{{duration .SuggestedBreak}}", "break", "8m"},
+		{"remaining", "{{clock .Remaining}} {{clock .Overtime}}", "break", "4:30 0:00"},
+		{"overtime", "{{clock .Remaining}} {{clock .Overtime}}", "overtime", "0:00 2:00"},
+		{"long break", "{{.LongBreak}}", "break", "false"},
+		{"cycle", "{{.Cycle}}/{{.CycleLength}}", "idle", "1/0"},
+		{"interruptions", "{{.Interruptions}}", "flow", "0"},
+		{"json", "{{json .Tags}}", "flow", `["review","urgent"]`},
+		{"status text", "{{statusText .}}", "break", "Break 4:30 left"},
+		{"tooltip", "{{statusTooltip .}}", "idle", "No active session"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, now := statusAt(t, tt.phase, task)
+			if got := formatStatus(t, tt.format, state, now); got != tt.want+"\n" {
+				t.Errorf("got %q, want %q", got, tt.want+"\n")
+			}
+		})
+	}
+}
+
+func TestStatusFormatInvalid(t *testing.T) {
+	if _, err := parseStatusFormat("{{.State"); err == nil {
+		t.Fatal("expected an error for an unterminated action")
+	}
+}
+
+// TestStatusPresetsEscapeTask checks that a task cannot inject a status bar's
+// markup, such as a polybar click action that runs a command.
+func TestStatusPresetsEscapeTask(t *testing.T) {
+	const task = `Fix #[fg=red] %{A1:rm -rf ~:}login%{A} "now"`
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"tmux", `#[fg=cyan]● Fix ##[fg=red] %{A1:rm -rf ~:}login%{A} "now" 25:03#[default]` + "\n"},
+		{"polybar", `%{F#8be9fd}Fix #[fg=red] %%{A1:rm -rf ~:}login%%{A} "now" 25:03%{F-}` + "\n"},
+		{"waybar", `{"text": "Fix #[fg=red] %{A1:rm -rf ~:}login%{A} \"now\" 25:03", "alt": "flow", "class": ["flow"], ` +
+			`"tooltip": "Fix #[fg=red] %{A1:rm -rf ~:}login%{A} \"now\"\ncycle 1"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			state, now := statusAt(t, "flow", task)
+			if got := formatStatus(t, tt.format, state, now); got != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,10 +2,13 @@ package cli
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Broderick-Westrope/flower/internal/flowtime"
 	"github.com/Broderick-Westrope/flower/internal/paginate"
+	"github.com/Broderick-Westrope/flower/internal/storage"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/table"
 )
@@ -17,22 +20,32 @@ func PrintStatus(state *flowtime.FlowState, now time.Time) {
 		return
 	}
 
+	cycle := flowtime.FormatCycle(state.Cycle(now), state.LongBreakRule().Every)
+
 	if state.CurrentBreak == nil {
-		workDuration := now.Sub(state.CurrentSession.StartTime)
-		fmt.Printf("Working on '%s' for %s\n",
+		workDuration := now.Sub(state.CurrentSession.FlowStart())
+		fmt.Printf("Working on '%s' for %s (%s)\n",
 			state.CurrentSession.Task,
-			flowtime.FormatDuration(workDuration))
+			flowtime.FormatDuration(workDuration),
+			cycle)
+		if interruptions := state.CurrentSession.Interruptions; len(interruptions) > 0 {
+			fmt.Printf("Interruptions: %s\n", flowtime.FormatInterruptions(interruptions))
+		}
 		return
 	}
 
+	kind := "Break"
+	if state.OnLongBreak() {
+		kind = "Long break"
+	}
 	breakDuration := now.Sub(state.CurrentBreak.StartTime)
 	remaining := state.CurrentBreak.SuggestedDuration - breakDuration
 
 	if remaining > 0 {
-		fmt.Printf("Break: %s remaining\n", flowtime.FormatDuration(remaining))
+		fmt.Printf("%s: %s remaining (%s)\n", kind, flowtime.FormatDuration(remaining), cycle)
 	} else {
 		elapsed := breakDuration - state.CurrentBreak.SuggestedDuration
-		fmt.Printf("Break: %s overtime\n", flowtime.FormatDuration(elapsed))
+		fmt.Printf("%s: %s overtime (%s)\n", kind, flowtime.FormatDuration(elapsed), cycle)
 	}
 }
 
@@ -45,7 +58,7 @@ func PrintLog(sessions []flowtime.CompletedSession, page, count int, now time.Ti
 
 	t := table.New().
 		Border(lipgloss.RoundedBorder()).
-		Headers("COMPLETED AT", "TASK", "DURATION", "BREAK").
+		Headers("ID", "COMPLETED AT", "TASK", "PROJECT", "TAGS", "DURATION", "BREAK", "INT").
 		StyleFunc(func(row, col int) lipgloss.Style {
 			baseStyle := lipgloss.NewStyle().Padding(0, 1)
 
@@ -64,12 +77,322 @@ func PrintLog(sessions []flowtime.CompletedSession, page, count int, now time.Ti
 		}
 
 		t.Row(
+			session.ID,
 			flowtime.FormatHumanDateTime(session.CompletedAt, now),
 			session.Task,
+			session.Project,
+			strings.Join(session.Tags, ", "),
 			flowtime.FormatDuration(session.FlowDuration),
 			breakInfo,
+			strconv.Itoa(len(session.Interruptions)),
 		)
 	}
 
 	fmt.Printf("Recent sessions:\n%s\n", t.Render())
 }
+
+// PrintReport prints a report's rows and their total as a table to stdout.
+func PrintReport(rows []flowtime.ReportRow, by flowtime.ReportGrouping) {
+	if len(rows) == 0 {
+		fmt.Println("No sessions to report")
+		return
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		Headers(strings.ToUpper(string(by)), "SESSIONS", "FLOW", "BREAK", "AVG FLOW").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			baseStyle := lipgloss.NewStyle().Padding(0, 1)
+
+			if row == table.HeaderRow || row == len(rows) {
+				baseStyle = baseStyle.Bold(true)
+			}
+			if col > 0 {
+				baseStyle = baseStyle.Align(lipgloss.Right)
+			}
+
+			return baseStyle
+		})
+
+	for _, r := range append(rows, flowtime.ReportTotal(rows)) {
+		group := r.Group
+		if by == flowtime.ByDay && !r.Start.IsZero() {
+			group = r.Start.Format("Mon 2006-01-02")
+		}
+		t.Row(
+			group,
+			strconv.Itoa(r.Sessions),
+			flowtime.FormatDuration(r.Flow),
+			flowtime.FormatDuration(r.Break),
+			flowtime.FormatDuration(r.AverageFlow()),
+		)
+	}
+
+	fmt.Printf("Time by %s:\n%s\n", by, t.Render())
+}
+
+// PrintSession prints a completed work block and its flow and break intervals to stdout.
+func PrintSession(session flowtime.CompletedSession, now time.Time) {
+	breakInfo := "none"
+	if session.BreakDuration != nil {
+		breakInfo = flowtime.FormatDuration(*session.BreakDuration)
+	}
+
+	fmt.Printf("Session %s: %s\n", session.ID, session.Task)
+	if labels := flowtime.FormatLabels(session.Project, session.Tags); labels != "" {
+		fmt.Printf("Labels: %s\n", labels)
+	}
+	fmt.Printf("Completed: %s\n", flowtime.FormatHumanDateTime(session.CompletedAt, now))
+	fmt.Printf("Flow: %s, break: %s\n", flowtime.FormatDuration(session.FlowDuration), breakInfo)
+	if session.DeletedAt != nil {
+		fmt.Printf("Deleted: %s\n", flowtime.FormatHumanDateTime(*session.DeletedAt, now))
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		Headers("#", "KIND", "START", "END", "DURATION").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			baseStyle := lipgloss.NewStyle().Padding(0, 1)
+
+			if row == table.HeaderRow {
+				baseStyle = baseStyle.Bold(true)
+			}
+
+			return baseStyle
+		})
+
+	for i, iv := range session.Intervals {
+		t.Row(
+			strconv.Itoa(i+1),
+			string(iv.Kind),
+			iv.Start.Format("15:04:05"),
+			iv.End.Format("15:04:05"),
+			flowtime.FormatDuration(iv.Duration()),
+		)
+	}
+
+	fmt.Printf("Intervals:\n%s\n", t.Render())
+
+	if len(session.Interruptions) > 0 {
+		fmt.Printf("Interruptions: %s\n", flowtime.FormatInterruptions(session.Interruptions))
+		for _, in := range session.Interruptions {
+			fmt.Printf("  %s  %s\n", in.At.Format("15:04"), interruptionDetails(in))
+		}
+	}
+
+	if len(session.Notes) > 0 {
+		fmt.Println("Notes:")
+		for _, n := range session.Notes {
+			fmt.Printf("  %s  %s\n", n.At.Format("15:04"), n.Text)
+		}
+	}
+}
+
+// interruptionDetails describes an interruption's kind and reason, e.g.
+// "external: colleague question".
+func interruptionDetails(in flowtime.Interruption) string {
+	switch {
+	case in.Kind != "" && in.Reason != "":
+		return string(in.Kind) + ": " + in.Reason
+	case in.Kind != "":
+		return string(in.Kind)
+	case in.Reason != "":
+		return in.Reason
+	}
+	return "-"
+}
+
+// PrintTrash prints deleted sessions as a paginated table to stdout.
+func PrintTrash(sessions []flowtime.CompletedSession, page, count int, now time.Time) {
+	if len(sessions) == 0 {
+		fmt.Println("Trash is empty")
+		return
+	}
+
+	paginated := paginate.ReversePaginate(sessions, page, count)
+	fmt.Printf("Deleted sessions:\n%s\n", deletedSessionsTable(paginated, now))
+}
+
+// deletedSessionsTable renders deleted sessions, in the given order, as a table.
+func deletedSessionsTable(sessions []flowtime.CompletedSession, now time.Time) string {
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		Headers("ID", "COMPLETED AT", "TASK", "DURATION", "DELETED AT").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			baseStyle := lipgloss.NewStyle().Padding(0, 1)
+
+			if row == table.HeaderRow {
+				baseStyle = baseStyle.Bold(true)
+			}
+
+			return baseStyle
+		})
+
+	for _, session := range sessions {
+		t.Row(
+			session.ID,
+			flowtime.FormatHumanDateTime(session.CompletedAt, now),
+			session.Task,
+			flowtime.FormatDuration(session.FlowDuration),
+			flowtime.FormatHumanDateTime(*session.DeletedAt, now),
+		)
+	}
+
+	return t.Render()
+}
+
+// PrintPurge prints the deleted sessions that a purge removes, oldest first.
+func PrintPurge(sessions []flowtime.CompletedSession, dryRun bool, now time.Time) {
+	verb := "Purging"
+	if dryRun {
+		verb = "Would purge"
+	}
+	fmt.Printf("%s %d deleted sessions:\n%s\n", verb, len(sessions), deletedSessionsTable(sessions, now))
+}
+
+// PrintHistory prints recorded state transitions as a paginated table to stdout.
+func PrintHistory(events []flowtime.Event, page, count int, now time.Time) {
+	if len(events) == 0 {
+		fmt.Println("No recorded events")
+		return
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		Headers("AT", "EVENT", "DETAILS").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			baseStyle := lipgloss.NewStyle().Padding(0, 1)
+
+			if row == table.HeaderRow {
+				baseStyle = baseStyle.Bold(true)
+			}
+
+			return baseStyle
+		})
+
+	for _, e := range paginate.ReversePaginate(events, page, count) {
+		t.Row(
+			flowtime.FormatHumanDateTime(e.At, now),
+			string(e.Kind),
+			eventDetails(e),
+		)
+	}
+
+	fmt.Printf("Recent events:\n%s\n", t.Render())
+}
+
+// describeEvent names an event and when it happened, with its details if any,
+// e.g. "stop from Today 14:02 (completed 3f9c2a1b)".
+func describeEvent(e flowtime.Event, now time.Time) string {
+	s := fmt.Sprintf("%s from %s", e.Kind, flowtime.FormatHumanDateTime(e.At, now))
+	if details := eventDetails(e); details != "" {
+		s += " (" + details + ")"
+	}
+	return s
+}
+
+// eventDetails summarises the payload of an event for display.
+func eventDetails(e flowtime.Event) string {
+	switch e.Kind {
+	case flowtime.EventStart:
+		return e.Task
+	case flowtime.EventNote:
+		return e.Text
+	case flowtime.EventInterrupt:
+		return interruptionDetails(flowtime.Interruption{Kind: e.Interruption, Reason: e.Text})
+	case flowtime.EventBreak:
+		return "suggested " + flowtime.FormatDuration(e.SuggestedBreak)
+	case flowtime.EventSwitch:
+		return fmt.Sprintf("completed %s, started %s", e.SessionID, e.Task)
+	case flowtime.EventStop:
+		if e.Text != "" {
+			return fmt.Sprintf("completed %s: %s", e.SessionID, e.Text)
+		}
+		if e.SessionID != "" {
+			return "completed " + e.SessionID
+		}
+	case flowtime.EventAdd:
+		return "session " + e.Session.ID
+	case flowtime.EventEdit:
+		return "session " + e.SessionID
+	case flowtime.EventDelete, flowtime.EventRestore:
+		if e.SessionID != "" {
+			return "session " + e.SessionID
+		}
+		return fmt.Sprintf("session entry %d", e.Index+1)
+	case flowtime.EventRestoreSince:
+		return "deleted since " + e.Since.Format("2006-01-02 15:04")
+	case flowtime.EventPurge:
+		return "deleted before " + e.Before.Format("2006-01-02 15:04")
+	case flowtime.EventReplace:
+		return fmt.Sprintf("%d sessions", len(e.Snapshot.CompletedSessions))
+	case flowtime.EventUndo, flowtime.EventRedo:
+		return string(e.Undone)
+	}
+	return ""
+}
+
+// PrintBackups prints the available backups, newest first, to stdout.
+func PrintBackups(backups []storage.Backup, now time.Time) {
+	if len(backups) == 0 {
+		fmt.Println("No backups")
+		return
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		Headers("ID", "CREATED AT").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			baseStyle := lipgloss.NewStyle().Padding(0, 1)
+
+			if row == table.HeaderRow {
+				baseStyle = baseStyle.Bold(true)
+			}
+
+			return baseStyle
+		})
+
+	for i := len(backups) - 1; i >= 0; i-- {
+		t.Row(backups[i].ID, flowtime.FormatHumanDateTime(backups[i].CreatedAt, now))
+	}
+
+	fmt.Printf("Backups:\n%s\n", t.Render())
+}
+
+// PrintProfiles prints the known profiles to stdout, marking the current one.
+func PrintProfiles(profiles []storage.Profile, current string) {
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		Headers("", "PROFILE", "DIRECTORY").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			baseStyle := lipgloss.NewStyle().Padding(0, 1)
+
+			if row == table.HeaderRow {
+				baseStyle = baseStyle.Bold(true)
+			}
+
+			return baseStyle
+		})
+
+	for _, p := range profiles {
+		marker := ""
+		if p.Name == current {
+			marker = "*"
+		}
+		t.Row(marker, p.Name, p.Dir)
+	}
+
+	fmt.Println(t.Render())
+}
+
+// PrintProblems prints the problems found by a state check to stdout.
+func PrintProblems(problems []flowtime.Problem) {
+	fmt.Printf("Found %d problems:\n", len(problems))
+	for _, p := range problems {
+		fix := "needs manual fix"
+		if p.Fixable {
+			fix = "fixable"
+		}
+		fmt.Printf("  - [%s, %s] %s\n", p.Code, fix, p.Message)
+	}
+}
@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+// Output formats selected with --output. Every format other than OutputText
+// writes the records defined in this file, whose fields are documented in the
+// README and kept stable: timestamps are RFC 3339 and durations are whole seconds.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputCSV  = "csv"
+	OutputTSV  = "tsv"
+)
+
+// record is the machine-readable form of one item of output. fields returns
+// its values for CSV and TSV, in the order of the record type's columns.
+type record interface {
+	fields() []string
+}
+
+// statusRecord describes the current state. State is "idle", "flow" or "break";
+// the session fields are empty when idle and the break fields when not on a break.
+type statusRecord struct {
+	State                 string   `json:"state"`
+	Task                  string   `json:"task"`
+	Project               string   `json:"project"`
+	Tags                  []string `json:"tags"`
+	SessionStart          *string  `json:"session_start"`
+	IntervalStart         *string  `json:"interval_start"`
+	ElapsedSeconds        int64    `json:"elapsed_seconds"`
+	SuggestedBreakSeconds int64    `json:"suggested_break_seconds"`
+	LongBreak             bool     `json:"long_break"`
+	Cycle                 int      `json:"cycle"`
+	Interruptions         int      `json:"interruptions"`
+}
+
+var statusColumns = []string{
+	"state", "task", "project", "tags", "session_start", "interval_start",
+	"elapsed_seconds", "suggested_break_seconds", "long_break", "cycle", "interruptions",
+}
+
+func newStatusRecord(state *flowtime.FlowState, now time.Time) statusRecord {
+	r := statusRecord{State: "idle", Tags: []string{}, Cycle: state.Cycle(now)}
+	session := state.CurrentSession
+	if session == nil {
+		return r
+	}
+
+	r.State = "flow"
+	r.Task = session.Task
+	r.Project = session.Project
+	r.Tags = nonNil(session.Tags)
+	r.SessionStart = isoTime(session.StartTime)
+	r.Interruptions = len(session.Interruptions)
+	intervalStart := session.FlowStart()
+	if brk := state.CurrentBreak; brk != nil {
+		r.State = "break"
+		intervalStart = brk.StartTime
+		r.SuggestedBreakSeconds = seconds(brk.SuggestedDuration)
+		r.LongBreak = state.OnLongBreak()
+	}
+	r.IntervalStart = isoTime(intervalStart)
+	r.ElapsedSeconds = seconds(now.Sub(intervalStart))
+	return r
+}
+
+func (r statusRecord) fields() []string {
+	return []string{
+		r.State, r.Task, r.Project, strings.Join(r.Tags, ","), deref(r.SessionStart), deref(r.IntervalStart),
+		strconv.FormatInt(r.ElapsedSeconds, 10), strconv.FormatInt(r.SuggestedBreakSeconds, 10),
+		strconv.FormatBool(r.LongBreak), strconv.Itoa(r.Cycle), strconv.Itoa(r.Interruptions),
+	}
+}
+
+// sessionRecord describes a completed session. DeletedAt is null unless the
+// session is in the trash.
+type sessionRecord struct {
+	ID            string   `json:"id"`
+	Task          string   `json:"task"`
+	Project       string   `json:"project"`
+	Tags          []string `json:"tags"`
+	StartedAt     *string  `json:"started_at"`
+	CompletedAt   *string  `json:"completed_at"`
+	FlowSeconds   int64    `json:"flow_seconds"`
+	BreakSeconds  int64    `json:"break_seconds"`
+	Interruptions int      `json:"interruptions"`
+	Notes         int      `json:"notes"`
+	DeletedAt     *string  `json:"deleted_at"`
+}
+
+var sessionColumns = []string{
+	"id", "task", "project", "tags", "started_at", "completed_at",
+	"flow_seconds", "break_seconds", "interruptions", "notes", "deleted_at",
+}
+
+func newSessionRecord(cs flowtime.CompletedSession) sessionRecord {
+	r := sessionRecord{
+		ID:            cs.ID,
+		Task:          cs.Task,
+		Project:       cs.Project,
+		Tags:          nonNil(cs.Tags),
+		StartedAt:     isoTime(cs.Start()),
+		CompletedAt:   isoTime(cs.CompletedAt),
+		FlowSeconds:   seconds(cs.FlowDuration),
+		Interruptions: len(cs.Interruptions),
+		Notes:         len(cs.Notes),
+	}
+	if cs.BreakDuration != nil {
+		r.BreakSeconds = seconds(*cs.BreakDuration)
+	}
+	if cs.DeletedAt != nil {
+		r.DeletedAt = isoTime(*cs.DeletedAt)
+	}
+	return r
+}
+
+func (r sessionRecord) fields() []string {
+	return []string{
+		r.ID, r.Task, r.Project, strings.Join(r.Tags, ","), deref(r.StartedAt), deref(r.CompletedAt),
+		strconv.FormatInt(r.FlowSeconds, 10), strconv.FormatInt(r.BreakSeconds, 10),
+		strconv.Itoa(r.Interruptions), strconv.Itoa(r.Notes), deref(r.DeletedAt),
+	}
+}
+
+// writeSessionRecords writes sessions to w as sessionRecords in the given format.
+func writeSessionRecords(w io.Writer, format string, sessions []flowtime.CompletedSession) error {
+	records := make([]sessionRecord, len(sessions))
+	for i, cs := range sessions {
+		records[i] = newSessionRecord(cs)
+	}
+	return writeRecords(w, format, sessionColumns, records)
+}
+
+// reportRecord describes one row of a report. Start is null when grouping by task.
+type reportRecord struct {
+	Group              string  `json:"group"`
+	Start              *string `json:"start"`
+	Sessions           int     `json:"sessions"`
+	FlowSeconds        int64   `json:"flow_seconds"`
+	BreakSeconds       int64   `json:"break_seconds"`
+	AverageFlowSeconds int64   `json:"average_flow_seconds"`
+}
+
+var reportColumns = []string{"group", "start", "sessions", "flow_seconds", "break_seconds", "average_flow_seconds"}
+
+func newReportRecord(r flowtime.ReportRow) reportRecord {
+	rec := reportRecord{
+		Group:              r.Group,
+		Sessions:           r.Sessions,
+		FlowSeconds:        seconds(r.Flow),
+		BreakSeconds:       seconds(r.Break),
+		AverageFlowSeconds: seconds(r.AverageFlow()),
+	}
+	if !r.Start.IsZero() {
+		rec.Start = isoTime(r.Start)
+	}
+	return rec
+}
+
+func (r reportRecord) fields() []string {
+	return []string{
+		r.Group, deref(r.Start), strconv.Itoa(r.Sessions), strconv.FormatInt(r.FlowSeconds, 10),
+		strconv.FormatInt(r.BreakSeconds, 10), strconv.FormatInt(r.AverageFlowSeconds, 10),
+	}
+}
+
+// locationRecord describes where the selected profile's state is stored.
+type locationRecord struct {
+	Profile string `json:"profile"`
+	Path    string `json:"path"`
+}
+
+var locationColumns = []string{"profile", "path"}
+
+func (r locationRecord) fields() []string {
+	return []string{r.Profile, r.Path}
+}
+
+// writeRecord writes a single record to w in the given format: a JSON object,
+// or a header row and one row of CSV or TSV.
+func writeRecord[R record](w io.Writer, format string, columns []string, r R) error {
+	if format == OutputJSON {
+		return writeJSON(w, r)
+	}
+	return writeDelimited(w, format, columns, []R{r})
+}
+
+// writeRecords writes records to w in the given format: a JSON array, or a
+// header row and one row per record of CSV or TSV.
+func writeRecords[R record](w io.Writer, format string, columns []string, records []R) error {
+	if format == OutputJSON {
+		if records == nil {
+			records = []R{}
+		}
+		return writeJSON(w, records)
+	}
+	return writeDelimited(w, format, columns, records)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("writing JSON: %w", err)
+	}
+	return nil
+}
+
+func writeDelimited[R record](w io.Writer, format string, columns []string, records []R) error {
+	cw := csv.NewWriter(w)
+	switch format {
+	case OutputCSV:
+	case OutputTSV:
+		cw.Comma = '\t'
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	cw.Write(columns)
+	for _, r := range records {
+		cw.Write(r.fields())
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("writing %s: %w", strings.ToUpper(format), err)
+	}
+	return nil
+}
+
+// isoTime formats t as RFC 3339 for a record.
+func isoTime(t time.Time) *string {
+	s := t.Format(time.RFC3339)
+	return &s
+}
+
+// seconds returns d in whole seconds for a record.
+func seconds(d time.Duration) int64 {
+	return int64(d / time.Second)
+}
+
+// nonNil returns tags, or an empty list instead of nil so JSON shows [] rather than null.
+func nonNil(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+// These tests pin the machine-readable output documented in the README: field
+// names, their order, RFC 3339 timestamps and durations in whole seconds.
+// Scripts depend on them, so a change here is a breaking change.
+
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+// assertFormats writes with each output format and compares the result to want.
+func assertFormats(t *testing.T, write func(w io.Writer, format string) error, want map[string]string) {
+	t.Helper()
+	for _, format := range []string{OutputJSON, OutputCSV, OutputTSV} {
+		t.Run(format, func(t *testing.T) {
+			var b strings.Builder
+			if err := write(&b, format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := b.String(); got != want[format] {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want[format])
+			}
+		})
+	}
+}
+
+func TestStatusRecord(t *testing.T) {
+	t.Run("idle", func(t *testing.T) {
+		clock := &testClock{now: time.Date(2025, 6, 15, 9, 0, 0, 0, time.UTC)}
+		state := flowtime.NewFlowState(clock)
+
+		assertFormats(t, func(w io.Writer, format string) error {
+			return writeRecord(w, format, statusColumns, newStatusRecord(state, clock.now))
+		}, map[string]string{
+			OutputJSON: `{
+  "state": "idle",
+  "task": "",
+  "project": "",
+  "tags": [],
+  "session_start": null,
+  "interval_start": null,
+  "elapsed_seconds": 0,
+  "suggested_break_seconds": 0,
+  "long_break": false,
+  "cycle": 1,
+  "interruptions": 0
+}
+`,
+			OutputCSV: `state,task,project,tags,session_start,interval_start,elapsed_seconds,suggested_break_seconds,long_break,cycle,interruptions
+idle,,,,,,0,0,false,1,0
+`,
+			OutputTSV: "state\ttask\tproject\ttags\tsession_start\tinterval_start\telapsed_seconds\tsuggested_break_seconds\tlong_break\tcycle\tinterruptions\n" +
+				"idle\t\t\t\t\t\t0\t0\tfalse\t1\t0\n",
+		})
+	})
+
+	t.Run("break", func(t *testing.T) {
+		clock := &testClock{now: time.Date(2025, 6, 15, 9, 0, 0, 0, time.UTC)}
+		state := flowtime.NewFlowState(clock)
+		if err := state.StartSession(`Fix login, "again" +auth @review @urgent`); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		clock.now = clock.now.Add(10 * time.Minute)
+		if err := state.Interrupt(flowtime.InterruptionExternal, "call"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		clock.now = clock.now.Add(40 * time.Minute)
+		if err := state.TakeBreak(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		now := clock.now.Add(3*time.Minute + 30*time.Second)
+
+		assertFormats(t, func(w io.Writer, format string) error {
+			return writeRecord(w, format, statusColumns, newStatusRecord(state, now))
+		}, map[string]string{
+			OutputJSON: `{
+  "state": "break",
+  "task": "Fix login, \"again\"",
+  "project": "auth",
+  "tags": [
+    "review",
+    "urgent"
+  ],
+  "session_start": "2025-06-15T09:00:00Z",
+  "interval_start": "2025-06-15T09:50:00Z",
+  "elapsed_seconds": 210,
+  "suggested_break_seconds": 480,
+  "long_break": false,
+  "cycle": 1,
+  "interruptions": 1
+}
+`,
+			OutputCSV: `state,task,project,tags,session_start,interval_start,elapsed_seconds,suggested_break_seconds,long_break,cycle,interruptions
+break,"Fix login, ""again""",auth,"review,urgent",2025-06-15T09:00:00Z,2025-06-15T09:50:00Z,210,480,false,1,1
+`,
+			OutputTSV: "state\ttask\tproject\ttags\tsession_start\tinterval_start\telapsed_seconds\tsuggested_break_seconds\tlong_break\tcycle\tinterruptions\n" +
+				"break\t\"Fix login, \"\"again\"\"\"\tauth\treview,urgent\t2025-06-15T09:00:00Z\t2025-06-15T09:50:00Z\t210\t480\tfalse\t1\t1\n",
+		})
+	})
+}
+
+func TestSessionRecords(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2025, 6, 15, hour, min, 0, 0, time.UTC) }
+	brk := 8 * time.Minute
+	deletedAt := at(12, 0)
+	sessions := []flowtime.CompletedSession{
+		{
+			ID:      "3f9c2a1b",
+			Task:    "Fix login",
+			Project: "auth",
+			Tags:    []string{"review"},
+			Intervals: []flowtime.Interval{
+				{Kind: flowtime.IntervalFlow, Start: at(9, 0), End: at(9, 50)},
+				{Kind: flowtime.IntervalBreak, Start: at(9, 50), End: at(9, 58)},
+			},
+			Notes:         []flowtime.Note{{At: at(9, 30), Text: "root cause"}},
+			Interruptions: []flowtime.Interruption{{At: at(9, 10), Kind: flowtime.InterruptionExternal}},
+			FlowDuration:  50 * time.Minute,
+			BreakDuration: &brk,
+			CompletedAt:   at(9, 58),
+		},
+		{ID: "77aa01cd", Task: "email", FlowDuration: 15 * time.Minute, CompletedAt: at(11, 0), DeletedAt: &deletedAt},
+	}
+
+	t.Run("sessions", func(t *testing.T) {
+		assertFormats(t, func(w io.Writer, format string) error {
+			return writeSessionRecords(w, format, sessions)
+		}, map[string]string{
+			OutputJSON: `[
+  {
+    "id": "3f9c2a1b",
+    "task": "Fix login",
+    "project": "auth",
+    "tags": [
+      "review"
+    ],
+    "started_at": "2025-06-15T09:00:00Z",
+    "completed_at": "2025-06-15T09:58:00Z",
+    "flow_seconds": 3000,
+    "break_seconds": 480,
+    "interruptions": 1,
+    "notes": 1,
+    "deleted_at": null
+  },
+  {
+    "id": "77aa01cd",
+    "task": "email",
+    "project": "",
+    "tags": [],
+    "started_at": "2025-06-15T11:00:00Z",
+    "completed_at": "2025-06-15T11:00:00Z",
+    "flow_seconds": 900,
+    "break_seconds": 0,
+    "interruptions": 0,
+    "notes": 0,
+    "deleted_at": "2025-06-15T12:00:00Z"
+  }
+]
+`,
+			OutputCSV: `id,task,project,tags,started_at,completed_at,flow_seconds,break_seconds,interruptions,notes,deleted_at
+3f9c2a1b,Fix login,auth,review,2025-06-15T09:00:00Z,2025-06-15T09:58:00Z,3000,480,1,1,
+77aa01cd,email,,,2025-06-15T11:00:00Z,2025-06-15T11:00:00Z,900,0,0,0,2025-06-15T12:00:00Z
+`,
+			OutputTSV: "id\ttask\tproject\ttags\tstarted_at\tcompleted_at\tflow_seconds\tbreak_seconds\tinterruptions\tnotes\tdeleted_at\n" +
+				"3f9c2a1b\tFix login\tauth\treview\t2025-06-15T09:00:00Z\t2025-06-15T09:58:00Z\t3000\t480\t1\t1\t\n" +
+				"77aa01cd\temail\t\t\t2025-06-15T11:00:00Z\t2025-06-15T11:00:00Z\t900\t0\t0\t0\t2025-06-15T12:00:00Z\n",
+		})
+	})
+
+	t.Run("none", func(t *testing.T) {
+		assertFormats(t, func(w io.Writer, format string) error {
+			return writeSessionRecords(w, format, nil)
+		}, map[string]string{
+			OutputJSON: "[]\n",
+			OutputCSV:  "id,task,project,tags,started_at,completed_at,flow_seconds,break_seconds,interruptions,notes,deleted_at\n",
+			OutputTSV:  "id\ttask\tproject\ttags\tstarted_at\tcompleted_at\tflow_seconds\tbreak_seconds\tinterruptions\tnotes\tdeleted_at\n",
+		})
+	})
+}
+
+func TestLocationRecord(t *testing.T) {
+	r := locationRecord{Profile: "work", Path: "/home/me/.local/share/flower/profiles/work/state.json"}
+
+	assertFormats(t, func(w io.Writer, format string) error {
+		return writeRecord(w, format, locationColumns, r)
+	}, map[string]string{
+		OutputJSON: `{
+  "profile": "work",
+  "path": "/home/me/.local/share/flower/profiles/work/state.json"
+}
+`,
+		OutputCSV: "profile,path\nwork,/home/me/.local/share/flower/profiles/work/state.json\n",
+		OutputTSV: "profile\tpath\nwork\t/home/me/.local/share/flower/profiles/work/state.json\n",
+	})
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+	"github.com/adrg/xdg"
+)
+
+// Config holds user settings read from the config file. The zero value is the
+// default configuration.
+type Config struct {
+	// Retention is how long deleted sessions are kept before they are purged
+	// permanently. Zero keeps them forever.
+	Retention Duration `json:"retention,omitempty"`
+
+	// Break selects how break durations are suggested.
+	Break BreakConfig `json:"break,omitempty"`
+}
+
+// Break policy names accepted in BreakConfig.Policy.
+const (
+	PolicyTiered = "tiered"
+	PolicyRatio  = "ratio"
+	PolicyFixed  = "fixed"
+)
+
+// BreakConfig configures the break policy. Only the fields of the selected
+// policy are used; an empty policy means the default tiers.
+type BreakConfig struct {
+	Policy string `json:"policy,omitempty"`
+
+	// tiered: flow up to each tier's up_to earns its break; longer flow earns Beyond.
+	Tiers  []TierConfig `json:"tiers,omitempty"`
+	Beyond Duration     `json:"beyond,omitempty"`
+
+	// ratio: the break is Ratio times the flow, clamped to [Min, Max].
+	Ratio float64  `json:"ratio,omitempty"`
+	Min   Duration `json:"min,omitempty"`
+	Max   Duration `json:"max,omitempty"`
+
+	// fixed: the break is always Duration.
+	Duration Duration `json:"duration,omitempty"`
+
+	// Every LongEvery-th break of the day lasts LongDuration instead,
+	// whatever the policy. Zero disables long breaks.
+	LongEvery    int      `json:"long_every,omitempty"`
+	LongDuration Duration `json:"long_duration,omitempty"`
+}
+
+// TierConfig is one tier of the tiered break policy.
+type TierConfig struct {
+	UpTo  Duration `json:"up_to"`
+	Break Duration `json:"break"`
+}
+
+// LongBreakRule builds the configured long break rule, or returns an error if
+// the configuration is invalid.
+func (c BreakConfig) LongBreakRule() (flowtime.LongBreakRule, error) {
+	r := flowtime.LongBreakRule{Every: c.LongEvery, Duration: time.Duration(c.LongDuration)}
+	return r, r.Validate()
+}
+
+// BreakPolicy builds the configured break policy, or returns an error if the
+// configuration is invalid.
+func (c BreakConfig) BreakPolicy() (flowtime.BreakPolicy, error) {
+	switch c.Policy {
+	case "":
+		return flowtime.DefaultBreakPolicy, nil
+	case PolicyTiered:
+		p := flowtime.TieredPolicy{Beyond: time.Duration(c.Beyond)}
+		for _, tier := range c.Tiers {
+			p.Tiers = append(p.Tiers, flowtime.Tier{UpTo: time.Duration(tier.UpTo), Break: time.Duration(tier.Break)})
+		}
+		return p, p.Validate()
+	case PolicyRatio:
+		p := flowtime.RatioPolicy{Ratio: c.Ratio, Min: time.Duration(c.Min), Max: time.Duration(c.Max)}
+		return p, p.Validate()
+	case PolicyFixed:
+		p := flowtime.FixedPolicy{Duration: time.Duration(c.Duration)}
+		return p, p.Validate()
+	default:
+		return nil, fmt.Errorf("%w: unknown policy %q (use %q, %q or %q)",
+			flowtime.ErrInvalidBreakPolicy, c.Policy, PolicyTiered, PolicyRatio, PolicyFixed)
+	}
+}
+
+// Duration is a time.Duration written in config files as a string such as
+// "90m" or "30d".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30d\": %w", err)
+	}
+	parsed, err := flowtime.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Path returns the location of the config file.
+func Path() string {
+	return filepath.Join(xdg.ConfigHome, "flower", "config.json")
+}
+
+// Load reads the config file. A missing file yields the default configuration.
+func Load() (*Config, error) {
+	path := Path()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file %q: %w", path, err)
+	}
+	if _, err := cfg.Break.BreakPolicy(); err != nil {
+		return nil, fmt.Errorf("config file %q: break: %w", path, err)
+	}
+	if _, err := cfg.Break.LongBreakRule(); err != nil {
+		return nil, fmt.Errorf("config file %q: break: %w", path, err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+	"github.com/adrg/xdg"
+)
+
+func TestLoad(t *testing.T) {
+	configHome := xdg.ConfigHome
+	t.Cleanup(func() { xdg.ConfigHome = configHome })
+
+	t.Run("missing file yields defaults", func(t *testing.T) {
+		xdg.ConfigHome = t.TempDir()
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Retention != 0 {
+			t.Errorf("retention = %v, want 0", time.Duration(cfg.Retention))
+		}
+	})
+
+	t.Run("parses retention", func(t *testing.T) {
+		xdg.ConfigHome = t.TempDir()
+		writeConfig(t, `{"retention": "30d"}`)
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := time.Duration(cfg.Retention), 30*24*time.Hour; got != want {
+			t.Errorf("retention = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("rejects invalid retention", func(t *testing.T) {
+		xdg.ConfigHome = t.TempDir()
+		writeConfig(t, `{"retention": "soon"}`)
+
+		if _, err := Load(); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestBreakPolicy(t *testing.T) {
+	configHome := xdg.ConfigHome
+	t.Cleanup(func() { xdg.ConfigHome = configHome })
+
+	tests := []struct {
+		name   string
+		config string
+		want   flowtime.BreakPolicy
+	}{
+		{"default", `{}`, flowtime.DefaultBreakPolicy},
+		{
+			"tiered",
+			`{"break": {"policy": "tiered", "tiers": [{"up_to": "30m", "break": "5m"}, {"up_to": "1h", "break": "10m"}], "beyond": "20m"}}`,
+			flowtime.TieredPolicy{
+				Tiers:  []flowtime.Tier{{UpTo: 30 * time.Minute, Break: 5 * time.Minute}, {UpTo: time.Hour, Break: 10 * time.Minute}},
+				Beyond: 20 * time.Minute,
+			},
+		},
+		{
+			"ratio",
+			`{"break": {"policy": "ratio", "ratio": 0.2, "min": "5m", "max": "30m"}}`,
+			flowtime.RatioPolicy{Ratio: 0.2, Min: 5 * time.Minute, Max: 30 * time.Minute},
+		},
+		{
+			"fixed",
+			`{"break": {"policy": "fixed", "duration": "10m"}}`,
+			flowtime.FixedPolicy{Duration: 10 * time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xdg.ConfigHome = t.TempDir()
+			writeConfig(t, tt.config)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := cfg.Break.BreakPolicy()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("policy = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("long breaks", func(t *testing.T) {
+		xdg.ConfigHome = t.TempDir()
+		writeConfig(t, `{"break": {"long_every": 4, "long_duration": "30m"}}`)
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := cfg.Break.LongBreakRule()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := (flowtime.LongBreakRule{Every: 4, Duration: 30 * time.Minute}); got != want {
+			t.Errorf("long break rule = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("rejects invalid policy", func(t *testing.T) {
+		for _, config := range []string{
+			`{"break": {"long_every": 4}}`,
+			`{"break": {"policy": "pomodoro"}}`,
+			`{"break": {"policy": "ratio", "ratio": 2}}`,
+			`{"break": {"policy": "fixed"}}`,
+		} {
+			xdg.ConfigHome = t.TempDir()
+			writeConfig(t, config)
+
+			if _, err := Load(); !errors.Is(err, flowtime.ErrInvalidBreakPolicy) {
+				t.Errorf("%s: error = %v, want %v", config, err, flowtime.ErrInvalidBreakPolicy)
+			}
+		}
+	})
+}
+
+func writeConfig(t *testing.T, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(Path()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(Path(), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,19 +1,121 @@
 package flowtime
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
-// CalculateBreak returns a suggested break duration based on how long the work session lasted.
-func CalculateBreak(workDuration time.Duration) time.Duration {
-	workMinutes := int(workDuration.Minutes())
+var ErrInvalidBreakPolicy = errors.New("invalid break policy")
+
+// BreakPolicy decides how long a break to suggest after a stretch of flow.
+type BreakPolicy interface {
+	SuggestBreak(flow time.Duration) time.Duration
+}
+
+// Tier maps flow lasting up to UpTo to a break of Break.
+type Tier struct {
+	UpTo  time.Duration
+	Break time.Duration
+}
+
+// TieredPolicy suggests the break of the first tier whose UpTo covers the flow,
+// counted in whole minutes, or Beyond if the flow outlasts every tier.
+type TieredPolicy struct {
+	Tiers  []Tier
+	Beyond time.Duration
+}
+
+// DefaultBreakPolicy is the policy used when none is configured: the tiers
+// suggested by the Flowtime Technique.
+var DefaultBreakPolicy = TieredPolicy{
+	Tiers: []Tier{
+		{UpTo: 25 * time.Minute, Break: 5 * time.Minute},
+		{UpTo: 50 * time.Minute, Break: 8 * time.Minute},
+		{UpTo: 90 * time.Minute, Break: 10 * time.Minute},
+	},
+	Beyond: 15 * time.Minute,
+}
+
+func (p TieredPolicy) SuggestBreak(flow time.Duration) time.Duration {
+	flow = flow.Truncate(time.Minute)
+	for _, tier := range p.Tiers {
+		if flow <= tier.UpTo {
+			return tier.Break
+		}
+	}
+	return p.Beyond
+}
+
+// Validate returns an error unless the tiers are in increasing order and every
+// break is positive.
+func (p TieredPolicy) Validate() error {
+	for i, tier := range p.Tiers {
+		if tier.Break <= 0 {
+			return fmt.Errorf("%w: tier %d has no break", ErrInvalidBreakPolicy, i+1)
+		}
+		if i > 0 && tier.UpTo <= p.Tiers[i-1].UpTo {
+			return fmt.Errorf("%w: tier %d does not cover more flow than the one before it", ErrInvalidBreakPolicy, i+1)
+		}
+	}
+	if p.Beyond <= 0 {
+		return fmt.Errorf("%w: no break for flow beyond the last tier", ErrInvalidBreakPolicy)
+	}
+	return nil
+}
 
-	if workMinutes <= 25 {
-		return 5 * time.Minute
+// RatioPolicy suggests a fixed fraction of the flow, e.g. 0.2 for one fifth,
+// clamped to [Min, Max]. A zero Max means no upper limit.
+type RatioPolicy struct {
+	Ratio float64
+	Min   time.Duration
+	Max   time.Duration
+}
+
+func (p RatioPolicy) SuggestBreak(flow time.Duration) time.Duration {
+	suggested := time.Duration(float64(flow) * p.Ratio).Round(time.Second)
+	if suggested < p.Min {
+		suggested = p.Min
+	}
+	if p.Max > 0 && suggested > p.Max {
+		suggested = p.Max
+	}
+	return suggested
+}
+
+// Validate returns an error unless the ratio is in (0, 1] and the bounds are consistent.
+func (p RatioPolicy) Validate() error {
+	if p.Ratio <= 0 || p.Ratio > 1 {
+		return fmt.Errorf("%w: ratio %v must be greater than 0 and at most 1", ErrInvalidBreakPolicy, p.Ratio)
 	}
-	if workMinutes <= 50 {
-		return 8 * time.Minute
+	if p.Min < 0 || p.Max < 0 {
+		return fmt.Errorf("%w: min and max cannot be negative", ErrInvalidBreakPolicy)
 	}
-	if workMinutes <= 90 {
-		return 10 * time.Minute
+	if p.Max > 0 && p.Min > p.Max {
+		return fmt.Errorf("%w: min %s is greater than max %s", ErrInvalidBreakPolicy, FormatDuration(p.Min), FormatDuration(p.Max))
+	}
+	return nil
+}
+
+// FixedPolicy suggests the same break whatever the flow.
+type FixedPolicy struct {
+	Duration time.Duration
+}
+
+func (p FixedPolicy) SuggestBreak(time.Duration) time.Duration {
+	return p.Duration
+}
+
+// Validate returns an error unless the break is positive.
+func (p FixedPolicy) Validate() error {
+	if p.Duration <= 0 {
+		return fmt.Errorf("%w: fixed break must be positive", ErrInvalidBreakPolicy)
 	}
-	return 15 * time.Minute
+	return nil
+}
+
+// CalculateBreak returns a suggested break duration based on how long the work
+// session lasted, using DefaultBreakPolicy.
+func CalculateBreak(workDuration time.Duration) time.Duration {
+	return DefaultBreakPolicy.SuggestBreak(workDuration)
 }
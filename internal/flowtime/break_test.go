@@ -1,6 +1,7 @@
 package flowtime
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -31,3 +32,80 @@ func TestCalculateBreak(t *testing.T) {
 		})
 	}
 }
+
+func TestRatioPolicy(t *testing.T) {
+	p := RatioPolicy{Ratio: 0.2, Min: 3 * time.Minute, Max: 20 * time.Minute}
+	tests := []struct {
+		work     time.Duration
+		expected time.Duration
+	}{
+		{5 * time.Minute, 3 * time.Minute},
+		{50 * time.Minute, 10 * time.Minute},
+		{52*time.Minute + 30*time.Second, 10*time.Minute + 30*time.Second},
+		{3 * time.Hour, 20 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := p.SuggestBreak(tt.work); got != tt.expected {
+			t.Errorf("SuggestBreak(%v) = %v, want %v", tt.work, got, tt.expected)
+		}
+	}
+
+	unbounded := RatioPolicy{Ratio: 0.25}
+	if got := unbounded.SuggestBreak(4 * time.Hour); got != time.Hour {
+		t.Errorf("unbounded SuggestBreak(4h) = %v, want 1h", got)
+	}
+}
+
+func TestFixedPolicy(t *testing.T) {
+	p := FixedPolicy{Duration: 7 * time.Minute}
+	for _, work := range []time.Duration{0, time.Minute, 3 * time.Hour} {
+		if got := p.SuggestBreak(work); got != 7*time.Minute {
+			t.Errorf("SuggestBreak(%v) = %v, want 7m", work, got)
+		}
+	}
+}
+
+func TestBreakPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy interface{ Validate() error }
+		valid  bool
+	}{
+		{"default tiers", DefaultBreakPolicy, true},
+		{"unordered tiers", TieredPolicy{Tiers: []Tier{{UpTo: time.Hour, Break: time.Minute}, {UpTo: time.Minute, Break: time.Minute}}, Beyond: time.Minute}, false},
+		{"tier without break", TieredPolicy{Tiers: []Tier{{UpTo: time.Hour}}, Beyond: time.Minute}, false},
+		{"tiers without beyond", TieredPolicy{Tiers: []Tier{{UpTo: time.Hour, Break: time.Minute}}}, false},
+		{"ratio", RatioPolicy{Ratio: 0.2, Min: time.Minute, Max: time.Hour}, true},
+		{"zero ratio", RatioPolicy{}, false},
+		{"ratio above one", RatioPolicy{Ratio: 1.5}, false},
+		{"min above max", RatioPolicy{Ratio: 0.2, Min: time.Hour, Max: time.Minute}, false},
+		{"fixed", FixedPolicy{Duration: time.Minute}, true},
+		{"zero fixed", FixedPolicy{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidBreakPolicy) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidBreakPolicy)
+			}
+		})
+	}
+}
+
+func TestTakeBreakUsesPolicy(t *testing.T) {
+	clock := newTestClock()
+	state := NewFlowState(clock)
+	state.SetBreakPolicy(FixedPolicy{Duration: 12 * time.Minute})
+
+	_ = state.StartSession("write code")
+	clock.Advance(10 * time.Minute)
+	if err := state.TakeBreak(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := state.CurrentBreak.SuggestedDuration; got != 12*time.Minute {
+		t.Errorf("suggested break = %v, want 12m", got)
+	}
+}
@@ -0,0 +1,100 @@
+package flowtime
+
+import (
+	"fmt"
+	"time"
+)
+
+// LongBreakRule replaces every Every-th break of the day with a break of
+// Duration. A zero Every disables long breaks.
+type LongBreakRule struct {
+	Every    int
+	Duration time.Duration
+}
+
+// Validate returns an error if the rule is enabled without a positive duration.
+func (r LongBreakRule) Validate() error {
+	if r.Every < 0 {
+		return fmt.Errorf("%w: long breaks cannot come every %d cycles", ErrInvalidBreakPolicy, r.Every)
+	}
+	if r.Every > 0 && r.Duration <= 0 {
+		return fmt.Errorf("%w: long break must be positive", ErrInvalidBreakPolicy)
+	}
+	return nil
+}
+
+// SetLongBreakRule sets the rule TakeBreak uses to suggest long breaks.
+func (s *FlowState) SetLongBreakRule(r LongBreakRule) {
+	s.longBreak = r
+}
+
+// LongBreakRule returns the rule TakeBreak uses to suggest long breaks.
+func (s *FlowState) LongBreakRule() LongBreakRule {
+	return s.longBreak
+}
+
+// BreaksOn returns how many breaks, including one in progress, started on the
+// same local day as day. Each break ends a flow/break cycle.
+func (s *FlowState) BreaksOn(day time.Time) int {
+	y, m, d := day.Date()
+	sameDay := func(t time.Time) bool {
+		ty, tm, td := t.In(day.Location()).Date()
+		return ty == y && tm == m && td == d
+	}
+	countBreaks := func(intervals []Interval) int {
+		n := 0
+		for _, iv := range intervals {
+			if iv.Kind == IntervalBreak && sameDay(iv.Start) {
+				n++
+			}
+		}
+		return n
+	}
+
+	count := 0
+	for _, cs := range s.CompletedSessions {
+		if cs.DeletedAt == nil {
+			count += countBreaks(cs.Intervals)
+		}
+	}
+	if s.CurrentSession != nil {
+		count += countBreaks(s.CurrentSession.Intervals)
+	}
+	if s.CurrentBreak != nil && sameDay(s.CurrentBreak.StartTime) {
+		count++
+	}
+	return count
+}
+
+// Cycle returns the 1-based number of the flow/break cycle in progress at now:
+// the current flow and the break that follows it, or the current break and the
+// flow before it. With long breaks enabled the count restarts after each long
+// break, so it runs from 1 to the rule's Every.
+func (s *FlowState) Cycle(now time.Time) int {
+	cycle := s.BreaksOn(now)
+	if s.CurrentBreak == nil {
+		cycle++
+	}
+	if every := s.longBreak.Every; every > 0 && cycle > 0 {
+		cycle = (cycle-1)%every + 1
+	}
+	return cycle
+}
+
+// OnLongBreak reports whether the current break is a long break.
+func (s *FlowState) OnLongBreak() bool {
+	if s.CurrentBreak == nil || s.longBreak.Every <= 0 {
+		return false
+	}
+	return s.BreaksOn(s.CurrentBreak.StartTime)%s.longBreak.Every == 0
+}
+
+// suggestBreak returns the break to suggest when a break starts at now after
+// flowing for flow: a long break if it completes a run of cycles, otherwise
+// whatever the break policy suggests.
+func (s *FlowState) suggestBreak(flow time.Duration, now time.Time) time.Duration {
+	if every := s.longBreak.Every; every > 0 && (s.BreaksOn(now)+1)%every == 0 {
+		return s.longBreak.Duration
+	}
+	return s.BreakPolicy().SuggestBreak(flow)
+}
@@ -0,0 +1,78 @@
+package flowtime
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLongBreakAfterCycles(t *testing.T) {
+	clock := newTestClock()
+	state := NewFlowState(clock)
+	state.SetLongBreakRule(LongBreakRule{Every: 3, Duration: 30 * time.Minute})
+
+	_ = state.StartSession("write code")
+	for i, want := range []time.Duration{5 * time.Minute, 5 * time.Minute, 30 * time.Minute, 5 * time.Minute} {
+		cycle := i%3 + 1
+		if got := state.Cycle(clock.Now()); got != cycle {
+			t.Errorf("flow %d: cycle = %d, want %d", i+1, got, cycle)
+		}
+
+		clock.Advance(20 * time.Minute)
+		if err := state.TakeBreak(); err != nil {
+			t.Fatalf("break %d: %v", i+1, err)
+		}
+		if got := state.CurrentBreak.SuggestedDuration; got != want {
+			t.Errorf("break %d: suggested = %v, want %v", i+1, got, want)
+		}
+		if got := state.OnLongBreak(); got != (want == 30*time.Minute) {
+			t.Errorf("break %d: long = %v", i+1, got)
+		}
+		if got := state.Cycle(clock.Now()); got != cycle {
+			t.Errorf("break %d: cycle = %d, want %d", i+1, got, cycle)
+		}
+
+		clock.Advance(5 * time.Minute)
+		if _, err := state.Resume(); err != nil {
+			t.Fatalf("resume %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestCycleCountsOnlyToday(t *testing.T) {
+	clock := newTestClock()
+	state := NewFlowState(clock)
+
+	_ = state.StartSession("yesterday")
+	clock.Advance(20 * time.Minute)
+	_ = state.TakeBreak()
+	clock.Advance(5 * time.Minute)
+	_, _ = state.Stop()
+	if got := state.Cycle(clock.Now()); got != 2 {
+		t.Errorf("cycle = %d, want 2", got)
+	}
+
+	clock.Advance(24 * time.Hour)
+	_ = state.StartSession("today")
+	if got := state.Cycle(clock.Now()); got != 1 {
+		t.Errorf("next day: cycle = %d, want 1", got)
+	}
+	if got := state.BreaksOn(clock.Now().Add(-24 * time.Hour)); got != 1 {
+		t.Errorf("breaks yesterday = %d, want 1", got)
+	}
+}
+
+func TestLongBreakRuleValidate(t *testing.T) {
+	if err := (LongBreakRule{}).Validate(); err != nil {
+		t.Errorf("disabled rule: unexpected error: %v", err)
+	}
+	if err := (LongBreakRule{Every: 4, Duration: 30 * time.Minute}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (LongBreakRule{Every: 4}).Validate(); !errors.Is(err, ErrInvalidBreakPolicy) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidBreakPolicy)
+	}
+	if err := (LongBreakRule{Every: -1, Duration: time.Minute}).Validate(); !errors.Is(err, ErrInvalidBreakPolicy) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidBreakPolicy)
+	}
+}
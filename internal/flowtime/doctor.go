@@ -0,0 +1,179 @@
+package flowtime
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxPlausibleDuration is the longest flow or break considered realistic; longer
+// ones almost certainly come from a forgotten session or a clock problem.
+const maxPlausibleDuration = 24 * time.Hour
+
+// Problem codes reported by Check.
+const (
+	ProblemOrphanBreak         = "orphan-break"
+	ProblemBreakBeforeSession  = "break-before-session"
+	ProblemFutureStart         = "future-start"
+	ProblemNegativeDuration    = "negative-duration"
+	ProblemImplausibleDuration = "implausible-duration"
+	ProblemFutureCompletion    = "future-completion"
+	ProblemUnsortedHistory     = "unsorted-history"
+	ProblemMissingID           = "missing-id"
+	ProblemDuplicateID         = "duplicate-id"
+	ProblemDuplicateSession    = "duplicate-session"
+	ProblemInvalidIntervals    = "invalid-intervals"
+	ProblemReplayFailed        = "replay-failed"
+)
+
+// Problem is a violated invariant found by Check.
+type Problem struct {
+	Code      string
+	SessionID string // affected completed session, if any
+	Message   string
+	Fixable   bool // whether Repair fixes it
+}
+
+// Check reports every invariant the state violates, relative to now.
+func (s *FlowState) Check(now time.Time) []Problem {
+	var problems []Problem
+	add := func(code, sessionID string, fixable bool, format string, args ...any) {
+		problems = append(problems, Problem{
+			Code:      code,
+			SessionID: sessionID,
+			Message:   fmt.Sprintf(format, args...),
+			Fixable:   fixable,
+		})
+	}
+
+	if s.CurrentBreak != nil && s.CurrentSession == nil {
+		add(ProblemOrphanBreak, "", true,
+			"a break started at %s is in progress without a session; it will be discarded",
+			s.CurrentBreak.StartTime.Format(time.DateTime))
+	}
+	if s.CurrentSession != nil {
+		if s.CurrentSession.StartTime.After(now) {
+			add(ProblemFutureStart, "", false,
+				"the current session %q starts in the future (%s); cancel it and start again",
+				s.CurrentSession.Task, s.CurrentSession.StartTime.Format(time.DateTime))
+		}
+		if s.CurrentBreak != nil && s.CurrentBreak.StartTime.Before(s.CurrentSession.StartTime) {
+			add(ProblemBreakBeforeSession, "", true,
+				"the current break starts at %s, before its session started at %s; it will be moved to the session start",
+				s.CurrentBreak.StartTime.Format(time.DateTime), s.CurrentSession.StartTime.Format(time.DateTime))
+		}
+	}
+
+	seenIDs := make(map[string]bool)
+	seenSessions := make(map[string]string)
+	for i, cs := range s.CompletedSessions {
+		label := fmt.Sprintf("session %q completed %s", cs.Task, cs.CompletedAt.Format(time.DateTime))
+
+		switch {
+		case cs.ID == "":
+			add(ProblemMissingID, "", true, "%s has no ID; one will be assigned", label)
+		case seenIDs[cs.ID]:
+			add(ProblemDuplicateID, cs.ID, true, "%s shares its ID with an earlier session; it will get a new one", label)
+		}
+		seenIDs[cs.ID] = true
+
+		if cs.FlowDuration < 0 {
+			add(ProblemNegativeDuration, cs.ID, false, "%s has a negative flow duration (%s)", label, cs.FlowDuration)
+		} else if cs.FlowDuration > maxPlausibleDuration {
+			add(ProblemImplausibleDuration, cs.ID, false, "%s has an implausible flow duration (%s)", label, FormatDuration(cs.FlowDuration))
+		}
+		if cs.BreakDuration != nil {
+			if *cs.BreakDuration < 0 {
+				add(ProblemNegativeDuration, cs.ID, false, "%s has a negative break duration (%s)", label, *cs.BreakDuration)
+			} else if *cs.BreakDuration > maxPlausibleDuration {
+				add(ProblemImplausibleDuration, cs.ID, false, "%s has an implausible break duration (%s)", label, FormatDuration(*cs.BreakDuration))
+			}
+		}
+
+		if !intervalsOrdered(cs.Intervals) {
+			add(ProblemInvalidIntervals, cs.ID, false, "%s has overlapping or reversed flow and break intervals", label)
+		}
+
+		if cs.CompletedAt.After(now) {
+			add(ProblemFutureCompletion, cs.ID, false, "%s is in the future", label)
+		}
+
+		if i > 0 && cs.CompletedAt.Before(s.CompletedSessions[i-1].CompletedAt) {
+			add(ProblemUnsortedHistory, cs.ID, true, "%s is out of order: it is stored after a later session; history will be sorted", label)
+		}
+
+		if cs.DeletedAt == nil {
+			key := duplicateKey(cs)
+			if original, ok := seenSessions[key]; ok {
+				add(ProblemDuplicateSession, cs.ID, true, "%s duplicates session %s; the copy will be deleted (it can be restored from the trash)", label, original)
+			} else {
+				seenSessions[key] = cs.ID
+			}
+		}
+	}
+
+	return problems
+}
+
+// Repair returns a copy of the state with every fixable problem reported by
+// Check repaired. Duplicate sessions are soft-deleted at now rather than removed.
+func (s *FlowState) Repair(now time.Time) *FlowState {
+	r := s.clone()
+
+	if r.CurrentBreak != nil && r.CurrentSession == nil {
+		r.CurrentBreak = nil
+	}
+	if r.CurrentBreak != nil && r.CurrentBreak.StartTime.Before(r.CurrentSession.StartTime) {
+		r.CurrentBreak.StartTime = r.CurrentSession.StartTime
+	}
+
+	r.BackfillSessionIDs()
+	seenIDs := make(map[string]bool)
+	for i := range r.CompletedSessions {
+		cs := &r.CompletedSessions[i]
+		if seenIDs[cs.ID] {
+			cs.ID = r.newSessionID()
+		}
+		seenIDs[cs.ID] = true
+	}
+
+	sort.SliceStable(r.CompletedSessions, func(i, j int) bool {
+		return r.CompletedSessions[i].CompletedAt.Before(r.CompletedSessions[j].CompletedAt)
+	})
+
+	seenSessions := make(map[string]bool)
+	for i := range r.CompletedSessions {
+		cs := &r.CompletedSessions[i]
+		if cs.DeletedAt != nil {
+			continue
+		}
+		key := duplicateKey(*cs)
+		if seenSessions[key] {
+			deletedAt := now
+			cs.DeletedAt = &deletedAt
+		}
+		seenSessions[key] = true
+	}
+
+	return r
+}
+
+// intervalsOrdered reports whether every interval ends no earlier than it starts
+// and starts no earlier than the previous one ends.
+func intervalsOrdered(intervals []Interval) bool {
+	for i, iv := range intervals {
+		if iv.End.Before(iv.Start) || (i > 0 && iv.Start.Before(intervals[i-1].End)) {
+			return false
+		}
+	}
+	return true
+}
+
+// duplicateKey identifies sessions with identical recorded data.
+func duplicateKey(cs CompletedSession) string {
+	breakDuration := time.Duration(-1)
+	if cs.BreakDuration != nil {
+		breakDuration = *cs.BreakDuration
+	}
+	return fmt.Sprintf("%s|%d|%d|%d", cs.Task, cs.CompletedAt.UnixNano(), cs.FlowDuration, breakDuration)
+}
@@ -0,0 +1,122 @@
+package flowtime
+
+import (
+	"testing"
+	"time"
+)
+
+func problemCodes(problems []Problem) map[string]int {
+	codes := make(map[string]int)
+	for _, p := range problems {
+		codes[p.Code]++
+	}
+	return codes
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	t.Run("healthy state has no problems", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		_ = state.StartSession("task")
+		clock.Advance(30 * time.Minute)
+		_ = state.TakeBreak()
+		clock.Advance(5 * time.Minute)
+		_, _ = state.Resume()
+
+		if problems := state.Check(clock.Now()); len(problems) != 0 {
+			t.Errorf("problems = %+v, want none", problems)
+		}
+	})
+
+	t.Run("reports each violated invariant", func(t *testing.T) {
+		negative := -time.Minute
+		state := NewFlowState(newTestClock())
+		state.CurrentBreak = &Break{StartTime: now.Add(-time.Hour)}
+		state.CompletedSessions = []CompletedSession{
+			{ID: "aaaaaaaa", Task: "a", FlowDuration: time.Hour, CompletedAt: now.Add(-2 * time.Hour)},
+			{ID: "aaaaaaaa", Task: "a", FlowDuration: time.Hour, CompletedAt: now.Add(-2 * time.Hour)},
+			{ID: "bbbbbbbb", Task: "b", FlowDuration: 48 * time.Hour, BreakDuration: &negative, CompletedAt: now.Add(-3 * time.Hour)},
+			{ID: "cccccccc", Task: "c", FlowDuration: -time.Minute, CompletedAt: now.Add(time.Hour)},
+			{Task: "d", CompletedAt: now.Add(-time.Minute)},
+			{ID: "eeeeeeee", Task: "e", CompletedAt: now, Intervals: []Interval{
+				{Kind: IntervalFlow, Start: now.Add(-time.Hour), End: now.Add(-30 * time.Minute)},
+				{Kind: IntervalBreak, Start: now.Add(-40 * time.Minute), End: now},
+			}},
+		}
+
+		got := problemCodes(state.Check(now))
+		want := map[string]int{
+			ProblemOrphanBreak:         1,
+			ProblemDuplicateID:         1,
+			ProblemDuplicateSession:    1,
+			ProblemImplausibleDuration: 1,
+			ProblemNegativeDuration:    2,
+			ProblemUnsortedHistory:     2,
+			ProblemFutureCompletion:    1,
+			ProblemMissingID:           1,
+			ProblemInvalidIntervals:    1,
+		}
+		for code, n := range want {
+			if got[code] != n {
+				t.Errorf("%s reported %d times, want %d", code, got[code], n)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("problem codes = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("reports current session problems", func(t *testing.T) {
+		state := NewFlowState(newTestClock())
+		state.CurrentSession = &Session{Task: "a", StartTime: now.Add(time.Hour)}
+		state.CurrentBreak = &Break{StartTime: now}
+
+		got := problemCodes(state.Check(now))
+		if got[ProblemFutureStart] != 1 || got[ProblemBreakBeforeSession] != 1 {
+			t.Errorf("problem codes = %v, want future start and break before session", got)
+		}
+	})
+}
+
+func TestRepair(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	state := NewFlowState(newTestClock())
+	state.CurrentBreak = &Break{StartTime: now.Add(-time.Hour)}
+	state.CompletedSessions = []CompletedSession{
+		{ID: "aaaaaaaa", Task: "a", FlowDuration: time.Hour, CompletedAt: now.Add(-2 * time.Hour)},
+		{ID: "aaaaaaaa", Task: "a", FlowDuration: time.Hour, CompletedAt: now.Add(-2 * time.Hour)},
+		{ID: "bbbbbbbb", Task: "b", FlowDuration: -time.Minute, CompletedAt: now.Add(-3 * time.Hour)},
+		{Task: "c", CompletedAt: now.Add(-time.Minute)},
+	}
+
+	repaired := state.Repair(now)
+
+	for _, p := range repaired.Check(now) {
+		if p.Fixable {
+			t.Errorf("fixable problem left after repair: %+v", p)
+		}
+	}
+	if got := problemCodes(repaired.Check(now)); got[ProblemNegativeDuration] != 1 {
+		t.Errorf("expected the unfixable negative duration to remain, got %v", got)
+	}
+
+	if repaired.CurrentBreak != nil {
+		t.Error("expected orphaned break to be discarded")
+	}
+	if len(repaired.CompletedSessions) != 4 {
+		t.Fatalf("completed sessions = %d, want 4 (duplicates are soft-deleted, not removed)", len(repaired.CompletedSessions))
+	}
+	if repaired.CompletedSessions[0].Task != "b" {
+		t.Errorf("first session = %q, want history sorted by completion", repaired.CompletedSessions[0].Task)
+	}
+	if deleted := len(repaired.DeletedSessions()); deleted != 1 {
+		t.Errorf("deleted sessions = %d, want 1", deleted)
+	}
+
+	// The original state is left untouched.
+	if state.CurrentBreak == nil || state.CompletedSessions[1].ID != "aaaaaaaa" {
+		t.Error("expected Repair not to modify the receiver")
+	}
+}
@@ -0,0 +1,430 @@
+package flowtime
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var ErrUnknownEvent = errors.New("unknown event kind")
+
+// EventKind identifies the type of a FlowState transition.
+type EventKind string
+
+const (
+	EventStart     EventKind = "start"
+	EventBreak     EventKind = "break"
+	EventResume    EventKind = "resume"
+	EventStop      EventKind = "stop"
+	EventSwitch    EventKind = "switch"
+	EventNote      EventKind = "note"
+	EventInterrupt EventKind = "interrupt"
+	EventCancel    EventKind = "cancel"
+	EventDelete    EventKind = "delete"
+	EventDeleteAll EventKind = "delete_all"
+	EventReplace   EventKind = "replace"
+	EventAdd       EventKind = "add"
+	EventEdit      EventKind = "edit"
+	EventUndo      EventKind = "undo"
+	EventRedo      EventKind = "redo"
+
+	EventRestore      EventKind = "restore"
+	EventRestoreSince EventKind = "restore_since"
+	EventPurge        EventKind = "purge"
+)
+
+// Event records a single FlowState transition. Every mutation of a FlowState is
+// made by applying an event, so replaying the events in order rebuilds the state.
+// Only the fields relevant to the kind are set.
+type Event struct {
+	Kind EventKind
+	At   time.Time
+
+	Task           string            // EventStart, EventSwitch: the new session's task
+	Project        string            // EventStart, EventSwitch
+	Tags           []string          // EventStart, EventSwitch
+	SuggestedBreak time.Duration     // EventBreak
+	Text           string            // EventNote: the note; EventInterrupt: the reason; EventStop: the outcome, if any
+	Interruption   InterruptionKind  // EventInterrupt
+	SessionID      string            // EventStop, EventSwitch: ID of the completed session; EventDelete, EventRestore, EventEdit: target
+	Index          int               // EventDelete, EventRestore: index into CompletedSessions, for events without a SessionID
+	Since          time.Time         // EventRestoreSince: sessions deleted at or after this time are restored
+	Before         time.Time         // EventPurge: sessions deleted at or before this time are removed
+	Snapshot       *FlowState        // EventReplace: the state to replace the current one with
+	Session        *CompletedSession // EventAdd: the session to add; EventEdit: the edited session
+	Undone         EventKind         // EventUndo, EventRedo: the kind of transition undone or redone
+	Change         *Change           // EventUndo, EventRedo: the parts of the state to put back
+}
+
+// Replay rebuilds a FlowState by applying events in order to an empty state.
+func Replay(clock Clock, events []Event) (*FlowState, error) {
+	state := NewFlowState(clock)
+	for i, e := range events {
+		if err := state.Apply(e); err != nil {
+			return nil, fmt.Errorf("replaying event %d (%s): %w", i+1, e.Kind, err)
+		}
+	}
+	return state, nil
+}
+
+// PendingEvents returns the events applied since the state was loaded or last
+// saved, oldest first. The storage layer appends these to its event log.
+func (s *FlowState) PendingEvents() []Event {
+	return s.pending
+}
+
+// ClearPendingEvents forgets the pending events once they have been persisted.
+func (s *FlowState) ClearPendingEvents() {
+	s.pending = nil
+}
+
+// Rebase replays the pending events on top of base, which is typically a newer
+// version of the state saved by another process. On success the state becomes
+// the result and keeps its pending events; if any event no longer applies the
+// state is left unchanged and the error is returned.
+func (s *FlowState) Rebase(base *FlowState) error {
+	rebased := base.clone()
+	rebased.breakPolicy = s.breakPolicy
+	rebased.longBreak = s.longBreak
+	for _, e := range s.pending {
+		// Undo and redo put back parts of the state they were based on, which
+		// could discard the other changes.
+		if e.Kind == EventUndo || e.Kind == EventRedo {
+			return fmt.Errorf("reapplying %s: %w", e.Kind, ErrUndoConflict)
+		}
+		if err := rebased.Apply(e); err != nil {
+			return fmt.Errorf("reapplying %s: %w", e.Kind, err)
+		}
+	}
+
+	pending := s.pending
+	*s = *rebased
+	s.pending = pending
+	return nil
+}
+
+// record applies a new event and queues it for persistence.
+func (s *FlowState) record(e Event) error {
+	if err := s.Apply(e); err != nil {
+		return err
+	}
+	s.pending = append(s.pending, e)
+	return nil
+}
+
+// Apply validates the event against the current state and applies it. Applied
+// events are not queued for persistence; use this to replay stored events.
+// Applying a transition pushes it onto the undo stack and clears the redo stack;
+// a replace or purge clears both.
+func (s *FlowState) Apply(e Event) error {
+	switch e.Kind {
+	case EventUndo, EventRedo:
+		return s.applyUndo(e)
+	case EventReplace, EventPurge:
+		if err := s.apply(e); err != nil {
+			return err
+		}
+		s.undo, s.redo = nil, nil
+		return nil
+	}
+
+	inverse := s.inverse(e)
+	if err := s.apply(e); err != nil {
+		return err
+	}
+	s.undo = pushStep(s.undo, UndoStep{Event: e, Change: inverse})
+	s.redo = nil
+	return nil
+}
+
+// apply dispatches the event to the transition for its kind.
+func (s *FlowState) apply(e Event) error {
+	switch e.Kind {
+	case EventStart:
+		return s.applyStart(e)
+	case EventBreak:
+		return s.applyBreak(e)
+	case EventResume:
+		return s.applyResume(e)
+	case EventNote:
+		return s.applyNote(e)
+	case EventInterrupt:
+		return s.applyInterrupt(e)
+	case EventStop:
+		return s.applyStop(e)
+	case EventSwitch:
+		return s.applySwitch(e)
+	case EventCancel:
+		return s.applyCancel()
+	case EventDelete:
+		return s.applyDelete(e)
+	case EventDeleteAll:
+		return s.applyDeleteAll(e)
+	case EventReplace:
+		return s.applyReplace(e)
+	case EventAdd:
+		return s.applyAdd(e)
+	case EventEdit:
+		return s.applyEdit(e)
+	case EventRestore:
+		return s.applyRestore(e)
+	case EventRestoreSince:
+		return s.applyRestoreSince(e)
+	case EventPurge:
+		return s.applyPurge(e)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownEvent, e.Kind)
+	}
+}
+
+func (s *FlowState) applyStart(e Event) error {
+	if e.Task == "" {
+		return ErrTaskEmpty
+	}
+	if len(e.Task) > 100 {
+		return fmt.Errorf("%w: got %d characters", ErrTaskTooLong, len(e.Task))
+	}
+	if s.CurrentSession != nil {
+		return ErrSessionActive
+	}
+
+	s.CurrentSession = &Session{
+		Task:      e.Task,
+		Project:   e.Project,
+		Tags:      slices.Clone(e.Tags),
+		StartTime: e.At,
+	}
+	return nil
+}
+
+func (s *FlowState) applyBreak(e Event) error {
+	if s.CurrentSession == nil {
+		return ErrNoActiveSession
+	}
+	if s.CurrentBreak != nil {
+		return ErrAlreadyOnBreak
+	}
+
+	s.closeInterval(e.At)
+	s.CurrentBreak = &Break{
+		StartTime:         e.At,
+		SuggestedDuration: e.SuggestedBreak,
+	}
+	return nil
+}
+
+func (s *FlowState) applyResume(e Event) error {
+	// Path 1: on break with active session — end the break and continue the block
+	if s.CurrentSession != nil && s.CurrentBreak != nil {
+		s.closeInterval(e.At)
+		return nil
+	}
+
+	// Path 2: idle with history — start new session with last active task
+	if s.CurrentSession == nil && s.CurrentBreak == nil {
+		active := s.ActiveSessions()
+		if len(active) > 0 {
+			last := active[len(active)-1]
+			s.CurrentSession = &Session{
+				Task:      last.Task,
+				Project:   last.Project,
+				Tags:      slices.Clone(last.Tags),
+				StartTime: e.At,
+			}
+			return nil
+		}
+	}
+
+	// Path 3: already flowing (session active, no break)
+	if s.CurrentSession != nil && s.CurrentBreak == nil {
+		return ErrAlreadyFlowing
+	}
+
+	// Path 4: no session and no history
+	return ErrNoSessionToResume
+}
+
+func (s *FlowState) applyStop(e Event) error {
+	if s.CurrentSession == nil {
+		return ErrNoActiveSession
+	}
+	if len(e.Text) > maxNoteLength {
+		return fmt.Errorf("%w: got %d characters", ErrNoteTooLong, len(e.Text))
+	}
+
+	if e.Text != "" {
+		s.CurrentSession.Notes = append(s.CurrentSession.Notes, Note{At: e.At, Text: e.Text})
+	}
+	s.completeSession(e)
+	return nil
+}
+
+func (s *FlowState) applySwitch(e Event) error {
+	if s.CurrentSession == nil {
+		return ErrNoActiveSession
+	}
+	if e.Task == "" {
+		return ErrTaskEmpty
+	}
+	if len(e.Task) > 100 {
+		return fmt.Errorf("%w: got %d characters", ErrTaskTooLong, len(e.Task))
+	}
+
+	s.completeSession(e)
+	s.CurrentSession = &Session{
+		Task:      e.Task,
+		Project:   e.Project,
+		Tags:      slices.Clone(e.Tags),
+		StartTime: e.At,
+	}
+	return nil
+}
+
+// closeInterval ends the current session's interval in progress at end: the
+// current break if there is one, otherwise the current flow interval. A closed
+// break is cleared.
+func (s *FlowState) closeInterval(end time.Time) {
+	session := s.CurrentSession
+	if s.CurrentBreak != nil {
+		// Snapshots from before blocks kept intervals have none for the flow
+		// that preceded the break.
+		if breakStart := s.CurrentBreak.StartTime; session.FlowStart().Before(breakStart) {
+			session.Intervals = append(session.Intervals, Interval{Kind: IntervalFlow, Start: session.FlowStart(), End: breakStart})
+		}
+		session.Intervals = append(session.Intervals, Interval{Kind: IntervalBreak, Start: s.CurrentBreak.StartTime, End: end})
+		s.CurrentBreak = nil
+		return
+	}
+	session.Intervals = append(session.Intervals, Interval{Kind: IntervalFlow, Start: session.FlowStart(), End: end})
+}
+
+// completeSession closes the current session's open interval and moves it to
+// CompletedSessions as a finished block.
+func (s *FlowState) completeSession(e Event) {
+	s.closeInterval(e.At)
+	session := s.CurrentSession
+	completed := newCompletedSession(s.completedSessionID(e, session.Task), session.Task, session.Intervals, e.At)
+	completed.Project = session.Project
+	completed.Tags = session.Tags
+	completed.Notes = session.Notes
+	completed.Interruptions = session.Interruptions
+	s.CompletedSessions = append(s.CompletedSessions, completed)
+	s.CurrentSession = nil
+	s.CurrentBreak = nil
+}
+
+// completedSessionID returns the ID for a session completed by e. Events recorded
+// before sessions had IDs get a LegacySessionID.
+func (s *FlowState) completedSessionID(e Event, task string) string {
+	if e.SessionID != "" {
+		return e.SessionID
+	}
+	return LegacySessionID(len(s.CompletedSessions), task, e.At)
+}
+
+func (s *FlowState) applyCancel() error {
+	if s.CurrentSession == nil {
+		return ErrNoActiveSession
+	}
+
+	s.CurrentSession = nil
+	s.CurrentBreak = nil
+	return nil
+}
+
+// targetIndex returns the CompletedSessions index an event refers to, preferring
+// its SessionID over its Index, or -1 if there is no such session.
+func (s *FlowState) targetIndex(e Event) int {
+	if e.SessionID != "" {
+		return s.sessionIndex(e.SessionID)
+	}
+	if e.Index < 0 || e.Index >= len(s.CompletedSessions) {
+		return -1
+	}
+	return e.Index
+}
+
+func (s *FlowState) applyDelete(e Event) error {
+	index := s.targetIndex(e)
+	if index == -1 {
+		return ErrSessionNotFound
+	}
+	if s.CompletedSessions[index].DeletedAt != nil {
+		return ErrSessionDeleted
+	}
+
+	at := e.At
+	s.CompletedSessions[index].DeletedAt = &at
+	return nil
+}
+
+func (s *FlowState) applyDeleteAll(e Event) error {
+	active := s.ActiveSessions()
+	if len(active) == 0 {
+		return ErrNoSessionsToDelete
+	}
+
+	at := e.At
+	for i := range s.CompletedSessions {
+		if s.CompletedSessions[i].DeletedAt == nil {
+			s.CompletedSessions[i].DeletedAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *FlowState) applyRestore(e Event) error {
+	index := s.targetIndex(e)
+	if index == -1 {
+		return ErrSessionNotFound
+	}
+	if s.CompletedSessions[index].DeletedAt == nil {
+		return ErrSessionNotDeleted
+	}
+
+	s.CompletedSessions[index].DeletedAt = nil
+	return nil
+}
+
+func (s *FlowState) applyRestoreSince(e Event) error {
+	restored := 0
+	for i := range s.CompletedSessions {
+		deletedAt := s.CompletedSessions[i].DeletedAt
+		if deletedAt != nil && !deletedAt.Before(e.Since) {
+			s.CompletedSessions[i].DeletedAt = nil
+			restored++
+		}
+	}
+	if restored == 0 {
+		return ErrNoSessionsToRestore
+	}
+	return nil
+}
+
+func (s *FlowState) applyPurge(e Event) error {
+	kept := make([]CompletedSession, 0, len(s.CompletedSessions))
+	for _, cs := range s.CompletedSessions {
+		if cs.DeletedAt == nil || cs.DeletedAt.After(e.Before) {
+			kept = append(kept, cs)
+		}
+	}
+	if len(kept) == len(s.CompletedSessions) {
+		return ErrNoSessionsToPurge
+	}
+
+	s.CompletedSessions = kept
+	return nil
+}
+
+func (s *FlowState) applyReplace(e Event) error {
+	if e.Snapshot == nil {
+		return fmt.Errorf("%s event has no snapshot", e.Kind)
+	}
+
+	snapshot := e.Snapshot.clone()
+	s.CurrentSession = snapshot.CurrentSession
+	s.CurrentBreak = snapshot.CurrentBreak
+	s.CompletedSessions = snapshot.CompletedSessions
+	return nil
+}
@@ -0,0 +1,160 @@
+package flowtime
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// assertSameState compares the persisted fields of two states.
+func assertSameState(t *testing.T, got, want *FlowState) {
+	t.Helper()
+	if !reflect.DeepEqual(got.CurrentSession, want.CurrentSession) {
+		t.Errorf("current session = %+v, want %+v", got.CurrentSession, want.CurrentSession)
+	}
+	if !reflect.DeepEqual(got.CurrentBreak, want.CurrentBreak) {
+		t.Errorf("current break = %+v, want %+v", got.CurrentBreak, want.CurrentBreak)
+	}
+	if !reflect.DeepEqual(got.CompletedSessions, want.CompletedSessions) {
+		t.Errorf("completed sessions = %+v, want %+v", got.CompletedSessions, want.CompletedSessions)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	clock := newTestClock()
+	state := NewFlowState(clock)
+
+	_ = state.StartSession("write code")
+	clock.Advance(30 * time.Minute)
+	_ = state.TakeBreak()
+	clock.Advance(8 * time.Minute)
+	_, _ = state.Resume()
+	clock.Advance(20 * time.Minute)
+	_, _ = state.Stop()
+	_ = state.StartSession("plan")
+	clock.Advance(10 * time.Minute)
+	_, _ = state.Stop()
+	clock.Advance(time.Minute)
+	_ = state.DeleteSession(0)
+	_ = state.StartSession("review")
+	_ = state.CancelSession()
+	_, _ = state.Resume()
+
+	events := state.PendingEvents()
+	if len(events) != 10 {
+		t.Fatalf("pending events = %d, want 10", len(events))
+	}
+
+	replayed, err := Replay(clock, events)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSameState(t, replayed, state)
+
+	if len(replayed.PendingEvents()) != 0 {
+		t.Errorf("replayed state has %d pending events, want 0", len(replayed.PendingEvents()))
+	}
+}
+
+func TestReplayRejectsInvalidEvents(t *testing.T) {
+	clock := newTestClock()
+
+	_, err := Replay(clock, []Event{{Kind: EventBreak, At: clock.Now()}})
+	if !errors.Is(err, ErrNoActiveSession) {
+		t.Errorf("error = %v, want %v", err, ErrNoActiveSession)
+	}
+
+	_, err = Replay(clock, []Event{{Kind: "teleport", At: clock.Now()}})
+	if !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("error = %v, want %v", err, ErrUnknownEvent)
+	}
+}
+
+func TestFailedTransitionRecordsNoEvent(t *testing.T) {
+	state := NewFlowState(newTestClock())
+
+	if err := state.TakeBreak(); err == nil {
+		t.Fatal("expected error when no session active")
+	}
+	if len(state.PendingEvents()) != 0 {
+		t.Errorf("pending events = %d, want 0", len(state.PendingEvents()))
+	}
+}
+
+func TestReplace(t *testing.T) {
+	clock := newTestClock()
+	other := NewFlowState(clock)
+	_ = other.StartSession("imported")
+	_, _ = other.Stop()
+
+	state := NewFlowState(clock)
+	_ = state.StartSession("discarded")
+	if err := state.Replace(other); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSameState(t, state, other)
+
+	// The snapshot must be a copy, unaffected by later changes to other.
+	_ = other.DeleteSession(0)
+	if state.CompletedSessions[0].DeletedAt != nil {
+		t.Error("expected replaced state to be independent of the source")
+	}
+
+	replayed, err := Replay(clock, state.PendingEvents())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSameState(t, replayed, state)
+}
+
+func TestRebase(t *testing.T) {
+	t.Run("reapplies pending events on top of newer state", func(t *testing.T) {
+		clock := newTestClock()
+		base := NewFlowState(clock)
+		_ = base.StartSession("old task")
+		_, _ = base.Stop()
+		_ = base.StartSession("write code")
+		base.ClearPendingEvents()
+
+		// Another process deletes the old session while we take a break.
+		other := base.clone()
+		_ = other.DeleteSession(0)
+
+		ours := base.clone()
+		clock.Advance(30 * time.Minute)
+		_ = ours.TakeBreak()
+
+		if err := ours.Rebase(other); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ours.CompletedSessions[0].DeletedAt == nil {
+			t.Error("expected other process's delete to be kept")
+		}
+		if ours.CurrentBreak == nil {
+			t.Error("expected our break to be reapplied")
+		}
+		if len(ours.PendingEvents()) != 1 {
+			t.Errorf("pending events = %d, want 1", len(ours.PendingEvents()))
+		}
+	})
+
+	t.Run("leaves state unchanged when events no longer apply", func(t *testing.T) {
+		clock := newTestClock()
+		base := NewFlowState(clock)
+
+		other := base.clone()
+		_ = other.StartSession("theirs")
+
+		ours := base.clone()
+		_ = ours.StartSession("ours")
+
+		err := ours.Rebase(other)
+		if !errors.Is(err, ErrSessionActive) {
+			t.Fatalf("error = %v, want %v", err, ErrSessionActive)
+		}
+		if ours.CurrentSession.Task != "ours" {
+			t.Errorf("task = %q, want %q", ours.CurrentSession.Task, "ours")
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package flowtime
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// SessionFilter selects completed sessions. Zero-valued fields match everything.
+type SessionFilter struct {
+	Project string         // sessions in this project, ignoring case
+	Tags    []string       // sessions carrying every one of these tags, ignoring case
+	Since   time.Time      // sessions that started at or after this time
+	Until   time.Time      // sessions that started before this time
+	Task    *regexp.Regexp // sessions whose task matches; see TaskPattern
+	MinFlow time.Duration  // sessions with at least this much flow
+	MaxFlow time.Duration  // sessions with at most this much flow
+}
+
+// Match reports whether the session satisfies every criterion of the filter.
+func (f SessionFilter) Match(cs CompletedSession) bool {
+	if f.Project != "" && !strings.EqualFold(cs.Project, f.Project) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !cs.HasTag(tag) {
+			return false
+		}
+	}
+	if !f.Since.IsZero() && cs.Start().Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !cs.Start().Before(f.Until) {
+		return false
+	}
+	if f.Task != nil && !f.Task.MatchString(cs.Task) {
+		return false
+	}
+	if cs.FlowDuration < f.MinFlow || (f.MaxFlow > 0 && cs.FlowDuration > f.MaxFlow) {
+		return false
+	}
+	return true
+}
+
+// IsZero reports whether the filter has no criteria and so matches every session.
+func (f SessionFilter) IsZero() bool {
+	return f.Project == "" && len(f.Tags) == 0 && f.Since.IsZero() && f.Until.IsZero() &&
+		f.Task == nil && f.MinFlow == 0 && f.MaxFlow == 0
+}
+
+// Apply returns the sessions matching the filter, preserving order.
+func (f SessionFilter) Apply(sessions []CompletedSession) []CompletedSession {
+	var matched []CompletedSession
+	for _, cs := range sessions {
+		if f.Match(cs) {
+			matched = append(matched, cs)
+		}
+	}
+	return matched
+}
+
+// TaskPattern compiles a task filter for SessionFilter.Task. A value wrapped in
+// slashes, like "/^fix.*bug$/", is a regular expression; anything else matches
+// tasks containing it, ignoring case.
+func TaskPattern(s string) (*regexp.Regexp, error) {
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: task pattern %s: %w", ErrInvalidFilter, s, err)
+		}
+		return re, nil
+	}
+	return regexp.MustCompile("(?i)" + regexp.QuoteMeta(s)), nil
+}
+
+// ParseFilter parses a filter query such as
+// `review +auth @urgent since:"last week" min:25m`. Each term is one of:
+//
+//   - since:TIME or until:TIME, accepting anything ParseTime does
+//   - min:DURATION or max:DURATION, bounding the flow time
+//   - +project or @tag, as in a task description
+//   - any other word, which together make up the task to match (see TaskPattern)
+//
+// Values containing spaces must be double-quoted. An empty query matches
+// everything.
+func ParseFilter(query string, now time.Time) (SessionFilter, error) {
+	terms, err := splitQuery(query)
+	if err != nil {
+		return SessionFilter{}, err
+	}
+
+	var f SessionFilter
+	var words []string
+	for _, term := range terms {
+		key, value, _ := strings.Cut(term, ":")
+		switch strings.ToLower(key) {
+		case "since", "until":
+			t, err := ParseTime(value, now)
+			if err != nil {
+				return SessionFilter{}, fmt.Errorf("%w: %s: %w", ErrInvalidFilter, key, err)
+			}
+			if strings.EqualFold(key, "since") {
+				f.Since = t
+			} else {
+				f.Until = t
+			}
+		case "min", "max":
+			d, err := ParseDuration(value)
+			if err != nil {
+				return SessionFilter{}, fmt.Errorf("%w: %s: %w", ErrInvalidFilter, key, err)
+			}
+			if strings.EqualFold(key, "min") {
+				f.MinFlow = d
+			} else {
+				f.MaxFlow = d
+			}
+		default:
+			switch {
+			case len(term) > len(projectPrefix) && strings.HasPrefix(term, projectPrefix):
+				f.Project = strings.TrimPrefix(term, projectPrefix)
+			case len(term) > len(tagPrefix) && strings.HasPrefix(term, tagPrefix):
+				f.Tags = append(f.Tags, strings.TrimPrefix(term, tagPrefix))
+			default:
+				words = append(words, term)
+			}
+		}
+	}
+
+	if len(words) > 0 {
+		if f.Task, err = TaskPattern(strings.Join(words, " ")); err != nil {
+			return SessionFilter{}, err
+		}
+	}
+	return f, nil
+}
+
+// splitQuery splits a filter query into whitespace-separated terms, keeping
+// double-quoted text together and removing the quotes.
+func splitQuery(query string) ([]string, error) {
+	var terms []string
+	var term strings.Builder
+	inTerm, quoted := false, false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inTerm = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inTerm {
+				terms = append(terms, term.String())
+				term.Reset()
+				inTerm = false
+			}
+		default:
+			term.WriteRune(r)
+			inTerm = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("%w: unterminated quote in %q", ErrInvalidFilter, query)
+	}
+	if inTerm {
+		terms = append(terms, term.String())
+	}
+	return terms, nil
+}
@@ -0,0 +1,97 @@
+package flowtime
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func mustTaskPattern(t *testing.T, s string) *regexp.Regexp {
+	t.Helper()
+	re, err := TaskPattern(s)
+	if err != nil {
+		t.Fatalf("TaskPattern(%q): %v", s, err)
+	}
+	return re
+}
+
+func TestTaskPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		task    string
+		want    bool
+	}{
+		{"login", "Fix LOGIN bug", true},
+		{"a.b", "a.b", true},
+		{"a.b", "axb", false},
+		{"/^fix/", "fix login", true},
+		{"/^fix/", "Fix login", false},
+		{"/(?i)^fix/", "Fix login", true},
+		{"/", "a/b", true},
+	}
+	for _, tt := range tests {
+		if got := mustTaskPattern(t, tt.pattern).MatchString(tt.task); got != tt.want {
+			t.Errorf("TaskPattern(%q) matching %q = %v, want %v", tt.pattern, tt.task, got, tt.want)
+		}
+	}
+
+	if _, err := TaskPattern("/fix(/"); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidFilter)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	now := time.Date(2025, 6, 15, 14, 30, 0, 0, time.UTC)
+
+	t.Run("parses every term", func(t *testing.T) {
+		f, err := ParseFilter(`fix login +auth @review @urgent since:"last week" until:today min:25m max:2h`, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if f.Project != "auth" || !slices.Equal(f.Tags, []string{"review", "urgent"}) {
+			t.Errorf("labels = %q %v, want auth [review urgent]", f.Project, f.Tags)
+		}
+		if want := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC); !f.Since.Equal(want) {
+			t.Errorf("since = %v, want %v", f.Since, want)
+		}
+		if want := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC); !f.Until.Equal(want) {
+			t.Errorf("until = %v, want %v", f.Until, want)
+		}
+		if f.MinFlow != 25*time.Minute || f.MaxFlow != 2*time.Hour {
+			t.Errorf("flow = %v..%v, want 25m..2h", f.MinFlow, f.MaxFlow)
+		}
+		if f.Task == nil || !f.Task.MatchString("Fix Login bug") || f.Task.MatchString("login fix") {
+			t.Errorf("task = %v, want a substring match on %q", f.Task, "fix login")
+		}
+	})
+
+	t.Run("empty matches everything", func(t *testing.T) {
+		f, err := ParseFilter("  ", now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !f.IsZero() || !f.Match(CompletedSession{Task: "anything", CompletedAt: now}) {
+			t.Errorf("empty filter %+v did not match", f)
+		}
+	})
+
+	t.Run("quoted regex", func(t *testing.T) {
+		f, err := ParseFilter(`"/^fix (login|auth)$/"`, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !f.Task.MatchString("fix auth") || f.Task.MatchString("fix auth bug") {
+			t.Errorf("task = %v, want the regex", f.Task)
+		}
+	})
+
+	for _, query := range []string{"since:someday", "min:soon", `task "unterminated`, "/(/"} {
+		t.Run("rejects "+query, func(t *testing.T) {
+			if _, err := ParseFilter(query, now); !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidFilter)
+			}
+		})
+	}
+}
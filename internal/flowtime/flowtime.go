@@ -3,26 +3,42 @@ package flowtime
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 )
 
 var (
-	ErrNoActiveSession    = errors.New("no active session")
-	ErrSessionActive      = errors.New("session already active")
-	ErrAlreadyOnBreak     = errors.New("already on break")
-	ErrAlreadyFlowing     = errors.New("already in flow state")
-	ErrNoSessionToResume  = errors.New("no session to resume")
-	ErrTaskEmpty          = errors.New("task cannot be empty")
-	ErrTaskTooLong        = errors.New("task cannot exceed 100 characters")
-	ErrSessionNotFound    = errors.New("session not found")
-	ErrSessionDeleted     = errors.New("session already deleted")
-	ErrNoSessionsToDelete = errors.New("no sessions to delete")
+	ErrNoActiveSession     = errors.New("no active session")
+	ErrSessionActive       = errors.New("session already active")
+	ErrAlreadyOnBreak      = errors.New("already on break")
+	ErrAlreadyFlowing      = errors.New("already in flow state")
+	ErrNoSessionToResume   = errors.New("no session to resume")
+	ErrTaskEmpty           = errors.New("task cannot be empty")
+	ErrTaskTooLong         = errors.New("task cannot exceed 100 characters")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionDeleted      = errors.New("session already deleted")
+	ErrNoSessionsToDelete  = errors.New("no sessions to delete")
+	ErrSessionNotDeleted   = errors.New("session is not deleted")
+	ErrNoSessionsToRestore = errors.New("no deleted sessions to restore")
+	ErrNoSessionsToPurge   = errors.New("no deleted sessions to purge")
+	ErrTimeInFuture        = errors.New("time is in the future")
+	ErrTimeBeforePrevious  = errors.New("time is before the previous transition")
 )
 
-// Session represents an active flow session.
+// Session represents an active work block: flow interrupted by any number of
+// breaks under one task. StartTime is when the block started; Intervals holds
+// its finished intervals, oldest first, while the one in progress is implied by
+// CurrentBreak. Project and Tags are optional labels for slicing time; Notes
+// and Interruptions hold any remarks and interruptions recorded along the way,
+// oldest first.
 type Session struct {
-	Task      string
-	StartTime time.Time
+	Task          string
+	Project       string
+	Tags          []string
+	StartTime     time.Time
+	Intervals     []Interval
+	Notes         []Note
+	Interruptions []Interruption
 }
 
 // Break represents an active break period.
@@ -31,9 +47,18 @@ type Break struct {
 	SuggestedDuration time.Duration
 }
 
-// CompletedSession represents a finished flow session with its recorded durations.
+// CompletedSession represents a finished work block. Intervals lists its flow and
+// breaks in order; FlowDuration and BreakDuration are their totals, with
+// BreakDuration nil if the block had no break. ID is assigned on completion and
+// never changes.
 type CompletedSession struct {
+	ID            string
 	Task          string
+	Project       string
+	Tags          []string
+	Intervals     []Interval
+	Notes         []Note
+	Interruptions []Interruption
 	FlowDuration  time.Duration
 	BreakDuration *time.Duration
 	CompletedAt   time.Time
@@ -43,6 +68,11 @@ type CompletedSession struct {
 // FlowState holds the full state of the flowtime timer.
 type FlowState struct {
 	clock             Clock
+	breakPolicy       BreakPolicy
+	longBreak         LongBreakRule
+	revision          uint64
+	pending           []Event
+	undo, redo        []UndoStep
 	CurrentSession    *Session
 	CurrentBreak      *Break
 	CompletedSessions []CompletedSession
@@ -62,142 +92,164 @@ func (s *FlowState) SetClock(c Clock) {
 	s.clock = c
 }
 
-// StartSession begins a new flow session with the given task name.
-// Returns an error if a session is already active, the task is empty, or the task exceeds 100 characters.
-func (s *FlowState) StartSession(task string) error {
-	if task == "" {
-		return ErrTaskEmpty
-	}
-	if len(task) > 100 {
-		return fmt.Errorf("%w: got %d characters", ErrTaskTooLong, len(task))
+// SetBreakPolicy sets the policy TakeBreak uses to suggest break durations. A nil
+// policy selects DefaultBreakPolicy.
+func (s *FlowState) SetBreakPolicy(p BreakPolicy) {
+	s.breakPolicy = p
+}
+
+// BreakPolicy returns the policy TakeBreak uses to suggest break durations.
+func (s *FlowState) BreakPolicy() BreakPolicy {
+	if s.breakPolicy == nil {
+		return DefaultBreakPolicy
 	}
-	if s.CurrentSession != nil {
-		return ErrSessionActive
+	return s.breakPolicy
+}
+
+// Revision returns the store revision this state was loaded from or last saved as.
+func (s *FlowState) Revision() uint64 {
+	return s.revision
+}
+
+// SetRevision records the store revision of the FlowState. This is used by the
+// storage layer to detect saves based on a stale load.
+func (s *FlowState) SetRevision(rev uint64) {
+	s.revision = rev
+}
+
+// StartSession begins a new flow session with the given task description, from
+// which a "+project" and "@tags" are extracted (see ParseTask).
+// Returns an error if a session is already active, the task is empty, or the task exceeds 100 characters.
+func (s *FlowState) StartSession(input string) error {
+	return s.startSession(input, s.clock.Now())
+}
+
+// StartSessionAt is StartSession for a session that started at an earlier time.
+// Returns an error if at is in the future or before the previous transition.
+func (s *FlowState) StartSessionAt(input string, at time.Time) error {
+	if err := s.checkAt(at); err != nil {
+		return err
 	}
+	return s.startSession(input, at)
+}
 
-	s.CurrentSession = &Session{
-		Task:      task,
-		StartTime: s.clock.Now(),
+func (s *FlowState) startSession(input string, at time.Time) error {
+	task, project, tags, err := ParseTask(input)
+	if err != nil {
+		return err
 	}
-	return nil
+	return s.record(Event{Kind: EventStart, At: at, Task: task, Project: project, Tags: tags})
 }
 
-// TakeBreak pauses the current flow session and starts a break.
+// TakeBreak ends the current flow interval and starts a break within the same block.
+// The break policy suggests a break based on the flow interval just ended,
+// unless the long break rule calls for a long one.
 // Returns an error if no session is active or if already on a break.
 func (s *FlowState) TakeBreak() error {
+	return s.takeBreak(s.clock.Now())
+}
+
+// TakeBreakAt is TakeBreak for a break that started at an earlier time.
+// Returns an error if at is in the future or before the previous transition.
+func (s *FlowState) TakeBreakAt(at time.Time) error {
+	if err := s.checkAt(at); err != nil {
+		return err
+	}
+	return s.takeBreak(at)
+}
+
+func (s *FlowState) takeBreak(at time.Time) error {
 	if s.CurrentSession == nil {
 		return ErrNoActiveSession
 	}
-	if s.CurrentBreak != nil {
-		return ErrAlreadyOnBreak
-	}
-
-	now := s.clock.Now()
-	workDuration := now.Sub(s.CurrentSession.StartTime)
-	suggestedBreak := CalculateBreak(workDuration)
 
-	s.CurrentBreak = &Break{
-		StartTime:         now,
-		SuggestedDuration: suggestedBreak,
-	}
-	return nil
+	workDuration := at.Sub(s.CurrentSession.FlowStart())
+	return s.record(Event{Kind: EventBreak, At: at, SuggestedBreak: s.suggestBreak(workDuration, at)})
 }
 
-// Resume returns to a flow session. If currently on a break, the current session is completed
-// and a new session is started with the same task name (returns true). If idle with completed
-// sessions, a new session is started with the last completed task name (returns false).
+// Resume returns to flow. If currently on a break, the break ends and a new flow interval
+// starts in the same block (returns true). If idle with completed sessions, a new block is
+// started with the last completed task name (returns false).
 func (s *FlowState) Resume() (resumedCurrent bool, err error) {
-	// Path 1: on break with active session — complete current and start new
-	if s.CurrentSession != nil && s.CurrentBreak != nil {
-		now := s.clock.Now()
-		flowDuration := s.CurrentBreak.StartTime.Sub(s.CurrentSession.StartTime)
-		breakDuration := now.Sub(s.CurrentBreak.StartTime)
-
-		completed := CompletedSession{
-			Task:          s.CurrentSession.Task,
-			FlowDuration:  flowDuration,
-			BreakDuration: &breakDuration,
-			CompletedAt:   now,
-		}
-		s.CompletedSessions = append(s.CompletedSessions, completed)
+	return s.resume(s.clock.Now())
+}
 
-		s.CurrentBreak = nil
-		s.CurrentSession = &Session{
-			Task:      completed.Task,
-			StartTime: now,
-		}
-		return true, nil
-	}
-
-	// Path 2: idle with history — start new session with last active task
-	if s.CurrentSession == nil && s.CurrentBreak == nil {
-		active := s.ActiveSessions()
-		if len(active) > 0 {
-			lastTask := active[len(active)-1].Task
-			s.CurrentSession = &Session{
-				Task:      lastTask,
-				StartTime: s.clock.Now(),
-			}
-			return false, nil
-		}
+// ResumeAt is Resume for flow that resumed at an earlier time.
+// Returns an error if at is in the future or before the previous transition.
+func (s *FlowState) ResumeAt(at time.Time) (resumedCurrent bool, err error) {
+	if err := s.checkAt(at); err != nil {
+		return false, err
 	}
+	return s.resume(at)
+}
 
-	// Path 3: already flowing (session active, no break)
-	if s.CurrentSession != nil && s.CurrentBreak == nil {
-		return false, ErrAlreadyFlowing
+func (s *FlowState) resume(at time.Time) (resumedCurrent bool, err error) {
+	resumedCurrent = s.CurrentSession != nil && s.CurrentBreak != nil
+	if err := s.record(Event{Kind: EventResume, At: at}); err != nil {
+		return false, err
 	}
-
-	// Path 4: no session and no history
-	return false, ErrNoSessionToResume
+	return resumedCurrent, nil
 }
 
-// Stop ends the current session and returns the completed session.
+// Stop ends the current block, closing its open flow or break interval, and
+// returns the completed session.
 // Returns an error if no session is active.
 func (s *FlowState) Stop() (*CompletedSession, error) {
-	if s.CurrentSession == nil {
-		return nil, ErrNoActiveSession
-	}
+	return s.stop(s.clock.Now(), "")
+}
 
-	now := s.clock.Now()
-	var completed CompletedSession
+// StopAt is Stop for a block that ended at an earlier time.
+// Returns an error if at is in the future or before the previous transition.
+func (s *FlowState) StopAt(at time.Time) (*CompletedSession, error) {
+	if err := s.checkAt(at); err != nil {
+		return nil, err
+	}
+	return s.stop(at, "")
+}
 
-	if s.CurrentBreak != nil {
-		// On break: flow = break start - session start, break = now - break start
-		flowDuration := s.CurrentBreak.StartTime.Sub(s.CurrentSession.StartTime)
-		breakDuration := now.Sub(s.CurrentBreak.StartTime)
-		completed = CompletedSession{
-			Task:          s.CurrentSession.Task,
-			FlowDuration:  flowDuration,
-			BreakDuration: &breakDuration,
-			CompletedAt:   now,
-		}
-	} else {
-		// Flowing: flow = now - session start, no break
-		flowDuration := now.Sub(s.CurrentSession.StartTime)
-		completed = CompletedSession{
-			Task:         s.CurrentSession.Task,
-			FlowDuration: flowDuration,
-			CompletedAt:  now,
-		}
+func (s *FlowState) stop(at time.Time, outcome string) (*CompletedSession, error) {
+	if err := s.record(Event{Kind: EventStop, At: at, SessionID: s.newSessionID(), Text: outcome}); err != nil {
+		return nil, err
 	}
+	completed := s.CompletedSessions[len(s.CompletedSessions)-1]
+	return &completed, nil
+}
 
-	s.CompletedSessions = append(s.CompletedSessions, completed)
-	s.CurrentSession = nil
-	s.CurrentBreak = nil
+// Switch completes the current block at this instant, without a break, and
+// immediately starts a new one with the given task, parsed as for StartSession.
+// Both happen in a single transition, so there is no gap between the blocks.
+// Returns the completed session, or an error if no session is active or the
+// new task is invalid.
+func (s *FlowState) Switch(input string) (*CompletedSession, error) {
+	return s.switchTask(input, s.clock.Now())
+}
 
+// SwitchAt is Switch for a switch that happened at an earlier time.
+// Returns an error if at is in the future or before the previous transition.
+func (s *FlowState) SwitchAt(input string, at time.Time) (*CompletedSession, error) {
+	if err := s.checkAt(at); err != nil {
+		return nil, err
+	}
+	return s.switchTask(input, at)
+}
+
+func (s *FlowState) switchTask(input string, at time.Time) (*CompletedSession, error) {
+	task, project, tags, err := ParseTask(input)
+	if err != nil {
+		return nil, err
+	}
+	e := Event{Kind: EventSwitch, At: at, Task: task, Project: project, Tags: tags, SessionID: s.newSessionID()}
+	if err := s.record(e); err != nil {
+		return nil, err
+	}
+	completed := s.CompletedSessions[len(s.CompletedSessions)-1]
 	return &completed, nil
 }
 
 // CancelSession discards the current session without recording it.
 // Returns an error if no session is active.
 func (s *FlowState) CancelSession() error {
-	if s.CurrentSession == nil {
-		return ErrNoActiveSession
-	}
-
-	s.CurrentSession = nil
-	s.CurrentBreak = nil
-	return nil
+	return s.record(Event{Kind: EventCancel, At: s.clock.Now()})
 }
 
 // ActiveSessions returns only non-deleted completed sessions, preserving order.
@@ -211,35 +263,185 @@ func (s *FlowState) ActiveSessions() []CompletedSession {
 	return active
 }
 
+// DeletedSessions returns only soft-deleted completed sessions, preserving order.
+func (s *FlowState) DeletedSessions() []CompletedSession {
+	var deleted []CompletedSession
+	for _, cs := range s.CompletedSessions {
+		if cs.DeletedAt != nil {
+			deleted = append(deleted, cs)
+		}
+	}
+	return deleted
+}
+
 // DeleteSession soft-deletes the completed session at the given index (into the full
 // CompletedSessions slice, not the filtered active list). Returns an error if the
 // index is out of range or the session is already deleted.
 func (s *FlowState) DeleteSession(index int) error {
-	if index < 0 || index >= len(s.CompletedSessions) {
-		return ErrSessionNotFound
+	e := Event{Kind: EventDelete, At: s.clock.Now(), Index: index}
+	if index >= 0 && index < len(s.CompletedSessions) {
+		e.SessionID = s.CompletedSessions[index].ID
 	}
-	if s.CompletedSessions[index].DeletedAt != nil {
-		return ErrSessionDeleted
-	}
-
-	now := s.clock.Now()
-	s.CompletedSessions[index].DeletedAt = &now
-	return nil
+	return s.record(e)
 }
 
 // DeleteAllSessions soft-deletes all non-deleted completed sessions.
 // Returns an error if there are no active sessions to delete.
 func (s *FlowState) DeleteAllSessions() error {
-	active := s.ActiveSessions()
-	if len(active) == 0 {
-		return ErrNoSessionsToDelete
+	return s.record(Event{Kind: EventDeleteAll, At: s.clock.Now()})
+}
+
+// RestoreSession undeletes the soft-deleted session at the given index (into the
+// full CompletedSessions slice). Returns an error if the index is out of range or
+// the session is not deleted.
+func (s *FlowState) RestoreSession(index int) error {
+	e := Event{Kind: EventRestore, At: s.clock.Now(), Index: index}
+	if index >= 0 && index < len(s.CompletedSessions) {
+		e.SessionID = s.CompletedSessions[index].ID
+	}
+	return s.record(e)
+}
+
+// RestoreSessionsSince undeletes every session deleted at or after since and
+// returns how many were restored. Returns an error if there are none.
+func (s *FlowState) RestoreSessionsSince(since time.Time) (int, error) {
+	count := 0
+	for _, cs := range s.CompletedSessions {
+		if cs.DeletedAt != nil && !cs.DeletedAt.Before(since) {
+			count++
+		}
+	}
+	if err := s.record(Event{Kind: EventRestoreSince, At: s.clock.Now(), Since: since}); err != nil {
+		return 0, err
 	}
+	return count, nil
+}
 
-	now := s.clock.Now()
-	for i := range s.CompletedSessions {
-		if s.CompletedSessions[i].DeletedAt == nil {
-			s.CompletedSessions[i].DeletedAt = &now
+// PurgeableSessions returns the deleted sessions that PurgeDeleted(before) would
+// remove, preserving order.
+func (s *FlowState) PurgeableSessions(before time.Time) []CompletedSession {
+	var purgeable []CompletedSession
+	for _, cs := range s.CompletedSessions {
+		if cs.DeletedAt != nil && !cs.DeletedAt.After(before) {
+			purgeable = append(purgeable, cs)
 		}
 	}
+	return purgeable
+}
+
+// PurgeDeleted permanently removes every session deleted at or before the given
+// time and returns how many were removed. Returns an error if there are none.
+func (s *FlowState) PurgeDeleted(before time.Time) (int, error) {
+	count := len(s.PurgeableSessions(before))
+	if err := s.record(Event{Kind: EventPurge, At: s.clock.Now(), Before: before}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Replace swaps the whole state for a copy of other, e.g. to import data that
+// predates the event log. The replacement is recorded like any other transition.
+func (s *FlowState) Replace(other *FlowState) error {
+	return s.record(Event{Kind: EventReplace, At: s.clock.Now(), Snapshot: other.clone()})
+}
+
+// checkAt validates an explicit time for a transition: it cannot be in the
+// future, or before the previous transition, which would reorder history.
+func (s *FlowState) checkAt(at time.Time) error {
+	if now := s.clock.Now(); at.After(now) {
+		return fmt.Errorf("%w: %s", ErrTimeInFuture, at.Format("2006-01-02 15:04:05"))
+	}
+	if previous := s.lastTransition(); at.Before(previous) {
+		return fmt.Errorf("%w at %s", ErrTimeBeforePrevious, previous.Format("2006-01-02 15:04:05"))
+	}
 	return nil
 }
+
+// lastTransition returns the time of the latest recorded change to the
+// current session, or the completion of the last session if idle. It is the
+// zero time if there is neither.
+func (s *FlowState) lastTransition() time.Time {
+	var last time.Time
+	later := func(t time.Time) {
+		if t.After(last) {
+			last = t
+		}
+	}
+
+	if session := s.CurrentSession; session != nil {
+		later(session.FlowStart())
+		if s.CurrentBreak != nil {
+			later(s.CurrentBreak.StartTime)
+		}
+		for _, n := range session.Notes {
+			later(n.At)
+		}
+		for _, in := range session.Interruptions {
+			later(in.At)
+		}
+		return last
+	}
+
+	for _, cs := range s.ActiveSessions() {
+		later(cs.CompletedAt)
+	}
+	return last
+}
+
+// clone returns a deep copy of the state's data. Pending events are not copied.
+func (s *FlowState) clone() *FlowState {
+	c := &FlowState{
+		clock:             s.clock,
+		breakPolicy:       s.breakPolicy,
+		longBreak:         s.longBreak,
+		revision:          s.revision,
+		undo:              slices.Clone(s.undo),
+		redo:              slices.Clone(s.redo),
+		CurrentSession:    cloneSession(s.CurrentSession),
+		CurrentBreak:      cloneBreak(s.CurrentBreak),
+		CompletedSessions: make([]CompletedSession, len(s.CompletedSessions)),
+	}
+	for i, cs := range s.CompletedSessions {
+		c.CompletedSessions[i] = cloneCompletedSession(cs)
+	}
+	return c
+}
+
+// cloneSession returns a deep copy of session, which may be nil.
+func cloneSession(session *Session) *Session {
+	if session == nil {
+		return nil
+	}
+	c := *session
+	c.Intervals = cloneIntervals(c.Intervals)
+	c.Tags = slices.Clone(c.Tags)
+	c.Notes = slices.Clone(c.Notes)
+	c.Interruptions = slices.Clone(c.Interruptions)
+	return &c
+}
+
+// cloneBreak returns a copy of brk, which may be nil.
+func cloneBreak(brk *Break) *Break {
+	if brk == nil {
+		return nil
+	}
+	c := *brk
+	return &c
+}
+
+// cloneCompletedSession returns a deep copy of cs.
+func cloneCompletedSession(cs CompletedSession) CompletedSession {
+	cs.Intervals = cloneIntervals(cs.Intervals)
+	cs.Tags = slices.Clone(cs.Tags)
+	cs.Notes = slices.Clone(cs.Notes)
+	cs.Interruptions = slices.Clone(cs.Interruptions)
+	if cs.BreakDuration != nil {
+		bd := *cs.BreakDuration
+		cs.BreakDuration = &bd
+	}
+	if cs.DeletedAt != nil {
+		t := *cs.DeletedAt
+		cs.DeletedAt = &t
+	}
+	return cs
+}
@@ -2,6 +2,7 @@ package flowtime
 
 import (
 	"errors"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -136,11 +137,12 @@ func TestTakeBreak(t *testing.T) {
 }
 
 func TestResume(t *testing.T) {
-	t.Run("from break returns true with correct completed session", func(t *testing.T) {
+	t.Run("from break returns true and continues the block", func(t *testing.T) {
 		clock := newTestClock()
 		state := NewFlowState(clock)
 
 		_ = state.StartSession("write code")
+		start := clock.Now()
 		clock.Advance(25 * time.Minute) // 25 min flow
 		_ = state.TakeBreak()
 		clock.Advance(5 * time.Minute) // 5 min break
@@ -153,30 +155,32 @@ func TestResume(t *testing.T) {
 			t.Error("expected resumedCurrent = true")
 		}
 
-		// Should have one completed session
-		if len(state.CompletedSessions) != 1 {
-			t.Fatalf("completed sessions = %d, want 1", len(state.CompletedSessions))
-		}
-		cs := state.CompletedSessions[0]
-		if cs.Task != "write code" {
-			t.Errorf("completed task = %q, want %q", cs.Task, "write code")
-		}
-		if cs.FlowDuration != 25*time.Minute {
-			t.Errorf("flow duration = %v, want %v", cs.FlowDuration, 25*time.Minute)
+		if len(state.CompletedSessions) != 0 {
+			t.Fatalf("completed sessions = %d, want 0", len(state.CompletedSessions))
 		}
-		if cs.BreakDuration == nil || *cs.BreakDuration != 5*time.Minute {
-			t.Errorf("break duration = %v, want %v", cs.BreakDuration, 5*time.Minute)
+		if state.CurrentBreak != nil {
+			t.Error("expected current break to be cleared")
 		}
 
-		// New session should be started with same task
+		// The same block continues with the same task
 		if state.CurrentSession == nil {
-			t.Fatal("expected new current session")
+			t.Fatal("expected current session")
 		}
 		if state.CurrentSession.Task != "write code" {
-			t.Errorf("new session task = %q, want %q", state.CurrentSession.Task, "write code")
+			t.Errorf("session task = %q, want %q", state.CurrentSession.Task, "write code")
 		}
-		if state.CurrentBreak != nil {
-			t.Error("expected current break to be cleared")
+		if !state.CurrentSession.StartTime.Equal(start) {
+			t.Errorf("session start = %v, want %v", state.CurrentSession.StartTime, start)
+		}
+		wantIntervals := []Interval{
+			{Kind: IntervalFlow, Start: start, End: start.Add(25 * time.Minute)},
+			{Kind: IntervalBreak, Start: start.Add(25 * time.Minute), End: start.Add(30 * time.Minute)},
+		}
+		if !reflect.DeepEqual(state.CurrentSession.Intervals, wantIntervals) {
+			t.Errorf("intervals = %+v, want %+v", state.CurrentSession.Intervals, wantIntervals)
+		}
+		if !state.CurrentSession.FlowStart().Equal(clock.Now()) {
+			t.Errorf("flow start = %v, want %v", state.CurrentSession.FlowStart(), clock.Now())
 		}
 	})
 
@@ -308,14 +312,104 @@ func TestStop(t *testing.T) {
 	})
 }
 
+func TestSwitch(t *testing.T) {
+	t.Run("completes the session and starts the next without a gap", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+
+		_ = state.StartSession("write code +flower")
+		clock.Advance(25 * time.Minute)
+		switchedAt := clock.Now()
+
+		completed, err := state.Switch("review PR @review")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if completed.Task != "write code" || completed.Project != "flower" {
+			t.Errorf("completed = %q +%s, want %q +flower", completed.Task, completed.Project, "write code")
+		}
+		if completed.FlowDuration != 25*time.Minute || completed.BreakDuration != nil {
+			t.Errorf("flow, break = %v, %v; want 25m, nil", completed.FlowDuration, completed.BreakDuration)
+		}
+		if !completed.CompletedAt.Equal(switchedAt) {
+			t.Errorf("completed at = %v, want %v", completed.CompletedAt, switchedAt)
+		}
+
+		current := state.CurrentSession
+		if current == nil || current.Task != "review PR" || current.Project != "" || !reflect.DeepEqual(current.Tags, []string{"review"}) {
+			t.Fatalf("current session = %+v, want review PR @review", current)
+		}
+		if !current.StartTime.Equal(switchedAt) {
+			t.Errorf("new session started at %v, want %v", current.StartTime, switchedAt)
+		}
+		if events := state.PendingEvents(); len(events) != 2 || events[1].Kind != EventSwitch {
+			t.Errorf("events = %+v, want start then a single switch", events)
+		}
+	})
+
+	t.Run("from break ends the break", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+
+		_ = state.StartSession("write code")
+		clock.Advance(30 * time.Minute)
+		_ = state.TakeBreak()
+		clock.Advance(5 * time.Minute)
+
+		completed, err := state.Switch("email")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if completed.BreakDuration == nil || *completed.BreakDuration != 5*time.Minute {
+			t.Errorf("break duration = %v, want 5m", completed.BreakDuration)
+		}
+		if state.CurrentBreak != nil {
+			t.Error("expected current break to be cleared")
+		}
+	})
+
+	t.Run("rejects invalid switches", func(t *testing.T) {
+		state := NewFlowState(newTestClock())
+		if _, err := state.Switch("task"); !errors.Is(err, ErrNoActiveSession) {
+			t.Errorf("idle: error = %v, want %v", err, ErrNoActiveSession)
+		}
+
+		_ = state.StartSession("task")
+		if _, err := state.Switch("  "); !errors.Is(err, ErrTaskEmpty) {
+			t.Errorf("empty task: error = %v, want %v", err, ErrTaskEmpty)
+		}
+		if len(state.CompletedSessions) != 0 || state.CurrentSession.Task != "task" {
+			t.Errorf("a rejected switch changed the state: %+v", state.CurrentSession)
+		}
+	})
+
+	t.Run("replays", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		_ = state.StartSession("first")
+		clock.Advance(time.Hour)
+		_, _ = state.Switch("second")
+
+		replayed, err := Replay(clock, state.PendingEvents())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(replayed.CompletedSessions, state.CompletedSessions) || !reflect.DeepEqual(replayed.CurrentSession, state.CurrentSession) {
+			t.Errorf("replayed = %+v, %+v; want %+v, %+v",
+				replayed.CompletedSessions, replayed.CurrentSession, state.CompletedSessions, state.CurrentSession)
+		}
+	})
+}
+
 func TestFullCycle(t *testing.T) {
 	clock := newTestClock()
 	state := NewFlowState(clock)
+	start := clock.Now()
 
-	// Session 1: start -> break -> resume
+	// Flow -> break -> resume within one block
 	err := state.StartSession("implement feature")
 	if err != nil {
-		t.Fatalf("start session 1: %v", err)
+		t.Fatalf("start session: %v", err)
 	}
 
 	clock.Advance(40 * time.Minute) // 40 min flow
@@ -329,31 +423,22 @@ func TestFullCycle(t *testing.T) {
 
 	resumed, err := state.Resume()
 	if err != nil {
-		t.Fatalf("resume 1: %v", err)
+		t.Fatalf("resume: %v", err)
 	}
 	if !resumed {
-		t.Error("expected resumedCurrent = true for first resume")
+		t.Error("expected resumedCurrent = true")
 	}
 
-	// Verify first completed session
-	if len(state.CompletedSessions) != 1 {
-		t.Fatalf("after resume 1: completed sessions = %d, want 1", len(state.CompletedSessions))
-	}
-	cs1 := state.CompletedSessions[0]
-	if cs1.FlowDuration != 40*time.Minute {
-		t.Errorf("session 1 flow = %v, want %v", cs1.FlowDuration, 40*time.Minute)
-	}
-	if cs1.BreakDuration == nil || *cs1.BreakDuration != 8*time.Minute {
-		t.Errorf("session 1 break = %v, want %v", cs1.BreakDuration, 8*time.Minute)
-	}
-
-	// Session 2: (already resumed) -> break -> stop
+	// (already resumed) -> break -> stop
 	clock.Advance(20 * time.Minute) // 20 min flow
 
 	err = state.TakeBreak()
 	if err != nil {
 		t.Fatalf("take break 2: %v", err)
 	}
+	if state.CurrentBreak.SuggestedDuration != CalculateBreak(20*time.Minute) {
+		t.Errorf("suggested break = %v, want one based on the 20 min interval", state.CurrentBreak.SuggestedDuration)
+	}
 
 	clock.Advance(5 * time.Minute) // 5 min break
 
@@ -362,18 +447,27 @@ func TestFullCycle(t *testing.T) {
 		t.Fatalf("stop: %v", err)
 	}
 
-	// Verify second completed session
-	if len(state.CompletedSessions) != 2 {
-		t.Fatalf("after stop: completed sessions = %d, want 2", len(state.CompletedSessions))
+	// The whole block is one completed session
+	if len(state.CompletedSessions) != 1 {
+		t.Fatalf("after stop: completed sessions = %d, want 1", len(state.CompletedSessions))
 	}
-	if completed.FlowDuration != 20*time.Minute {
-		t.Errorf("session 2 flow = %v, want %v", completed.FlowDuration, 20*time.Minute)
+	if completed.FlowDuration != 60*time.Minute {
+		t.Errorf("flow = %v, want %v", completed.FlowDuration, 60*time.Minute)
 	}
-	if completed.BreakDuration == nil || *completed.BreakDuration != 5*time.Minute {
-		t.Errorf("session 2 break = %v, want %v", completed.BreakDuration, 5*time.Minute)
+	if completed.BreakDuration == nil || *completed.BreakDuration != 13*time.Minute {
+		t.Errorf("break = %v, want %v", completed.BreakDuration, 13*time.Minute)
 	}
 	if completed.Task != "implement feature" {
-		t.Errorf("session 2 task = %q, want %q", completed.Task, "implement feature")
+		t.Errorf("task = %q, want %q", completed.Task, "implement feature")
+	}
+	wantIntervals := []Interval{
+		{Kind: IntervalFlow, Start: start, End: start.Add(40 * time.Minute)},
+		{Kind: IntervalBreak, Start: start.Add(40 * time.Minute), End: start.Add(48 * time.Minute)},
+		{Kind: IntervalFlow, Start: start.Add(48 * time.Minute), End: start.Add(68 * time.Minute)},
+		{Kind: IntervalBreak, Start: start.Add(68 * time.Minute), End: start.Add(73 * time.Minute)},
+	}
+	if !reflect.DeepEqual(completed.Intervals, wantIntervals) {
+		t.Errorf("intervals = %+v, want %+v", completed.Intervals, wantIntervals)
 	}
 
 	// State should be idle
@@ -634,3 +728,190 @@ func TestResumeSkipsDeletedSessions(t *testing.T) {
 		t.Errorf("task = %q, want %q", state.CurrentSession.Task, "old task")
 	}
 }
+
+func TestRestoreSession(t *testing.T) {
+	t.Run("undeletes session at index", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+
+		now := clock.Now()
+		state.CompletedSessions = []CompletedSession{
+			{ID: "aaaaaaaa", Task: "task 1", FlowDuration: 10 * time.Minute, CompletedAt: now, DeletedAt: &now},
+			{ID: "bbbbbbbb", Task: "task 2", FlowDuration: 20 * time.Minute, CompletedAt: now},
+		}
+
+		if err := state.RestoreSession(0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if state.CompletedSessions[0].DeletedAt != nil {
+			t.Error("expected DeletedAt to be cleared")
+		}
+		if len(state.DeletedSessions()) != 0 {
+			t.Errorf("deleted sessions = %d, want 0", len(state.DeletedSessions()))
+		}
+	})
+
+	t.Run("errors on out-of-range index", func(t *testing.T) {
+		state := NewFlowState(newTestClock())
+
+		err := state.RestoreSession(0)
+		if !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("error = %v, want %v", err, ErrSessionNotFound)
+		}
+	})
+
+	t.Run("errors on session that is not deleted", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		state.CompletedSessions = []CompletedSession{
+			{ID: "aaaaaaaa", Task: "task 1", FlowDuration: 10 * time.Minute, CompletedAt: clock.Now()},
+		}
+
+		err := state.RestoreSession(0)
+		if !errors.Is(err, ErrSessionNotDeleted) {
+			t.Errorf("error = %v, want %v", err, ErrSessionNotDeleted)
+		}
+		if len(state.PendingEvents()) != 0 {
+			t.Errorf("pending events = %d, want 0", len(state.PendingEvents()))
+		}
+	})
+}
+
+func TestRestoreSessionsSince(t *testing.T) {
+	clock := newTestClock()
+	state := NewFlowState(clock)
+
+	early := clock.Now()
+	late := early.Add(2 * time.Hour)
+	state.CompletedSessions = []CompletedSession{
+		{ID: "aaaaaaaa", Task: "deleted early", CompletedAt: early, DeletedAt: &early},
+		{ID: "bbbbbbbb", Task: "deleted late", CompletedAt: early, DeletedAt: &late},
+		{ID: "cccccccc", Task: "active", CompletedAt: early},
+	}
+
+	restored, err := state.RestoreSessionsSince(early.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restored != 1 {
+		t.Errorf("restored = %d, want 1", restored)
+	}
+	if state.CompletedSessions[0].DeletedAt == nil {
+		t.Error("expected session deleted before the cutoff to stay deleted")
+	}
+	if state.CompletedSessions[1].DeletedAt != nil {
+		t.Error("expected session deleted after the cutoff to be restored")
+	}
+
+	_, err = state.RestoreSessionsSince(early.Add(time.Hour))
+	if !errors.Is(err, ErrNoSessionsToRestore) {
+		t.Errorf("error = %v, want %v", err, ErrNoSessionsToRestore)
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	clock := newTestClock()
+	state := NewFlowState(clock)
+
+	early := clock.Now()
+	late := early.Add(2 * time.Hour)
+	state.CompletedSessions = []CompletedSession{
+		{ID: "aaaaaaaa", Task: "deleted early", CompletedAt: early, DeletedAt: &early},
+		{ID: "bbbbbbbb", Task: "active", CompletedAt: early},
+		{ID: "cccccccc", Task: "deleted late", CompletedAt: early, DeletedAt: &late},
+	}
+
+	cutoff := early.Add(time.Hour)
+	if got := state.PurgeableSessions(cutoff); len(got) != 1 || got[0].ID != "aaaaaaaa" {
+		t.Fatalf("purgeable = %+v, want only the early deletion", got)
+	}
+
+	purged, err := state.PurgeDeleted(cutoff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("purged = %d, want 1", purged)
+	}
+	if len(state.CompletedSessions) != 2 || state.CompletedSessions[0].ID != "bbbbbbbb" || state.CompletedSessions[1].ID != "cccccccc" {
+		t.Errorf("remaining sessions = %+v", state.CompletedSessions)
+	}
+
+	_, err = state.PurgeDeleted(cutoff)
+	if !errors.Is(err, ErrNoSessionsToPurge) {
+		t.Errorf("error = %v, want %v", err, ErrNoSessionsToPurge)
+	}
+}
+
+func TestTransitionsAt(t *testing.T) {
+	t.Run("records the given times", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		start := clock.Now()
+		clock.Advance(2 * time.Hour)
+
+		if err := state.StartSessionAt("write code", start); err != nil {
+			t.Fatalf("start: unexpected error: %v", err)
+		}
+		if err := state.TakeBreakAt(start.Add(30 * time.Minute)); err != nil {
+			t.Fatalf("break: unexpected error: %v", err)
+		}
+		if state.CurrentBreak.SuggestedDuration != 8*time.Minute {
+			t.Errorf("suggested break = %v, want 8m for 30m of flow", state.CurrentBreak.SuggestedDuration)
+		}
+		if _, err := state.ResumeAt(start.Add(40 * time.Minute)); err != nil {
+			t.Fatalf("resume: unexpected error: %v", err)
+		}
+		completed, err := state.StopWithNoteAt("done", start.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("stop: unexpected error: %v", err)
+		}
+
+		if completed.FlowDuration != 50*time.Minute || *completed.BreakDuration != 10*time.Minute {
+			t.Errorf("flow, break = %v, %v; want 50m, 10m", completed.FlowDuration, *completed.BreakDuration)
+		}
+		if !completed.CompletedAt.Equal(start.Add(time.Hour)) || !completed.Notes[0].At.Equal(completed.CompletedAt) {
+			t.Errorf("completed at %v with note at %v, want both %v", completed.CompletedAt, completed.Notes[0].At, start.Add(time.Hour))
+		}
+	})
+
+	t.Run("rejects times in the future", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		if err := state.StartSessionAt("task", clock.Now().Add(time.Minute)); !errors.Is(err, ErrTimeInFuture) {
+			t.Errorf("error = %v, want %v", err, ErrTimeInFuture)
+		}
+		if state.CurrentSession != nil {
+			t.Error("expected no session to start")
+		}
+	})
+
+	t.Run("rejects times before the previous transition", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		state.StartSession("first")
+		clock.Advance(time.Hour)
+		state.Stop()
+		stopped := clock.Now()
+		clock.Advance(time.Hour)
+
+		if err := state.StartSessionAt("second", stopped.Add(-time.Minute)); !errors.Is(err, ErrTimeBeforePrevious) {
+			t.Errorf("start before last stop: error = %v, want %v", err, ErrTimeBeforePrevious)
+		}
+		if err := state.StartSessionAt("second", stopped); err != nil {
+			t.Fatalf("start at last stop: unexpected error: %v", err)
+		}
+
+		clock.Advance(10 * time.Minute)
+		state.Interrupt("", "")
+		if _, err := state.StopAt(clock.Now().Add(-time.Second)); !errors.Is(err, ErrTimeBeforePrevious) {
+			t.Errorf("stop before interruption: error = %v, want %v", err, ErrTimeBeforePrevious)
+		}
+		if err := state.TakeBreakAt(clock.Now().Add(-time.Second)); !errors.Is(err, ErrTimeBeforePrevious) {
+			t.Errorf("break before interruption: error = %v, want %v", err, ErrTimeBeforePrevious)
+		}
+		if len(state.CompletedSessions) != 1 || state.CurrentBreak != nil {
+			t.Error("rejected transitions changed the state")
+		}
+	})
+}
@@ -44,3 +44,12 @@ func FormatHumanDateTime(t time.Time, now time.Time) string {
 
 	return t.Format("Jan 2, 2006 15:04")
 }
+
+// FormatCycle describes a position in the flow/break rhythm, e.g. "cycle 2 of 4",
+// or just "cycle 2" when long breaks are disabled (every is zero).
+func FormatCycle(cycle, every int) string {
+	if every > 0 {
+		return fmt.Sprintf("cycle %d of %d", cycle, every)
+	}
+	return fmt.Sprintf("cycle %d", cycle)
+}
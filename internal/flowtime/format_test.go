@@ -67,3 +67,12 @@ func TestFormatHumanDateTime(t *testing.T) {
 		}
 	})
 }
+
+func TestFormatCycle(t *testing.T) {
+	if got := FormatCycle(2, 4); got != "cycle 2 of 4" {
+		t.Errorf("FormatCycle(2, 4) = %q, want %q", got, "cycle 2 of 4")
+	}
+	if got := FormatCycle(3, 0); got != "cycle 3" {
+		t.Errorf("FormatCycle(3, 0) = %q, want %q", got, "cycle 3")
+	}
+}
@@ -0,0 +1,96 @@
+package flowtime
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrAmbiguousID = errors.New("session id matches more than one session")
+
+// sessionIDLength is the number of hex characters in a session ID.
+const sessionIDLength = 8
+
+// newSessionID returns a random session ID not used by any completed session.
+func (s *FlowState) newSessionID() string {
+	buf := make([]byte, sessionIDLength/2)
+	for {
+		rand.Read(buf)
+		id := hex.EncodeToString(buf)
+		if isValidSessionID(id) && s.sessionIndex(id) == -1 {
+			return id
+		}
+	}
+}
+
+// LegacySessionID derives a stable ID for a session recorded before sessions had
+// IDs, from its position in CompletedSessions and its contents. The same inputs
+// always give the same ID, so replaying old data assigns the same IDs every time.
+func LegacySessionID(index int, task string, completedAt time.Time) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%s|%d", index, task, completedAt.UnixNano())))
+	digest := hex.EncodeToString(sum[:])
+	for i := 0; i+sessionIDLength <= len(digest); i++ {
+		if id := digest[i : i+sessionIDLength]; isValidSessionID(id) {
+			return id
+		}
+	}
+	// Unreachable in practice: a 64-character digest of only decimal digits.
+	return "a" + digest[1:sessionIDLength]
+}
+
+// isValidSessionID reports whether id can be told apart from a display index,
+// i.e. it is not made up only of decimal digits.
+func isValidSessionID(id string) bool {
+	_, err := strconv.Atoi(id)
+	return err != nil
+}
+
+// SessionIndex returns the index into CompletedSessions of the session whose ID
+// equals or uniquely starts with id. Deleted sessions are included.
+func (s *FlowState) SessionIndex(id string) (int, error) {
+	if id == "" {
+		return -1, ErrSessionNotFound
+	}
+	if i := s.sessionIndex(id); i != -1 {
+		return i, nil
+	}
+
+	match := -1
+	for i, cs := range s.CompletedSessions {
+		if strings.HasPrefix(cs.ID, id) {
+			if match != -1 {
+				return -1, fmt.Errorf("%w: %q", ErrAmbiguousID, id)
+			}
+			match = i
+		}
+	}
+	if match == -1 {
+		return -1, fmt.Errorf("%w: %q", ErrSessionNotFound, id)
+	}
+	return match, nil
+}
+
+// sessionIndex returns the index of the session with exactly the given ID, or -1.
+func (s *FlowState) sessionIndex(id string) int {
+	for i, cs := range s.CompletedSessions {
+		if cs.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// BackfillSessionIDs assigns a LegacySessionID to every completed session without an ID.
+func (s *FlowState) BackfillSessionIDs() {
+	for i := range s.CompletedSessions {
+		cs := &s.CompletedSessions[i]
+		if cs.ID == "" {
+			cs.ID = LegacySessionID(i, cs.Task, cs.CompletedAt)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package flowtime
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSessionIDsAssignedOnCompletion(t *testing.T) {
+	clock := newTestClock()
+	state := NewFlowState(clock)
+
+	_ = state.StartSession("first")
+	clock.Advance(10 * time.Minute)
+	_, _ = state.Stop()
+	_ = state.StartSession("second")
+	clock.Advance(10 * time.Minute)
+	stopped, err := state.Stop()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(state.CompletedSessions) != 2 {
+		t.Fatalf("completed sessions = %d, want 2", len(state.CompletedSessions))
+	}
+	first, second := state.CompletedSessions[0].ID, state.CompletedSessions[1].ID
+	for _, id := range []string{first, second} {
+		if len(id) != sessionIDLength {
+			t.Errorf("id %q has length %d, want %d", id, len(id), sessionIDLength)
+		}
+		if _, err := strconv.Atoi(id); err == nil {
+			t.Errorf("id %q is numeric and could be mistaken for an index", id)
+		}
+	}
+	if first == second {
+		t.Errorf("sessions share id %q", first)
+	}
+	if stopped.ID != second {
+		t.Errorf("Stop returned id %q, want %q", stopped.ID, second)
+	}
+}
+
+func TestSessionIndex(t *testing.T) {
+	state := NewFlowState(newTestClock())
+	state.CompletedSessions = []CompletedSession{
+		{ID: "abc12345", Task: "a"},
+		{ID: "abd67890", Task: "b"},
+		{ID: "ffff0000", Task: "c"},
+	}
+
+	tests := map[string]struct {
+		id      string
+		want    int
+		wantErr error
+	}{
+		"exact match":      {id: "abd67890", want: 1},
+		"unique prefix":    {id: "ff", want: 2},
+		"ambiguous prefix": {id: "ab", wantErr: ErrAmbiguousID},
+		"no match":         {id: "zz", wantErr: ErrSessionNotFound},
+		"empty":            {id: "", wantErr: ErrSessionNotFound},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := state.SessionIndex(tt.id)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("index = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteSessionTargetsID(t *testing.T) {
+	clock := newTestClock()
+	state := NewFlowState(clock)
+	_ = state.StartSession("old")
+	_, _ = state.Stop()
+	_ = state.StartSession("new")
+	_, _ = state.Stop()
+
+	// The base has an extra session in front, shifting positions by one.
+	base := NewFlowState(clock)
+	base.CompletedSessions = append([]CompletedSession{{ID: "aaaaaaaa", Task: "extra"}}, state.CompletedSessions...)
+	state.ClearPendingEvents()
+
+	target := state.CompletedSessions[0].ID
+	if err := state.DeleteSession(0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := state.Rebase(base); err != nil {
+		t.Fatalf("unexpected rebase error: %v", err)
+	}
+
+	for _, cs := range state.CompletedSessions {
+		if deleted := cs.DeletedAt != nil; deleted != (cs.ID == target) {
+			t.Errorf("session %s (%s) deleted = %v", cs.ID, cs.Task, deleted)
+		}
+	}
+}
+
+func TestLegacySessionID(t *testing.T) {
+	at := time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	id := LegacySessionID(0, "task", at)
+	if id != LegacySessionID(0, "task", at) {
+		t.Error("expected the same inputs to give the same id")
+	}
+	if id == LegacySessionID(1, "task", at) {
+		t.Error("expected different positions to give different ids")
+	}
+	if len(id) != sessionIDLength || !isValidSessionID(id) {
+		t.Errorf("invalid id %q", id)
+	}
+}
+
+func TestBackfillSessionIDs(t *testing.T) {
+	at := time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)
+	state := NewFlowState(newTestClock())
+	state.CompletedSessions = []CompletedSession{
+		{Task: "a", CompletedAt: at},
+		{ID: "keepme12", Task: "b", CompletedAt: at},
+	}
+
+	state.BackfillSessionIDs()
+
+	if got, want := state.CompletedSessions[0].ID, LegacySessionID(0, "a", at); got != want {
+		t.Errorf("backfilled id = %q, want %q", got, want)
+	}
+	if got := state.CompletedSessions[1].ID; got != "keepme12" {
+		t.Errorf("existing id changed to %q", got)
+	}
+}
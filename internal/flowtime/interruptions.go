@@ -0,0 +1,97 @@
+package flowtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInterruptOnBreak        = errors.New("cannot record an interruption during a break")
+	ErrInvalidInterruptionKind = errors.New("interruption kind must be internal or external")
+	ErrReasonTooLong           = errors.New("interruption reason cannot exceed 200 characters")
+)
+
+// maxReasonLength is the longest interruption reason accepted, in bytes.
+const maxReasonLength = 200
+
+// InterruptionKind tells where an interruption came from. It is optional; the
+// zero value means it was not specified.
+type InterruptionKind string
+
+const (
+	// InterruptionInternal is a distraction that came from within, like the urge
+	// to check email.
+	InterruptionInternal InterruptionKind = "internal"
+	// InterruptionExternal is a distraction imposed by someone or something else,
+	// like a colleague's question.
+	InterruptionExternal InterruptionKind = "external"
+)
+
+// Interruption records something that broke the user's flow without them
+// taking a break.
+type Interruption struct {
+	At     time.Time
+	Kind   InterruptionKind
+	Reason string
+}
+
+// Interrupt records an interruption of the current flow. kind may be empty if
+// the source is not worth noting, and reason is optional.
+// Returns an error if no session is active, the session is on a break, the kind
+// is unknown or the reason exceeds 200 characters.
+func (s *FlowState) Interrupt(kind InterruptionKind, reason string) error {
+	return s.record(Event{Kind: EventInterrupt, At: s.clock.Now(), Interruption: kind, Text: strings.TrimSpace(reason)})
+}
+
+func (s *FlowState) applyInterrupt(e Event) error {
+	if s.CurrentSession == nil {
+		return ErrNoActiveSession
+	}
+	if s.CurrentBreak != nil {
+		return ErrInterruptOnBreak
+	}
+	switch e.Interruption {
+	case "", InterruptionInternal, InterruptionExternal:
+	default:
+		return fmt.Errorf("%w: got %q", ErrInvalidInterruptionKind, e.Interruption)
+	}
+	if len(e.Text) > maxReasonLength {
+		return fmt.Errorf("%w: got %d characters", ErrReasonTooLong, len(e.Text))
+	}
+
+	s.CurrentSession.Interruptions = append(s.CurrentSession.Interruptions, Interruption{
+		At:     e.At,
+		Kind:   e.Interruption,
+		Reason: e.Text,
+	})
+	return nil
+}
+
+// FormatInterruptions summarises interruptions as a count, split by kind where
+// known, e.g. "3 (2 internal, 1 external)". Returns "0" if there are none.
+func FormatInterruptions(interruptions []Interruption) string {
+	var internal, external int
+	for _, in := range interruptions {
+		switch in.Kind {
+		case InterruptionInternal:
+			internal++
+		case InterruptionExternal:
+			external++
+		}
+	}
+
+	var parts []string
+	if internal > 0 {
+		parts = append(parts, fmt.Sprintf("%d internal", internal))
+	}
+	if external > 0 {
+		parts = append(parts, fmt.Sprintf("%d external", external))
+	}
+	total := fmt.Sprint(len(interruptions))
+	if len(parts) == 0 {
+		return total
+	}
+	return total + " (" + strings.Join(parts, ", ") + ")"
+}
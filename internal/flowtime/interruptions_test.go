@@ -0,0 +1,81 @@
+package flowtime
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterrupt(t *testing.T) {
+	t.Run("records interruptions on the completed session", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		state.StartSession("write code")
+
+		clock.Advance(5 * time.Minute)
+		if err := state.Interrupt(InterruptionExternal, " colleague question "); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		first := clock.Now()
+		clock.Advance(5 * time.Minute)
+		if err := state.Interrupt("", ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		completed, err := state.Stop()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []Interruption{
+			{At: first, Kind: InterruptionExternal, Reason: "colleague question"},
+			{At: first.Add(5 * time.Minute)},
+		}
+		if !reflect.DeepEqual(completed.Interruptions, want) {
+			t.Errorf("interruptions = %v, want %v", completed.Interruptions, want)
+		}
+	})
+
+	t.Run("rejects invalid interruptions", func(t *testing.T) {
+		state := NewFlowState(newTestClock())
+		if err := state.Interrupt("", ""); !errors.Is(err, ErrNoActiveSession) {
+			t.Errorf("idle: error = %v, want %v", err, ErrNoActiveSession)
+		}
+
+		state.StartSession("task")
+		if err := state.Interrupt("phone", ""); !errors.Is(err, ErrInvalidInterruptionKind) {
+			t.Errorf("unknown kind: error = %v, want %v", err, ErrInvalidInterruptionKind)
+		}
+		if err := state.Interrupt("", strings.Repeat("x", 201)); !errors.Is(err, ErrReasonTooLong) {
+			t.Errorf("long reason: error = %v, want %v", err, ErrReasonTooLong)
+		}
+
+		state.TakeBreak()
+		if err := state.Interrupt(InterruptionInternal, ""); !errors.Is(err, ErrInterruptOnBreak) {
+			t.Errorf("on break: error = %v, want %v", err, ErrInterruptOnBreak)
+		}
+		if len(state.CurrentSession.Interruptions) != 0 {
+			t.Errorf("rejected interruptions were recorded: %v", state.CurrentSession.Interruptions)
+		}
+	})
+}
+
+func TestFormatInterruptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		interruptions []Interruption
+		want          string
+	}{
+		{"none", nil, "0"},
+		{"unspecified", []Interruption{{}, {}}, "2"},
+		{"mixed", []Interruption{{Kind: InterruptionInternal}, {}, {Kind: InterruptionExternal}, {Kind: InterruptionInternal}}, "4 (2 internal, 1 external)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatInterruptions(tt.interruptions); got != tt.want {
+				t.Errorf("FormatInterruptions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package flowtime
+
+import "time"
+
+// IntervalKind distinguishes the stretches of time that make up a work block.
+type IntervalKind string
+
+const (
+	IntervalFlow  IntervalKind = "flow"
+	IntervalBreak IntervalKind = "break"
+)
+
+// Interval is a single stretch of flow or break within a work block.
+type Interval struct {
+	Kind  IntervalKind
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the interval.
+func (i Interval) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// FlowStart returns when the session's current flow interval started: the end
+// of its last finished interval, or the start of the block.
+func (s *Session) FlowStart() time.Time {
+	if len(s.Intervals) == 0 {
+		return s.StartTime
+	}
+	return s.Intervals[len(s.Intervals)-1].End
+}
+
+// intervalTotals sums the flow and break time of intervals. breakDuration is nil
+// if there are no break intervals.
+func intervalTotals(intervals []Interval) (flow time.Duration, breakDuration *time.Duration) {
+	for _, iv := range intervals {
+		switch iv.Kind {
+		case IntervalFlow:
+			flow += iv.Duration()
+		case IntervalBreak:
+			if breakDuration == nil {
+				breakDuration = new(time.Duration)
+			}
+			*breakDuration += iv.Duration()
+		}
+	}
+	return flow, breakDuration
+}
+
+// newCompletedSession builds a completed block from its intervals, deriving the
+// flow and break totals.
+func newCompletedSession(id, task string, intervals []Interval, completedAt time.Time) CompletedSession {
+	flow, breakDuration := intervalTotals(intervals)
+	return CompletedSession{
+		ID:            id,
+		Task:          task,
+		Intervals:     intervals,
+		FlowDuration:  flow,
+		BreakDuration: breakDuration,
+		CompletedAt:   completedAt,
+	}
+}
+
+// LegacyIntervals reconstructs the intervals of a session recorded before blocks
+// kept them: one flow interval followed by an optional break that ended at
+// completion.
+func LegacyIntervals(flow time.Duration, breakDuration *time.Duration, completedAt time.Time) []Interval {
+	flowEnd := completedAt
+	var intervals []Interval
+	if breakDuration != nil {
+		flowEnd = completedAt.Add(-*breakDuration)
+		intervals = append(intervals, Interval{Kind: IntervalBreak, Start: flowEnd, End: completedAt})
+	}
+	return append([]Interval{{Kind: IntervalFlow, Start: flowEnd.Add(-flow), End: flowEnd}}, intervals...)
+}
+
+// BackfillIntervals reconstructs the intervals of every completed session
+// recorded without them.
+func (s *FlowState) BackfillIntervals() {
+	for i := range s.CompletedSessions {
+		cs := &s.CompletedSessions[i]
+		if len(cs.Intervals) == 0 {
+			cs.Intervals = LegacyIntervals(cs.FlowDuration, cs.BreakDuration, cs.CompletedAt)
+		}
+	}
+}
+
+// cloneIntervals returns a copy of intervals, or nil if there are none.
+func cloneIntervals(intervals []Interval) []Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+	return append([]Interval(nil), intervals...)
+}
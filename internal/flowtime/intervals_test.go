@@ -0,0 +1,59 @@
+package flowtime
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLegacyIntervals(t *testing.T) {
+	completedAt := time.Date(2025, 6, 15, 11, 0, 0, 0, time.UTC)
+
+	t.Run("flow only", func(t *testing.T) {
+		got := LegacyIntervals(30*time.Minute, nil, completedAt)
+		want := []Interval{
+			{Kind: IntervalFlow, Start: completedAt.Add(-30 * time.Minute), End: completedAt},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("intervals = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("flow then break", func(t *testing.T) {
+		breakDuration := 5 * time.Minute
+		got := LegacyIntervals(30*time.Minute, &breakDuration, completedAt)
+		want := []Interval{
+			{Kind: IntervalFlow, Start: completedAt.Add(-35 * time.Minute), End: completedAt.Add(-5 * time.Minute)},
+			{Kind: IntervalBreak, Start: completedAt.Add(-5 * time.Minute), End: completedAt},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("intervals = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestStopClosesLegacyBreak(t *testing.T) {
+	// A snapshot written before blocks kept intervals has a break but no
+	// interval for the flow before it.
+	clock := newTestClock()
+	start := clock.Now()
+	state := NewFlowState(clock)
+	state.CurrentSession = &Session{Task: "write code", StartTime: start}
+	state.CurrentBreak = &Break{StartTime: start.Add(20 * time.Minute), SuggestedDuration: 4 * time.Minute}
+	clock.Advance(24 * time.Minute)
+
+	completed, err := state.Stop()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Interval{
+		{Kind: IntervalFlow, Start: start, End: start.Add(20 * time.Minute)},
+		{Kind: IntervalBreak, Start: start.Add(20 * time.Minute), End: start.Add(24 * time.Minute)},
+	}
+	if !reflect.DeepEqual(completed.Intervals, want) {
+		t.Errorf("intervals = %+v, want %+v", completed.Intervals, want)
+	}
+	if completed.BreakDuration == nil || *completed.BreakDuration != 4*time.Minute {
+		t.Errorf("break = %v, want %v", completed.BreakDuration, 4*time.Minute)
+	}
+}
@@ -0,0 +1,243 @@
+package flowtime
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var (
+	ErrInvalidTimeRange = errors.New("session must end after it starts")
+	ErrNegativeDuration = errors.New("durations cannot be negative")
+	ErrSessionOverlap   = errors.New("session overlaps another session")
+	ErrNothingToEdit    = errors.New("nothing to edit")
+	ErrDuplicateID      = errors.New("a session with this ID already exists")
+)
+
+// SessionEdit lists the changes to make to a completed session. Nil fields are
+// left unchanged. A Break of zero removes the session's break.
+type SessionEdit struct {
+	Task        *string // parsed like StartSession's input; labels replace the session's if given
+	Flow        *time.Duration
+	Break       *time.Duration
+	CompletedAt *time.Time
+}
+
+// AddSession records a session that was never timed, worked from from to to
+// with a single break of breakDuration at its end. The task is parsed like
+// StartSession's input. Returns an error if the times are out of order or in
+// the future, the break is negative or leaves no time for flow, or the session
+// overlaps another one.
+func (s *FlowState) AddSession(input string, from, to time.Time, breakDuration time.Duration) (*CompletedSession, error) {
+	task, project, tags, err := ParseTask(input)
+	if err != nil {
+		return nil, err
+	}
+	if !to.After(from) {
+		return nil, ErrInvalidTimeRange
+	}
+	if to.After(s.clock.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrTimeInFuture, to.Format(time.DateTime))
+	}
+	if breakDuration < 0 {
+		return nil, ErrNegativeDuration
+	}
+	if breakDuration >= to.Sub(from) {
+		return nil, fmt.Errorf("%w: a %s break leaves no flow between %s and %s",
+			ErrInvalidTimeRange, FormatDuration(breakDuration), from.Format("15:04"), to.Format("15:04"))
+	}
+
+	var breakPtr *time.Duration
+	if breakDuration > 0 {
+		breakPtr = &breakDuration
+	}
+	session := newCompletedSession(s.newSessionID(), task, LegacyIntervals(to.Sub(from)-breakDuration, breakPtr, to), to)
+	session.Project = project
+	session.Tags = tags
+
+	if err := s.record(Event{Kind: EventAdd, At: s.clock.Now(), Session: &session}); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// EditSession applies edit to the completed session at the given index (into the
+// full CompletedSessions slice) and returns the edited session. Moving the
+// completion time shifts the whole block, while changing the flow or break
+// duration replaces its intervals with one flow interval followed by the break.
+// Returns an error if the session is deleted, the edit is empty or invalid, or
+// the edited session would overlap another one.
+func (s *FlowState) EditSession(index int, edit SessionEdit) (*CompletedSession, error) {
+	if index < 0 || index >= len(s.CompletedSessions) {
+		return nil, ErrSessionNotFound
+	}
+	if edit == (SessionEdit{}) {
+		return nil, ErrNothingToEdit
+	}
+
+	session := s.CompletedSessions[index]
+	session.Intervals = cloneIntervals(session.Intervals)
+	session.Notes = slices.Clone(session.Notes)
+	session.Interruptions = slices.Clone(session.Interruptions)
+
+	if edit.Task != nil {
+		task, project, tags, err := ParseTask(*edit.Task)
+		if err != nil {
+			return nil, err
+		}
+		session.Task = task
+		if project != "" || len(tags) > 0 {
+			session.Project = project
+			session.Tags = tags
+		}
+	}
+
+	if edit.CompletedAt != nil {
+		if edit.CompletedAt.After(s.clock.Now()) {
+			return nil, fmt.Errorf("%w: %s", ErrTimeInFuture, edit.CompletedAt.Format(time.DateTime))
+		}
+		session.shift(edit.CompletedAt.Sub(session.CompletedAt))
+	}
+
+	if edit.Flow != nil || edit.Break != nil {
+		flow, breakDuration := session.FlowDuration, session.BreakDuration
+		if edit.Flow != nil {
+			flow = *edit.Flow
+		}
+		if edit.Break != nil {
+			breakDuration = edit.Break
+			if *breakDuration == 0 {
+				breakDuration = nil
+			}
+		}
+		if flow < 0 || (breakDuration != nil && *breakDuration < 0) {
+			return nil, ErrNegativeDuration
+		}
+		session.Intervals = LegacyIntervals(flow, breakDuration, session.CompletedAt)
+	}
+
+	edited := newCompletedSession(session.ID, session.Task, session.Intervals, session.CompletedAt)
+	edited.Project = session.Project
+	edited.Tags = session.Tags
+	edited.Notes = session.Notes
+	edited.Interruptions = session.Interruptions
+	edited.DeletedAt = session.DeletedAt
+
+	if err := s.record(Event{Kind: EventEdit, At: s.clock.Now(), SessionID: edited.ID, Session: &edited}); err != nil {
+		return nil, err
+	}
+	return &edited, nil
+}
+
+// shift moves every timestamp of the session by d.
+func (cs *CompletedSession) shift(d time.Duration) {
+	cs.CompletedAt = cs.CompletedAt.Add(d)
+	for i := range cs.Intervals {
+		cs.Intervals[i].Start = cs.Intervals[i].Start.Add(d)
+		cs.Intervals[i].End = cs.Intervals[i].End.Add(d)
+	}
+	for i := range cs.Notes {
+		cs.Notes[i].At = cs.Notes[i].At.Add(d)
+	}
+	for i := range cs.Interruptions {
+		cs.Interruptions[i].At = cs.Interruptions[i].At.Add(d)
+	}
+}
+
+// Start returns when the session's first interval started, or its completion
+// time if it has none.
+func (cs CompletedSession) Start() time.Time {
+	if len(cs.Intervals) == 0 {
+		return cs.CompletedAt
+	}
+	return cs.Intervals[0].Start
+}
+
+func (s *FlowState) applyAdd(e Event) error {
+	if e.Session == nil {
+		return errors.New("add event has no session")
+	}
+	if s.sessionIndex(e.Session.ID) != -1 {
+		return fmt.Errorf("%w: %s", ErrDuplicateID, e.Session.ID)
+	}
+	if err := checkManualSession(*e.Session); err != nil {
+		return err
+	}
+	if err := s.checkOverlap(*e.Session, -1); err != nil {
+		return err
+	}
+
+	s.insertSession(cloneCompletedSession(*e.Session))
+	return nil
+}
+
+func (s *FlowState) applyEdit(e Event) error {
+	if e.Session == nil {
+		return errors.New("edit event has no session")
+	}
+	index := s.sessionIndex(e.SessionID)
+	if index == -1 {
+		return ErrSessionNotFound
+	}
+	if s.CompletedSessions[index].DeletedAt != nil {
+		return ErrSessionDeleted
+	}
+	if err := checkManualSession(*e.Session); err != nil {
+		return err
+	}
+	// Sessions that already overlap can still be edited as long as they don't move.
+	original := s.CompletedSessions[index]
+	if !e.Session.Start().Equal(original.Start()) || !e.Session.CompletedAt.Equal(original.CompletedAt) {
+		if err := s.checkOverlap(*e.Session, index); err != nil {
+			return err
+		}
+	}
+
+	s.CompletedSessions = slices.Delete(s.CompletedSessions, index, index+1)
+	s.insertSession(cloneCompletedSession(*e.Session))
+	return nil
+}
+
+// checkManualSession validates a session entered or edited by hand.
+func checkManualSession(cs CompletedSession) error {
+	if cs.Task == "" {
+		return ErrTaskEmpty
+	}
+	if len(cs.Task) > 100 {
+		return fmt.Errorf("%w: got %d characters", ErrTaskTooLong, len(cs.Task))
+	}
+	if len(cs.Intervals) == 0 || !intervalsOrdered(cs.Intervals) || !cs.Intervals[len(cs.Intervals)-1].End.Equal(cs.CompletedAt) {
+		return ErrNegativeDuration
+	}
+	return nil
+}
+
+// checkOverlap returns an error if cs overlaps the current session or a
+// non-deleted completed session other than the one at index skip.
+func (s *FlowState) checkOverlap(cs CompletedSession, skip int) error {
+	start, end := cs.Start(), cs.CompletedAt
+	for i, other := range s.CompletedSessions {
+		if i == skip || other.DeletedAt != nil {
+			continue
+		}
+		if start.Before(other.CompletedAt) && other.Start().Before(end) {
+			return fmt.Errorf("%w: %s %q (%s to %s)", ErrSessionOverlap, other.ID, other.Task,
+				other.Start().Format("2006-01-02 15:04"), other.CompletedAt.Format("15:04"))
+		}
+	}
+	if s.CurrentSession != nil && s.CurrentSession.StartTime.Before(end) {
+		return fmt.Errorf("%w: the current session %q, started at %s", ErrSessionOverlap,
+			s.CurrentSession.Task, s.CurrentSession.StartTime.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// insertSession adds cs to CompletedSessions, keeping them in order of completion.
+func (s *FlowState) insertSession(cs CompletedSession) {
+	i := len(s.CompletedSessions)
+	for i > 0 && s.CompletedSessions[i-1].CompletedAt.After(cs.CompletedAt) {
+		i--
+	}
+	s.CompletedSessions = slices.Insert(s.CompletedSessions, i, cs)
+}
@@ -0,0 +1,205 @@
+package flowtime
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddSession(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 6, 15, hour, minute, 0, 0, time.UTC)
+	}
+	// newState returns a state at 12:00 with a session from 10:00 to 11:00.
+	newState := func(t *testing.T) (*FlowState, *mockClock) {
+		t.Helper()
+		clock := &mockClock{now: at(10, 0)}
+		state := NewFlowState(clock)
+		state.StartSession("timed")
+		clock.Advance(time.Hour)
+		state.Stop()
+		clock.Advance(time.Hour)
+		return state, clock
+	}
+
+	t.Run("inserts the session in order", func(t *testing.T) {
+		state, _ := newState(t)
+		added, err := state.AddSession("planning +team @meeting", at(9, 0), at(9, 45), 5*time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if added.Task != "planning" || added.Project != "team" || !reflect.DeepEqual(added.Tags, []string{"meeting"}) {
+			t.Errorf("added = %q %q %v", added.Task, added.Project, added.Tags)
+		}
+		if added.FlowDuration != 40*time.Minute || added.BreakDuration == nil || *added.BreakDuration != 5*time.Minute {
+			t.Errorf("flow, break = %v, %v; want 40m, 5m", added.FlowDuration, added.BreakDuration)
+		}
+		if len(state.CompletedSessions) != 2 || state.CompletedSessions[0].ID != added.ID {
+			t.Fatalf("sessions = %+v, want the added one first", state.CompletedSessions)
+		}
+		if got := state.PendingEvents()[len(state.PendingEvents())-1].Kind; got != EventAdd {
+			t.Errorf("last event = %q, want %q", got, EventAdd)
+		}
+	})
+
+	t.Run("without a break", func(t *testing.T) {
+		state, _ := newState(t)
+		added, err := state.AddSession("review", at(11, 0), at(11, 30), 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if added.BreakDuration != nil || len(added.Intervals) != 1 {
+			t.Errorf("added = %+v, want a single flow interval", added)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		brk      time.Duration
+		want     error
+	}{
+		{"ends before it starts", at(9, 0), at(8, 0), 0, ErrInvalidTimeRange},
+		{"break fills the session", at(9, 0), at(9, 10), 10 * time.Minute, ErrInvalidTimeRange},
+		{"negative break", at(9, 0), at(9, 10), -time.Minute, ErrNegativeDuration},
+		{"in the future", at(11, 30), at(12, 30), 0, ErrTimeInFuture},
+		{"overlaps a session", at(9, 30), at(10, 30), 0, ErrSessionOverlap},
+		{"contains a session", at(9, 0), at(11, 30), 0, ErrSessionOverlap},
+	}
+	for _, tt := range tests {
+		t.Run("rejects "+tt.name, func(t *testing.T) {
+			state, _ := newState(t)
+			if _, err := state.AddSession("task", tt.from, tt.to, tt.brk); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			if len(state.CompletedSessions) != 1 {
+				t.Errorf("sessions = %d, want 1", len(state.CompletedSessions))
+			}
+		})
+	}
+
+	t.Run("rejects overlap with the current session", func(t *testing.T) {
+		state, clock := newState(t)
+		state.StartSession("now")
+		clock.Advance(30 * time.Minute)
+		if _, err := state.AddSession("task", at(11, 30), at(12, 0), 0); err != nil {
+			t.Fatalf("ending as the current session starts: unexpected error: %v", err)
+		}
+		if _, err := state.AddSession("task", at(12, 10), at(12, 20), 0); !errors.Is(err, ErrSessionOverlap) {
+			t.Errorf("error = %v, want %v", err, ErrSessionOverlap)
+		}
+	})
+}
+
+func TestEditSession(t *testing.T) {
+	// newState returns a state with two one-hour sessions, from 10:00 and 12:00,
+	// the first with a 10 minute break.
+	newState := func(t *testing.T) (*FlowState, *mockClock) {
+		t.Helper()
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		state.StartSession("first")
+		clock.Advance(50 * time.Minute)
+		state.TakeBreak()
+		clock.Advance(10 * time.Minute)
+		state.Stop()
+		clock.Advance(time.Hour)
+		state.StartSession("second")
+		clock.Advance(time.Hour)
+		state.Stop()
+		clock.Advance(time.Hour)
+		return state, clock
+	}
+	ptr := func(d time.Duration) *time.Duration { return &d }
+
+	t.Run("changes the task and keeps the intervals", func(t *testing.T) {
+		state, _ := newState(t)
+		task := "renamed"
+		edited, err := state.EditSession(0, SessionEdit{Task: &task})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if edited.Task != "renamed" || state.CompletedSessions[0].Task != "renamed" {
+			t.Errorf("task = %q, want %q", state.CompletedSessions[0].Task, "renamed")
+		}
+		if len(edited.Intervals) != 2 {
+			t.Errorf("intervals = %+v, want them kept", edited.Intervals)
+		}
+	})
+
+	t.Run("changes durations", func(t *testing.T) {
+		state, _ := newState(t)
+		edited, err := state.EditSession(0, SessionEdit{Flow: ptr(30 * time.Minute), Break: ptr(0)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if edited.FlowDuration != 30*time.Minute || edited.BreakDuration != nil {
+			t.Errorf("flow, break = %v, %v; want 30m, none", edited.FlowDuration, edited.BreakDuration)
+		}
+		if !edited.Start().Equal(edited.CompletedAt.Add(-30 * time.Minute)) {
+			t.Errorf("start = %v, want 30m before completion", edited.Start())
+		}
+	})
+
+	t.Run("moving the completion time shifts the block and reorders", func(t *testing.T) {
+		state, clock := newState(t)
+		first := state.CompletedSessions[0]
+		completedAt := clock.Now()
+		edited, err := state.EditSession(0, SessionEdit{CompletedAt: &completedAt})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if edited.FlowDuration != first.FlowDuration || !edited.Start().Equal(completedAt.Add(-time.Hour)) {
+			t.Errorf("edited = %+v, want the same block ending at %v", edited, completedAt)
+		}
+		if state.CompletedSessions[1].ID != first.ID {
+			t.Errorf("edited session is at index 0, want it last")
+		}
+	})
+
+	t.Run("rejects invalid edits", func(t *testing.T) {
+		state, clock := newState(t)
+		overlapping := state.CompletedSessions[1].CompletedAt.Add(-time.Minute)
+		future := clock.Now().Add(time.Minute)
+
+		tests := []struct {
+			name  string
+			index int
+			edit  SessionEdit
+			want  error
+		}{
+			{"empty", 0, SessionEdit{}, ErrNothingToEdit},
+			{"negative flow", 0, SessionEdit{Flow: ptr(-time.Minute)}, ErrNegativeDuration},
+			{"overlap", 0, SessionEdit{CompletedAt: &overlapping}, ErrSessionOverlap},
+			{"future", 0, SessionEdit{CompletedAt: &future}, ErrTimeInFuture},
+			{"growing into the previous session", 1, SessionEdit{Flow: ptr(3 * time.Hour)}, ErrSessionOverlap},
+		}
+		for _, tt := range tests {
+			if _, err := state.EditSession(tt.index, tt.edit); !errors.Is(err, tt.want) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+			}
+		}
+
+		state.DeleteSession(0)
+		task := "x"
+		if _, err := state.EditSession(0, SessionEdit{Task: &task}); !errors.Is(err, ErrSessionDeleted) {
+			t.Errorf("deleted: error = %v, want %v", err, ErrSessionDeleted)
+		}
+	})
+
+	t.Run("replays", func(t *testing.T) {
+		state, _ := newState(t)
+		task := "renamed"
+		state.EditSession(1, SessionEdit{Task: &task, Flow: ptr(20 * time.Minute)})
+
+		replayed, err := Replay(newTestClock(), state.PendingEvents())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(replayed.CompletedSessions, state.CompletedSessions) {
+			t.Errorf("replayed = %+v, want %+v", replayed.CompletedSessions, state.CompletedSessions)
+		}
+	})
+}
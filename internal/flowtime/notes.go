@@ -0,0 +1,70 @@
+package flowtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoteEmpty   = errors.New("note cannot be empty")
+	ErrNoteTooLong = errors.New("note cannot exceed 500 characters")
+)
+
+// maxNoteLength is the longest note accepted, in bytes.
+const maxNoteLength = 500
+
+// Note is a free-form remark attached to a work block, such as a finding made
+// along the way or the outcome recorded when it was stopped.
+type Note struct {
+	At   time.Time
+	Text string
+}
+
+// AddNote attaches a note to the current session. Surrounding whitespace is
+// trimmed. Returns an error if no session is active or the note is empty or
+// exceeds 500 characters.
+func (s *FlowState) AddNote(text string) error {
+	return s.addNote(text, s.clock.Now())
+}
+
+func (s *FlowState) addNote(text string, at time.Time) error {
+	return s.record(Event{Kind: EventNote, At: at, Text: strings.TrimSpace(text)})
+}
+
+// StopWithNote attaches an outcome note to the current session and stops it, in
+// a single transition, so undoing it restores the session without the note. An
+// empty note is skipped, so it behaves like Stop.
+func (s *FlowState) StopWithNote(text string) (*CompletedSession, error) {
+	return s.stopWithNote(text, s.clock.Now())
+}
+
+// StopWithNoteAt is StopWithNote for a block that ended at an earlier time; the
+// note is recorded at that time too.
+// Returns an error if at is in the future or before the previous transition.
+func (s *FlowState) StopWithNoteAt(text string, at time.Time) (*CompletedSession, error) {
+	if err := s.checkAt(at); err != nil {
+		return nil, err
+	}
+	return s.stopWithNote(text, at)
+}
+
+func (s *FlowState) stopWithNote(text string, at time.Time) (*CompletedSession, error) {
+	return s.stop(at, strings.TrimSpace(text))
+}
+
+func (s *FlowState) applyNote(e Event) error {
+	if s.CurrentSession == nil {
+		return ErrNoActiveSession
+	}
+	if e.Text == "" {
+		return ErrNoteEmpty
+	}
+	if len(e.Text) > maxNoteLength {
+		return fmt.Errorf("%w: got %d characters", ErrNoteTooLong, len(e.Text))
+	}
+
+	s.CurrentSession.Notes = append(s.CurrentSession.Notes, Note{At: e.At, Text: e.Text})
+	return nil
+}
@@ -0,0 +1,130 @@
+package flowtime
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddNote(t *testing.T) {
+	t.Run("attaches notes to the session in order", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		state.StartSession("debug")
+
+		clock.Advance(10 * time.Minute)
+		if err := state.AddNote("  found root cause "); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		first := clock.Now()
+		state.TakeBreak()
+		clock.Advance(5 * time.Minute)
+		if err := state.AddNote("remember to file a ticket"); err != nil {
+			t.Fatalf("unexpected error on break: %v", err)
+		}
+
+		want := []Note{
+			{At: first, Text: "found root cause"},
+			{At: clock.Now(), Text: "remember to file a ticket"},
+		}
+		if !reflect.DeepEqual(state.CurrentSession.Notes, want) {
+			t.Errorf("notes = %v, want %v", state.CurrentSession.Notes, want)
+		}
+
+		completed, err := state.Stop()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(completed.Notes, want) {
+			t.Errorf("completed notes = %v, want %v", completed.Notes, want)
+		}
+	})
+
+	t.Run("rejects invalid notes", func(t *testing.T) {
+		state := NewFlowState(newTestClock())
+		if err := state.AddNote("idle"); !errors.Is(err, ErrNoActiveSession) {
+			t.Errorf("idle: error = %v, want %v", err, ErrNoActiveSession)
+		}
+
+		state.StartSession("task")
+		if err := state.AddNote("   "); !errors.Is(err, ErrNoteEmpty) {
+			t.Errorf("blank: error = %v, want %v", err, ErrNoteEmpty)
+		}
+		if err := state.AddNote(strings.Repeat("x", 501)); !errors.Is(err, ErrNoteTooLong) {
+			t.Errorf("too long: error = %v, want %v", err, ErrNoteTooLong)
+		}
+		if len(state.CurrentSession.Notes) != 0 || len(state.PendingEvents()) != 1 {
+			t.Errorf("rejected notes changed the state: %v", state.CurrentSession.Notes)
+		}
+	})
+}
+
+func TestStopWithNote(t *testing.T) {
+	t.Run("records the outcome", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		state.StartSession("task")
+		clock.Advance(30 * time.Minute)
+
+		completed, err := state.StopWithNote("shipped the fix")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []Note{{At: clock.Now(), Text: "shipped the fix"}}
+		if !reflect.DeepEqual(completed.Notes, want) {
+			t.Errorf("notes = %v, want %v", completed.Notes, want)
+		}
+	})
+
+	t.Run("skips an empty outcome", func(t *testing.T) {
+		state := NewFlowState(newTestClock())
+		state.StartSession("task")
+
+		completed, err := state.StopWithNote(" ")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(completed.Notes) != 0 {
+			t.Errorf("notes = %v, want none", completed.Notes)
+		}
+	})
+
+	t.Run("is a single transition", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		state.StartSession("task")
+		before := state.clone()
+		clock.Advance(30 * time.Minute)
+
+		if _, err := state.StopWithNote("shipped the fix"); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(state.PendingEvents()); n != 2 {
+			t.Errorf("pending events = %d, want the start and the stop", n)
+		}
+		if _, err := state.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		assertSameState(t, state, before)
+	})
+
+	t.Run("rejects a long outcome", func(t *testing.T) {
+		state := NewFlowState(newTestClock())
+		state.StartSession("task")
+		if _, err := state.StopWithNote(strings.Repeat("a", maxNoteLength+1)); !errors.Is(err, ErrNoteTooLong) {
+			t.Errorf("error = %v, want %v", err, ErrNoteTooLong)
+		}
+		if state.CurrentSession == nil {
+			t.Error("session stopped, want it still running")
+		}
+	})
+
+	t.Run("fails without a session", func(t *testing.T) {
+		state := NewFlowState(newTestClock())
+		if _, err := state.StopWithNote("done"); !errors.Is(err, ErrNoActiveSession) {
+			t.Errorf("error = %v, want %v", err, ErrNoActiveSession)
+		}
+	})
+}
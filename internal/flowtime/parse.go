@@ -0,0 +1,141 @@
+package flowtime
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTime = errors.New("invalid time")
+
+// timeLayouts are the absolute forms accepted by ParseTime, tried in order.
+// Layouts without a date refer to the current day.
+var timeLayouts = []struct {
+	layout  string
+	hasDate bool
+}{
+	{time.RFC3339, true},
+	{"2006-01-02T15:04:05", true},
+	{"2006-01-02T15:04", true},
+	{"2006-01-02 15:04:05", true},
+	{"2006-01-02 15:04", true},
+	{"2006-01-02", true},
+	{"15:04:05", false},
+	{"15:04", false},
+}
+
+// ParseTime parses a user-supplied point in time relative to now. Accepted forms:
+//
+//   - "now", "today" (midnight) and "yesterday" (midnight)
+//   - "this week" and "last week" (midnight on Monday), "this month" and
+//     "last month" (midnight on the 1st)
+//   - "today" or "yesterday" followed by a time, e.g. "yesterday 17:00"
+//   - a duration, optionally followed by "ago", e.g. "90m ago" or "2d"
+//   - a date and/or time, e.g. "2025-06-15", "2025-06-15 14:30", "14:30" (today)
+//     or RFC 3339
+//
+// Times without a zone are in now's location.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	loc := now.Location()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	switch strings.ToLower(s) {
+	case "now":
+		return now, nil
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	case "this week":
+		return startOfWeek(midnight), nil
+	case "last week":
+		return startOfWeek(midnight).AddDate(0, 0, -7), nil
+	case "this month":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc), nil
+	case "last month":
+		return time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, loc), nil
+	}
+
+	if day, clock, ok := strings.Cut(s, " "); ok {
+		switch strings.ToLower(day) {
+		case "today":
+			return parseClock(strings.TrimSpace(clock), midnight, s)
+		case "yesterday":
+			return parseClock(strings.TrimSpace(clock), midnight.AddDate(0, 0, -1), s)
+		}
+	}
+
+	if rest, ok := strings.CutSuffix(s, " ago"); ok {
+		d, err := ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w %q: %w", ErrInvalidTime, s, err)
+		}
+		return now.Add(-d), nil
+	}
+	if d, err := ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	for _, l := range timeLayouts {
+		t, err := time.ParseInLocation(l.layout, s, loc)
+		if err != nil {
+			continue
+		}
+		if !l.hasDate {
+			t = onDay(t, midnight)
+		}
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w %q: use a date and/or time like \"2006-01-02 15:04\", \"15:04\" or \"2h ago\"", ErrInvalidTime, s)
+}
+
+// startOfWeek returns midnight on the Monday of the week containing the day
+// starting at midnight.
+func startOfWeek(midnight time.Time) time.Time {
+	return midnight.AddDate(0, 0, -(int(midnight.Weekday())+6)%7)
+}
+
+// parseClock parses a time of day, such as "17:00", on the day starting at
+// midnight. input is the full value being parsed, for errors.
+func parseClock(s string, midnight time.Time, input string) (time.Time, error) {
+	for _, l := range timeLayouts {
+		if l.hasDate {
+			continue
+		}
+		if t, err := time.ParseInLocation(l.layout, s, midnight.Location()); err == nil {
+			return onDay(t, midnight), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w %q: use a time of day like \"yesterday 17:00\"", ErrInvalidTime, input)
+}
+
+// onDay returns the time of day of t on the day starting at midnight.
+func onDay(t, midnight time.Time) time.Time {
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), t.Hour(), t.Minute(), t.Second(), 0, midnight.Location())
+}
+
+// ParseDuration is time.ParseDuration extended with a "d" unit for whole days,
+// e.g. "7d". Negative durations are rejected.
+func ParseDuration(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration %q is negative", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,84 @@
+package flowtime
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2025, 6, 15, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{"now", now},
+		{"today", time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"yesterday", time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC)},
+		{"yesterday 17:00", time.Date(2025, 6, 14, 17, 0, 0, 0, time.UTC)},
+		{"Today 09:15:30", time.Date(2025, 6, 15, 9, 15, 30, 0, time.UTC)},
+		{"90m ago", now.Add(-90 * time.Minute)},
+		{"2d ago", now.Add(-48 * time.Hour)},
+		{"3d", now.Add(-72 * time.Hour)},
+		{"this week", time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC)},
+		{"Last Week", time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)},
+		{"this month", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"last month", time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"09:15", time.Date(2025, 6, 15, 9, 15, 0, 0, time.UTC)},
+		{"09:15:30", time.Date(2025, 6, 15, 9, 15, 30, 0, time.UTC)},
+		{"2025-06-01", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-06-01 08:00", time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)},
+		{"2025-06-01T08:00", time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)},
+		{"2025-06-01T08:00:00+02:00", time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseTime(tt.input, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("ParseTime(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "soon", "-5m ago", "-5m", "25:00", "2025-13-01", "yesterday 2025-06-01", "today soon"} {
+		t.Run("rejects "+input, func(t *testing.T) {
+			if _, err := ParseTime(input, now); !errors.Is(err, ErrInvalidTime) {
+				t.Errorf("ParseTime(%q) error = %v, want %v", input, err, ErrInvalidTime)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"45m", 45 * time.Minute},
+		{"1h30m", 90 * time.Minute},
+		{"7d", 7 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDuration(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "d", "1.5d", "-1h"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q): expected error", input)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package flowtime
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ReportGrouping selects how Report groups sessions.
+type ReportGrouping string
+
+const (
+	ByDay   ReportGrouping = "day"
+	ByWeek  ReportGrouping = "week" // ISO 8601 weeks, starting on Monday
+	ByMonth ReportGrouping = "month"
+	ByTask  ReportGrouping = "task"
+)
+
+// ReportRow totals the sessions in one group of a report.
+type ReportRow struct {
+	Group    string    // e.g. "2025-06-15", "2025-W24", "2025-06" or the task
+	Start    time.Time // start of the day, week or month; zero when grouping by task
+	Sessions int
+	Flow     time.Duration
+	Break    time.Duration
+}
+
+// AverageFlow returns the mean flow time of the row's sessions.
+func (r ReportRow) AverageFlow() time.Duration {
+	if r.Sessions == 0 {
+		return 0
+	}
+	return r.Flow / time.Duration(r.Sessions)
+}
+
+// add counts cs in the row.
+func (r *ReportRow) add(cs CompletedSession) {
+	r.Sessions++
+	r.Flow += cs.FlowDuration
+	if cs.BreakDuration != nil {
+		r.Break += *cs.BreakDuration
+	}
+}
+
+// Report groups sessions by the day, week or month they started in, in loc, or
+// by task, and totals each group. Periods are in chronological order and tasks
+// by flow time, most first. Periods without sessions are left out.
+func Report(sessions []CompletedSession, by ReportGrouping, loc *time.Location) ([]ReportRow, error) {
+	var rows []ReportRow
+	index := map[string]int{}
+	for _, cs := range sessions {
+		start := cs.Start().In(loc)
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+
+		var row ReportRow
+		switch by {
+		case ByDay:
+			row.Start = day
+			row.Group = day.Format(time.DateOnly)
+		case ByWeek:
+			year, week := day.ISOWeek()
+			row.Start = startOfWeek(day)
+			row.Group = fmt.Sprintf("%d-W%02d", year, week)
+		case ByMonth:
+			row.Start = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, loc)
+			row.Group = row.Start.Format("2006-01")
+		case ByTask:
+			row.Group = cs.Task
+		default:
+			return nil, fmt.Errorf("unknown report grouping %q: use day, week, month or task", by)
+		}
+
+		i, ok := index[row.Group]
+		if !ok {
+			i = len(rows)
+			index[row.Group] = i
+			rows = append(rows, row)
+		}
+		rows[i].add(cs)
+	}
+
+	if by == ByTask {
+		slices.SortStableFunc(rows, func(a, b ReportRow) int { return cmp.Compare(b.Flow, a.Flow) })
+	} else {
+		slices.SortStableFunc(rows, func(a, b ReportRow) int { return a.Start.Compare(b.Start) })
+	}
+	return rows, nil
+}
+
+// ReportTotal sums the rows of a report.
+func ReportTotal(rows []ReportRow) ReportRow {
+	total := ReportRow{Group: "Total"}
+	for _, r := range rows {
+		total.Sessions += r.Sessions
+		total.Flow += r.Flow
+		total.Break += r.Break
+	}
+	return total
+}
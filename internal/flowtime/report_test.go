@@ -0,0 +1,101 @@
+package flowtime
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	// session returns a session that started at start and flowed for flow,
+	// followed by a break of brk if it is not zero.
+	session := func(task string, start time.Time, flow, brk time.Duration) CompletedSession {
+		var breakPtr *time.Duration
+		if brk > 0 {
+			breakPtr = &brk
+		}
+		end := start.Add(flow + brk)
+		return newCompletedSession("", task, LegacyIntervals(flow, breakPtr, end), end)
+	}
+	day := func(month time.Month, d, hour int) time.Time {
+		return time.Date(2025, month, d, hour, 0, 0, 0, time.UTC)
+	}
+
+	sessions := []CompletedSession{
+		session("write", day(6, 29, 9), time.Hour, 10*time.Minute), // Sunday, ISO week 26
+		session("review", day(6, 30, 9), 30*time.Minute, 0),        // Monday, week 27
+		session("write", day(6, 30, 23), 90*time.Minute, 0),        // ends the next day
+		session("write", day(7, 2, 14), 20*time.Minute, 5*time.Minute),
+	}
+
+	type row struct {
+		Group    string
+		Sessions int
+		Flow     time.Duration
+		Break    time.Duration
+	}
+	tests := []struct {
+		by   ReportGrouping
+		want []row
+	}{
+		{ByDay, []row{
+			{"2025-06-29", 1, time.Hour, 10 * time.Minute},
+			{"2025-06-30", 2, 2 * time.Hour, 0},
+			{"2025-07-02", 1, 20 * time.Minute, 5 * time.Minute},
+		}},
+		{ByWeek, []row{
+			{"2025-W26", 1, time.Hour, 10 * time.Minute},
+			{"2025-W27", 3, 140 * time.Minute, 5 * time.Minute},
+		}},
+		{ByMonth, []row{
+			{"2025-06", 3, 3 * time.Hour, 10 * time.Minute},
+			{"2025-07", 1, 20 * time.Minute, 5 * time.Minute},
+		}},
+		{ByTask, []row{
+			{"write", 3, 170 * time.Minute, 15 * time.Minute},
+			{"review", 1, 30 * time.Minute, 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.by), func(t *testing.T) {
+			rows, err := Report(sessions, tt.by, time.UTC)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []row
+			for _, r := range rows {
+				got = append(got, row{r.Group, r.Sessions, r.Flow, r.Break})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("weeks start on Monday", func(t *testing.T) {
+		rows, _ := Report(sessions[1:], ByWeek, time.UTC)
+		if want := day(6, 30, 0); !rows[0].Start.Equal(want) {
+			t.Errorf("start = %v, want %v", rows[0].Start, want)
+		}
+	})
+
+	t.Run("totals and averages", func(t *testing.T) {
+		rows, _ := Report(sessions, ByTask, time.UTC)
+		total := ReportTotal(rows)
+		if total.Sessions != 4 || total.Flow != 200*time.Minute || total.Break != 15*time.Minute {
+			t.Errorf("total = %+v", total)
+		}
+		if got := total.AverageFlow(); got != 50*time.Minute {
+			t.Errorf("average flow = %v, want 50m", got)
+		}
+		if got := (ReportRow{}).AverageFlow(); got != 0 {
+			t.Errorf("average of an empty row = %v, want 0", got)
+		}
+	})
+
+	t.Run("rejects an unknown grouping", func(t *testing.T) {
+		if _, err := Report(sessions, "year", time.UTC); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
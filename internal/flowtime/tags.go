@@ -0,0 +1,59 @@
+package flowtime
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrMultipleProjects = errors.New("a session can only belong to one project")
+
+// Prefixes marking the project and tags in a task description.
+const (
+	projectPrefix = "+"
+	tagPrefix     = "@"
+)
+
+// ParseTask splits a task description such as "Fix login bug +auth @review" into
+// the task text, its project (the word starting with "+") and its tags (the
+// words starting with "@", deduplicated in order). A lone "+" or "@" is kept as
+// part of the task. Returns ErrMultipleProjects if more than one project is given.
+func ParseTask(input string) (task, project string, tags []string, err error) {
+	var words []string
+	for _, word := range strings.Fields(input) {
+		switch {
+		case len(word) > len(projectPrefix) && strings.HasPrefix(word, projectPrefix):
+			name := strings.TrimPrefix(word, projectPrefix)
+			if project != "" && project != name {
+				return "", "", nil, fmt.Errorf("%w: got %q and %q", ErrMultipleProjects, project, name)
+			}
+			project = name
+		case len(word) > len(tagPrefix) && strings.HasPrefix(word, tagPrefix):
+			if name := strings.TrimPrefix(word, tagPrefix); !slices.Contains(tags, name) {
+				tags = append(tags, name)
+			}
+		default:
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " "), project, tags, nil
+}
+
+// FormatLabels renders a project and tags the way they are written in a task
+// description, e.g. "+auth @review @urgent". Returns "" if there are none.
+func FormatLabels(project string, tags []string) string {
+	var labels []string
+	if project != "" {
+		labels = append(labels, projectPrefix+project)
+	}
+	for _, tag := range tags {
+		labels = append(labels, tagPrefix+tag)
+	}
+	return strings.Join(labels, " ")
+}
+
+// HasTag reports whether the session is tagged with tag, ignoring case.
+func (cs CompletedSession) HasTag(tag string) bool {
+	return slices.ContainsFunc(cs.Tags, func(t string) bool { return strings.EqualFold(t, tag) })
+}
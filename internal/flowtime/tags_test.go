@@ -0,0 +1,124 @@
+package flowtime
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTask(t *testing.T) {
+	tests := []struct {
+		input   string
+		task    string
+		project string
+		tags    []string
+	}{
+		{"Fix login bug", "Fix login bug", "", nil},
+		{"Fix login bug +auth @review", "Fix login bug", "auth", []string{"review"}},
+		{"@urgent Fix +auth login @review bug @urgent", "Fix login bug", "auth", []string{"urgent", "review"}},
+		{"Add 1 + 2 @ once", "Add 1 + 2 @ once", "", nil},
+		{"  spaced   out  +p  ", "spaced out", "p", nil},
+		{"+auth +auth dedupe", "dedupe", "auth", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			task, project, tags, err := ParseTask(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if task != tt.task || project != tt.project || !reflect.DeepEqual(tags, tt.tags) {
+				t.Errorf("ParseTask(%q) = %q, %q, %v; want %q, %q, %v",
+					tt.input, task, project, tags, tt.task, tt.project, tt.tags)
+			}
+		})
+	}
+
+	t.Run("rejects two projects", func(t *testing.T) {
+		if _, _, _, err := ParseTask("task +a +b"); !errors.Is(err, ErrMultipleProjects) {
+			t.Errorf("error = %v, want %v", err, ErrMultipleProjects)
+		}
+	})
+}
+
+func TestFormatLabels(t *testing.T) {
+	if got := FormatLabels("auth", []string{"review", "urgent"}); got != "+auth @review @urgent" {
+		t.Errorf("FormatLabels = %q", got)
+	}
+	if got := FormatLabels("", nil); got != "" {
+		t.Errorf("FormatLabels with no labels = %q, want empty", got)
+	}
+}
+
+func TestStartSessionLabels(t *testing.T) {
+	clock := newTestClock()
+	state := NewFlowState(clock)
+
+	if err := state.StartSession("+auth @review"); !errors.Is(err, ErrTaskEmpty) {
+		t.Errorf("labels only: error = %v, want %v", err, ErrTaskEmpty)
+	}
+
+	if err := state.StartSession("Fix login bug +auth @review"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock.Advance(10 * time.Minute)
+	completed, err := state.Stop()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if completed.Task != "Fix login bug" || completed.Project != "auth" || !reflect.DeepEqual(completed.Tags, []string{"review"}) {
+		t.Errorf("completed = %+v, want task, project and tags split", completed)
+	}
+
+	// Resuming from idle keeps the labels of the last session.
+	if _, err := state.Resume(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.CurrentSession.Project != "auth" || !reflect.DeepEqual(state.CurrentSession.Tags, []string{"review"}) {
+		t.Errorf("resumed session = %+v, want labels carried over", state.CurrentSession)
+	}
+}
+
+func TestSessionFilter(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2025, 6, 15, hour, 0, 0, 0, time.UTC) }
+	sessions := []CompletedSession{
+		{ID: "a", Task: "Review login", Project: "auth", Tags: []string{"review", "urgent"}, FlowDuration: 25 * time.Minute, CompletedAt: at(9)},
+		{ID: "b", Task: "fix login bug", Project: "Auth", Tags: []string{"review"}, FlowDuration: time.Hour, CompletedAt: at(10)},
+		{ID: "c", Task: "invoices", Project: "billing", Tags: []string{"Urgent"}, FlowDuration: 90 * time.Minute, CompletedAt: at(11)},
+		{ID: "d", Task: "email", FlowDuration: 10 * time.Minute, CompletedAt: at(12)},
+	}
+	ids := func(sessions []CompletedSession) []string {
+		var ids []string
+		for _, cs := range sessions {
+			ids = append(ids, cs.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name   string
+		filter SessionFilter
+		want   []string
+	}{
+		{"empty matches all", SessionFilter{}, []string{"a", "b", "c", "d"}},
+		{"project ignores case", SessionFilter{Project: "AUTH"}, []string{"a", "b"}},
+		{"tag", SessionFilter{Tags: []string{"urgent"}}, []string{"a", "c"}},
+		{"all tags required", SessionFilter{Tags: []string{"review", "urgent"}}, []string{"a"}},
+		{"project and tag", SessionFilter{Project: "billing", Tags: []string{"review"}}, nil},
+		{"since is inclusive", SessionFilter{Since: at(10)}, []string{"b", "c", "d"}},
+		{"until is exclusive", SessionFilter{Until: at(11)}, []string{"a", "b"}},
+		{"range and project", SessionFilter{Project: "auth", Since: at(10), Until: at(12)}, []string{"b"}},
+		{"task substring ignores case", SessionFilter{Task: mustTaskPattern(t, "LOGIN")}, []string{"a", "b"}},
+		{"task regex", SessionFilter{Task: mustTaskPattern(t, "/^(fix|email)/")}, []string{"b", "d"}},
+		{"min flow is inclusive", SessionFilter{MinFlow: time.Hour}, []string{"b", "c"}},
+		{"max flow is inclusive", SessionFilter{MaxFlow: 25 * time.Minute}, []string{"a", "d"}},
+		{"flow range", SessionFilter{MinFlow: 20 * time.Minute, MaxFlow: time.Hour}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(tt.filter.Apply(sessions)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package flowtime
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+	ErrUndoConflict  = errors.New("cannot undo or redo a state changed elsewhere")
+)
+
+// undoLimit is how many transitions in a row can be undone.
+const undoLimit = 20
+
+// Change holds the parts of a FlowState that a transition changed, as they were
+// before or after it, so the transition can be undone or redone without the rest
+// of the state. Sessions replace the completed sessions with the same IDs, or
+// are added if there are none, and the sessions in Removed are taken out.
+type Change struct {
+	CurrentSession *Session
+	CurrentBreak   *Break
+	Sessions       []CompletedSession
+	Removed        []string
+}
+
+// UndoStep is a transition on the undo or redo stack: the event itself and the
+// change that reverts it, or for a redo, reapplies it.
+type UndoStep struct {
+	Event  Event
+	Change Change
+}
+
+// UndoSteps returns the transitions that can be undone and redone, in the order
+// they were pushed. This is used by the storage layer to persist them with the state.
+func (s *FlowState) UndoSteps() (undo, redo []UndoStep) {
+	return s.undo, s.redo
+}
+
+// SetUndoSteps restores the stacks returned by UndoSteps. This is used by the
+// storage layer after deserialization.
+func (s *FlowState) SetUndoSteps(undo, redo []UndoStep) {
+	s.undo, s.redo = undo, redo
+}
+
+// Undo reverts the latest transition that has not been undone, up to 20 in a
+// row, and returns it. The change that reverts it is recorded as an EventUndo,
+// so undoing survives across processes and can itself be redone. Replacing the
+// state or purging sessions cannot be undone, nor can anything before them.
+// Returns an error if there is nothing to undo.
+func (s *FlowState) Undo() (Event, error) {
+	if len(s.undo) == 0 {
+		return Event{}, ErrNothingToUndo
+	}
+	return s.recordStep(EventUndo, s.undo[len(s.undo)-1])
+}
+
+// Redo reapplies the latest transition reverted by Undo and returns it, as long
+// as nothing else has changed since.
+// Returns an error if there is nothing to redo.
+func (s *FlowState) Redo() (Event, error) {
+	if len(s.redo) == 0 {
+		return Event{}, ErrNothingToRedo
+	}
+	return s.recordStep(EventRedo, s.redo[len(s.redo)-1])
+}
+
+// recordStep records an EventUndo or EventRedo that applies the step's change.
+func (s *FlowState) recordStep(kind EventKind, step UndoStep) (Event, error) {
+	change := step.Change
+	if err := s.record(Event{Kind: kind, At: s.clock.Now(), Undone: step.Event.Kind, Change: &change}); err != nil {
+		return Event{}, fmt.Errorf("%s %s: %w", kind, step.Event.Kind, err)
+	}
+	return step.Event, nil
+}
+
+// applyUndo applies an EventUndo or EventRedo and moves the step between the stacks.
+func (s *FlowState) applyUndo(e Event) error {
+	if e.Change == nil {
+		return fmt.Errorf("%s event has no change", e.Kind)
+	}
+
+	from, to := &s.undo, &s.redo
+	if e.Kind == EventRedo {
+		from, to = &s.redo, &s.undo
+	}
+	// The stack can be short of the step when the state was stored before the
+	// stacks were; the event's change is all that is needed to apply it.
+	step := UndoStep{Event: Event{Kind: e.Undone}}
+	if n := len(*from); n > 0 {
+		step.Event = (*from)[n-1].Event
+		*from = (*from)[:n-1]
+	}
+	step.Change = s.applyChange(*e.Change)
+	*to = pushStep(*to, step)
+	return nil
+}
+
+// pushStep adds step to the top of stack, dropping the oldest steps beyond undoLimit.
+func pushStep(stack []UndoStep, step UndoStep) []UndoStep {
+	stack = append(stack, step)
+	if len(stack) > undoLimit {
+		stack = stack[len(stack)-undoLimit:]
+	}
+	return stack
+}
+
+// inverse returns the change that undoes e. It must be called before e is
+// applied, and only captures what e can change.
+func (s *FlowState) inverse(e Event) Change {
+	c := Change{CurrentSession: cloneSession(s.CurrentSession), CurrentBreak: cloneBreak(s.CurrentBreak)}
+	switch e.Kind {
+	case EventStop, EventSwitch:
+		if s.CurrentSession != nil {
+			c.Removed = []string{s.completedSessionID(e, s.CurrentSession.Task)}
+		}
+	case EventAdd:
+		if e.Session != nil {
+			c.Removed = []string{e.Session.ID}
+		}
+	case EventDelete, EventRestore, EventEdit:
+		if i := s.targetIndex(e); i != -1 {
+			c.Sessions = []CompletedSession{cloneCompletedSession(s.CompletedSessions[i])}
+		}
+	case EventDeleteAll, EventRestoreSince:
+		for _, cs := range s.CompletedSessions {
+			deleteAll := e.Kind == EventDeleteAll && cs.DeletedAt == nil
+			restore := e.Kind == EventRestoreSince && cs.DeletedAt != nil && !cs.DeletedAt.Before(e.Since)
+			if deleteAll || restore {
+				c.Sessions = append(c.Sessions, cloneCompletedSession(cs))
+			}
+		}
+	}
+	return c
+}
+
+// applyChange puts the parts of the state in c back and returns the change that
+// reverses it. Sessions that keep their completion time are replaced in place;
+// others are inserted in order.
+func (s *FlowState) applyChange(c Change) Change {
+	reverse := Change{CurrentSession: s.CurrentSession, CurrentBreak: s.CurrentBreak}
+
+	removed := make(map[string]bool, len(c.Removed))
+	for _, id := range c.Removed {
+		removed[id] = true
+	}
+	replacements := make(map[string]CompletedSession, len(c.Sessions))
+	for _, cs := range c.Sessions {
+		replacements[cs.ID] = cs
+	}
+
+	var inserted []CompletedSession
+	kept := make([]CompletedSession, 0, len(s.CompletedSessions)+len(c.Sessions))
+	for _, cs := range s.CompletedSessions {
+		if removed[cs.ID] {
+			reverse.Sessions = append(reverse.Sessions, cs)
+			continue
+		}
+		if replacement, ok := replacements[cs.ID]; ok {
+			delete(replacements, cs.ID)
+			reverse.Sessions = append(reverse.Sessions, cs)
+			if !replacement.CompletedAt.Equal(cs.CompletedAt) {
+				inserted = append(inserted, replacement)
+				continue
+			}
+			cs = cloneCompletedSession(replacement)
+		}
+		kept = append(kept, cs)
+	}
+	for _, cs := range c.Sessions {
+		if _, ok := replacements[cs.ID]; ok {
+			reverse.Removed = append(reverse.Removed, cs.ID)
+			inserted = append(inserted, cs)
+		}
+	}
+
+	s.CompletedSessions = kept
+	for _, cs := range inserted {
+		s.insertSession(cloneCompletedSession(cs))
+	}
+	s.CurrentSession = cloneSession(c.CurrentSession)
+	s.CurrentBreak = cloneBreak(c.CurrentBreak)
+	return reverse
+}
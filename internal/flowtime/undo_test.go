@@ -0,0 +1,159 @@
+package flowtime
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUndo(t *testing.T) {
+	t.Run("undoes and redoes transitions in order", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		_ = state.StartSession("write code")
+		clock.Advance(30 * time.Minute)
+		_ = state.TakeBreak()
+		afterBreak := state.clone()
+		clock.Advance(5 * time.Minute)
+		_, _ = state.Stop()
+		afterStop := state.clone()
+
+		undone, err := state.Undo()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if undone.Kind != EventStop {
+			t.Errorf("undone = %q, want %q", undone.Kind, EventStop)
+		}
+		assertSameState(t, state, afterBreak)
+
+		if undone, _ := state.Undo(); undone.Kind != EventBreak {
+			t.Errorf("second undo = %q, want %q", undone.Kind, EventBreak)
+		}
+		if state.CurrentBreak != nil || state.CurrentSession == nil {
+			t.Errorf("after undoing the break: session = %+v, break = %+v", state.CurrentSession, state.CurrentBreak)
+		}
+
+		_, _ = state.Redo()
+		if redone, err := state.Redo(); err != nil || redone.Kind != EventStop {
+			t.Fatalf("redo = %q, %v; want %q", redone.Kind, err, EventStop)
+		}
+		assertSameState(t, state, afterStop)
+		if _, err := state.Redo(); !errors.Is(err, ErrNothingToRedo) {
+			t.Errorf("error = %v, want %v", err, ErrNothingToRedo)
+		}
+	})
+
+	t.Run("survives a replay", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		_ = state.StartSession("write code")
+		clock.Advance(time.Hour)
+		_, _ = state.Stop()
+		_, _ = state.Undo()
+
+		replayed, err := Replay(clock, state.PendingEvents())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertSameState(t, replayed, state)
+		if redone, err := replayed.Redo(); err != nil || redone.Kind != EventStop {
+			t.Fatalf("redo = %q, %v; want %q", redone.Kind, err, EventStop)
+		}
+		if replayed.CurrentSession != nil || len(replayed.CompletedSessions) != 1 {
+			t.Errorf("session = %+v, completed = %d; want the session stopped", replayed.CurrentSession, len(replayed.CompletedSessions))
+		}
+	})
+
+	t.Run("records only what changed", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		for range 3 {
+			_ = state.StartSession("task")
+			clock.Advance(time.Minute)
+			_, _ = state.Stop()
+		}
+		_ = state.DeleteSession(1)
+		before := state.clone()
+		_, _ = state.Undo()
+
+		events := state.PendingEvents()
+		undo := events[len(events)-1]
+		if undo.Snapshot != nil || undo.Change == nil {
+			t.Fatalf("undo event = %+v, want a change and no snapshot", undo)
+		}
+		if n := len(undo.Change.Sessions); n != 1 || undo.Change.Sessions[0].ID != before.CompletedSessions[1].ID {
+			t.Errorf("change sessions = %+v, want only the deleted session", undo.Change.Sessions)
+		}
+		if state.CompletedSessions[1].DeletedAt != nil {
+			t.Error("session still deleted after undo")
+		}
+
+		_, _ = state.Redo()
+		assertSameState(t, state, before)
+	})
+
+	t.Run("puts an edited session back in order", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		for _, task := range []string{"first", "second"} {
+			_ = state.StartSession(task)
+			clock.Advance(time.Hour)
+			_, _ = state.Stop()
+		}
+		before := state.clone()
+		completedAt := state.CompletedSessions[1].CompletedAt.Add(-3 * time.Hour)
+		if _, err := state.EditSession(1, SessionEdit{CompletedAt: &completedAt}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := state.Undo(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertSameState(t, state, before)
+	})
+
+	t.Run("a new transition clears redo", func(t *testing.T) {
+		state := NewFlowState(newTestClock())
+		_ = state.StartSession("first")
+		_, _ = state.Undo()
+		_ = state.StartSession("second")
+
+		if _, err := state.Redo(); !errors.Is(err, ErrNothingToRedo) {
+			t.Errorf("error = %v, want %v", err, ErrNothingToRedo)
+		}
+	})
+
+	t.Run("stops at a replace", func(t *testing.T) {
+		state := NewFlowState(newTestClock())
+		_ = state.StartSession("first")
+		_ = state.Replace(NewFlowState(newTestClock()))
+		if _, err := state.Undo(); !errors.Is(err, ErrNothingToUndo) {
+			t.Errorf("error = %v, want %v", err, ErrNothingToUndo)
+		}
+	})
+
+	t.Run("is bounded", func(t *testing.T) {
+		clock := newTestClock()
+		state := NewFlowState(clock)
+		for range undoLimit + 5 {
+			_ = state.StartSession("task")
+			clock.Advance(time.Minute)
+			_, _ = state.Stop()
+		}
+
+		undone := 0
+		for {
+			if _, err := state.Undo(); err != nil {
+				if !errors.Is(err, ErrNothingToUndo) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				break
+			}
+			undone++
+		}
+		if undone != undoLimit {
+			t.Errorf("undone = %d, want %d", undone, undoLimit)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+// Options controls what WriteSessions includes.
+type Options struct {
+	// Breaks adds each session's break time as an event of its own.
+	Breaks bool
+	// Stamp is the DTSTAMP of every event, usually the time of the export.
+	Stamp time.Time
+}
+
+// WriteSessions writes sessions to w as an iCalendar (RFC 5545) calendar with
+// an event for each flow interval of every session, and with Breaks, for each
+// break interval. UIDs are derived from the session IDs and the intervals'
+// positions, so importing a later export again updates the events instead of
+// duplicating them.
+func WriteSessions(w io.Writer, sessions []flowtime.CompletedSession, opts Options) error {
+	bw := bufio.NewWriter(w)
+	cw := &contentWriter{w: bw}
+
+	cw.line("BEGIN", "VCALENDAR")
+	cw.line("VERSION", "2.0")
+	cw.line("PRODID", "-//flower//flower//EN")
+	cw.line("CALSCALE", "GREGORIAN")
+	cw.line("X-WR-CALNAME", "flower")
+	for _, cs := range sessions {
+		flows := intervalsOf(cs, flowtime.IntervalFlow)
+		breaks := intervalsOf(cs, flowtime.IntervalBreak)
+		for i, iv := range flows {
+			description := describe(cs)
+			if len(flows) > 1 {
+				description = fmt.Sprintf("Part %d of %d\n%s", i+1, len(flows), description)
+			}
+			cw.event(event{
+				uid:         uid(cs.ID, "", i),
+				stamp:       opts.Stamp,
+				start:       iv.Start,
+				end:         iv.End,
+				summary:     cs.Task,
+				description: description,
+				categories:  categories(cs),
+			})
+		}
+		if !opts.Breaks {
+			continue
+		}
+		for i, iv := range breaks {
+			if !iv.End.After(iv.Start) {
+				continue
+			}
+			cw.event(event{
+				uid:         uid(cs.ID, "-break", i),
+				stamp:       opts.Stamp,
+				start:       iv.Start,
+				end:         iv.End,
+				summary:     "Break: " + cs.Task,
+				description: "Break after " + flowtime.FormatDuration(flowBefore(flows, iv.Start)) + " of flow",
+				categories:  categories(cs),
+				transparent: true,
+			})
+		}
+	}
+	cw.line("END", "VCALENDAR")
+
+	if cw.err != nil {
+		return fmt.Errorf("writing calendar: %w", cw.err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("writing calendar: %w", err)
+	}
+	return nil
+}
+
+// intervalsOf returns the session's intervals of the given kind, in order.
+func intervalsOf(cs flowtime.CompletedSession, kind flowtime.IntervalKind) []flowtime.Interval {
+	var intervals []flowtime.Interval
+	for _, iv := range cs.Intervals {
+		if iv.Kind == kind {
+			intervals = append(intervals, iv)
+		}
+	}
+	return intervals
+}
+
+// flowBefore returns the length of the flow interval that ended at t, if any.
+func flowBefore(flows []flowtime.Interval, t time.Time) time.Duration {
+	for _, iv := range flows {
+		if iv.End.Equal(t) {
+			return iv.End.Sub(iv.Start)
+		}
+	}
+	return 0
+}
+
+// uid returns the UID of the i-th event with the given suffix for session id.
+// The first has no number, so a session with a single flow interval is simply
+// <id>@flower.
+func uid(id, suffix string, i int) string {
+	if i == 0 {
+		return id + suffix + "@flower"
+	}
+	return fmt.Sprintf("%s%s-%d@flower", id, suffix, i+1)
+}
+
+// event is a VEVENT. A transparent event does not show the time as busy.
+type event struct {
+	uid         string
+	stamp       time.Time
+	start, end  time.Time
+	summary     string
+	description string
+	categories  []string
+	transparent bool
+}
+
+// describe summarises a session for its event's description.
+func describe(cs flowtime.CompletedSession) string {
+	lines := []string{"Flow: " + flowtime.FormatDuration(cs.FlowDuration)}
+	if cs.BreakDuration != nil {
+		lines = append(lines, "Break: "+flowtime.FormatDuration(*cs.BreakDuration))
+	}
+	if labels := flowtime.FormatLabels(cs.Project, cs.Tags); labels != "" {
+		lines = append(lines, "Labels: "+labels)
+	}
+	if len(cs.Interruptions) > 0 {
+		lines = append(lines, "Interruptions: "+flowtime.FormatInterruptions(cs.Interruptions))
+	}
+	for _, n := range cs.Notes {
+		lines = append(lines, "Note: "+n.Text)
+	}
+	lines = append(lines, "Session: "+cs.ID)
+	return strings.Join(lines, "\n")
+}
+
+// categories returns the session's project and tags.
+func categories(cs flowtime.CompletedSession) []string {
+	var c []string
+	if cs.Project != "" {
+		c = append(c, cs.Project)
+	}
+	return append(c, cs.Tags...)
+}
+
+// contentWriter writes iCalendar content lines, keeping the first error.
+type contentWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (cw *contentWriter) event(e event) {
+	cw.line("BEGIN", "VEVENT")
+	cw.line("UID", e.uid)
+	cw.line("DTSTAMP", utc(e.stamp))
+	cw.line("DTSTART", utc(e.start))
+	cw.line("DTEND", utc(e.end))
+	cw.line("SUMMARY", escape(e.summary))
+	cw.line("DESCRIPTION", escape(e.description))
+	if len(e.categories) > 0 {
+		escaped := make([]string, len(e.categories))
+		for i, c := range e.categories {
+			escaped[i] = escape(c)
+		}
+		cw.line("CATEGORIES", strings.Join(escaped, ","))
+	}
+	if e.transparent {
+		cw.line("TRANSP", "TRANSPARENT")
+	}
+	cw.line("END", "VEVENT")
+}
+
+// line writes a content line, folded so no line exceeds 75 octets.
+func (cw *contentWriter) line(name, value string) {
+	if cw.err != nil {
+		return
+	}
+	_, cw.err = io.WriteString(cw.w, fold(name+":"+value))
+}
+
+// fold splits a content line into lines of at most 75 octets, without breaking
+// UTF-8 sequences, continuing each with a space, and ends it with CRLF.
+func fold(line string) string {
+	const limit = 75
+	var b strings.Builder
+	width := limit
+	for len(line) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		width = limit - 1 // the leading space counts
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// utc formats t as an iCalendar UTC date-time.
+func utc(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
@@ -0,0 +1,176 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+func TestWriteSessions(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2025, 6, 15, hour, min, 0, 0, time.UTC) }
+	brk := 10 * time.Minute
+	sessions := []flowtime.CompletedSession{
+		{
+			ID:            "3f9c2a1b",
+			Task:          "Fix login, again",
+			Project:       "auth",
+			Tags:          []string{"review"},
+			Notes:         []flowtime.Note{{Text: "found the root cause"}},
+			FlowDuration:  50 * time.Minute,
+			BreakDuration: &brk,
+			CompletedAt:   at(10, 0),
+			Intervals: []flowtime.Interval{
+				{Kind: flowtime.IntervalFlow, Start: at(9, 0), End: at(9, 50)},
+				{Kind: flowtime.IntervalBreak, Start: at(9, 50), End: at(10, 0)},
+			},
+		},
+		{
+			ID:           "77aa01cd",
+			Task:         "email",
+			FlowDuration: 15 * time.Minute,
+			CompletedAt:  at(11, 0),
+			Intervals:    []flowtime.Interval{{Kind: flowtime.IntervalFlow, Start: at(10, 45), End: at(11, 0)}},
+		},
+	}
+
+	t.Run("writes one event per session", func(t *testing.T) {
+		var b strings.Builder
+		if err := WriteSessions(&b, sessions, Options{Stamp: at(12, 0)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"PRODID:-//flower//flower//EN",
+			"CALSCALE:GREGORIAN",
+			"X-WR-CALNAME:flower",
+			"BEGIN:VEVENT",
+			"UID:3f9c2a1b@flower",
+			"DTSTAMP:20250615T120000Z",
+			"DTSTART:20250615T090000Z",
+			"DTEND:20250615T095000Z",
+			`SUMMARY:Fix login\, again`,
+			`DESCRIPTION:Flow: 50m\nBreak: 10m\nLabels: +auth @review\nNote: found the r`,
+			` oot cause\nSession: 3f9c2a1b`,
+			"CATEGORIES:auth,review",
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"UID:77aa01cd@flower",
+			"DTSTAMP:20250615T120000Z",
+			"DTSTART:20250615T104500Z",
+			"DTEND:20250615T110000Z",
+			"SUMMARY:email",
+			`DESCRIPTION:Flow: 15m\nSession: 77aa01cd`,
+			"END:VEVENT",
+			"END:VCALENDAR",
+			"",
+		}, "\r\n")
+		if got := b.String(); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("adds breaks as free time", func(t *testing.T) {
+		var b strings.Builder
+		if err := WriteSessions(&b, sessions, Options{Breaks: true, Stamp: at(12, 0)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := strings.Join([]string{
+			"BEGIN:VEVENT",
+			"UID:3f9c2a1b-break@flower",
+			"DTSTAMP:20250615T120000Z",
+			"DTSTART:20250615T095000Z",
+			"DTEND:20250615T100000Z",
+			`SUMMARY:Break: Fix login\, again`,
+			"DESCRIPTION:Break after 50m of flow",
+			"CATEGORIES:auth,review",
+			"TRANSP:TRANSPARENT",
+			"END:VEVENT",
+		}, "\r\n")
+		if got := b.String(); !strings.Contains(got, want) {
+			t.Errorf("got:\n%s\nwant it to contain:\n%s", got, want)
+		}
+		if n := strings.Count(b.String(), "BEGIN:VEVENT"); n != 3 {
+			t.Errorf("events = %d, want 3 (no break for the session without one)", n)
+		}
+	})
+}
+
+func TestWriteSessionsWithSeveralBreaks(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2025, 6, 15, hour, min, 0, 0, time.UTC) }
+	brk := 10 * time.Minute
+	session := flowtime.CompletedSession{
+		ID:            "3f9c2a1b",
+		Task:          "write code",
+		FlowDuration:  110 * time.Minute,
+		BreakDuration: &brk,
+		CompletedAt:   at(11, 0),
+		Intervals: []flowtime.Interval{
+			{Kind: flowtime.IntervalFlow, Start: at(9, 0), End: at(10, 0)},
+			{Kind: flowtime.IntervalBreak, Start: at(10, 0), End: at(10, 10)},
+			{Kind: flowtime.IntervalFlow, Start: at(10, 10), End: at(11, 0)},
+		},
+	}
+
+	var b strings.Builder
+	if err := WriteSessions(&b, []flowtime.CompletedSession{session}, Options{Breaks: true, Stamp: at(12, 0)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := strings.Join([]string{
+		"BEGIN:VEVENT",
+		"UID:3f9c2a1b@flower",
+		"DTSTAMP:20250615T120000Z",
+		"DTSTART:20250615T090000Z",
+		"DTEND:20250615T100000Z",
+		"SUMMARY:write code",
+		`DESCRIPTION:Part 1 of 2\nFlow: 1h 50m\nBreak: 10m\nSession: 3f9c2a1b`,
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:3f9c2a1b-2@flower",
+		"DTSTAMP:20250615T120000Z",
+		"DTSTART:20250615T101000Z",
+		"DTEND:20250615T110000Z",
+		"SUMMARY:write code",
+		`DESCRIPTION:Part 2 of 2\nFlow: 1h 50m\nBreak: 10m\nSession: 3f9c2a1b`,
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:3f9c2a1b-break@flower",
+		"DTSTAMP:20250615T120000Z",
+		"DTSTART:20250615T100000Z",
+		"DTEND:20250615T101000Z",
+		"SUMMARY:Break: write code",
+		"DESCRIPTION:Break after 1h of flow",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+	}, "\r\n")
+	if got := b.String(); !strings.Contains(got, want) {
+		t.Errorf("got:\n%s\nwant it to contain:\n%s", got, want)
+	}
+}
+
+func TestFold(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("é", 60)
+	folded := fold(line)
+	if !strings.HasSuffix(folded, "\r\n") {
+		t.Fatalf("folded line %q does not end with CRLF", folded)
+	}
+	parts := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+	var unfolded strings.Builder
+	for i, part := range parts {
+		if len(part) > 75 {
+			t.Errorf("line %d is %d octets, want at most 75", i, len(part))
+		}
+		if i > 0 {
+			if !strings.HasPrefix(part, " ") {
+				t.Errorf("continuation line %d %q does not start with a space", i, part)
+			}
+			part = part[1:]
+		}
+		unfolded.WriteString(part)
+	}
+	if unfolded.String() != line {
+		t.Errorf("unfolded = %q, want %q", unfolded.String(), line)
+	}
+}
@@ -10,7 +10,9 @@ func ReversePaginate[T any](elements []T, pageNumber, countPerPage int) []T {
 
 	totalElements := len(elements)
 	startIndex := totalElements - (pageNumber-1)*countPerPage - 1
-	endIndex := totalElements - pageNumber*countPerPage
+	// endIndex is exclusive, so it sits one below the page's oldest element;
+	// without the -1 each full page dropped that element.
+	endIndex := totalElements - pageNumber*countPerPage - 1
 
 	if startIndex < 0 {
 		return []T{}
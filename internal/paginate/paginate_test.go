@@ -0,0 +1,33 @@
+package paginate
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReversePaginate(t *testing.T) {
+	elements := []int{1, 2, 3, 4, 5, 6, 7}
+
+	tests := []struct {
+		name        string
+		page, count int
+		want        []int
+	}{
+		{"first page is newest", 1, 3, []int{7, 6, 5}},
+		{"middle page", 2, 3, []int{4, 3, 2}},
+		{"last page is partial", 3, 3, []int{1}},
+		{"past the end", 4, 3, []int{}},
+		// Regression: an off-by-one end index returned count-1 elements per page.
+		{"exact fit keeps oldest", 1, 7, []int{7, 6, 5, 4, 3, 2, 1}},
+		{"page larger than slice", 1, 10, []int{7, 6, 5, 4, 3, 2, 1}},
+		{"invalid page", 0, 3, []int{}},
+		{"invalid count", 1, 0, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReversePaginate(elements, tt.page, tt.count); !slices.Equal(got, tt.want) {
+				t.Errorf("ReversePaginate(%d, %d) = %v, want %v", tt.page, tt.count, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+var (
+	ErrBackupNotFound  = errors.New("backup not found")
+	ErrAmbiguousBackup = errors.New("backup id matches more than one backup")
+)
+
+// Default rotation: the most recent backups are kept, plus the newest backup of
+// each of the last few days so an old mistake can still be undone.
+const (
+	DefaultKeepRecent = 10
+	DefaultKeepDaily  = 7
+)
+
+// backupInterval is the least time between the automatic backups taken on save.
+// The event log already records every change, so backups only need to cover
+// the log itself being lost or damaged.
+const backupInterval = time.Hour
+
+// backupIDFormat names backups by creation time; IDs sort chronologically.
+const backupIDFormat = "20060102T150405.000"
+
+// Backup describes a snapshot of the state taken before a save.
+type Backup struct {
+	ID        string
+	CreatedAt time.Time
+	Path      string
+}
+
+// BackupStore is implemented by stores that keep rotating backups of their state.
+type BackupStore interface {
+	CreateBackup(state *flowtime.FlowState) (Backup, error)
+	ListBackups() ([]Backup, error)
+	LoadBackup(id string) (*flowtime.FlowState, error)
+	PruneBackups(keepRecent, keepDaily int) ([]Backup, error)
+}
+
+// backupDir returns the directory holding backups of the given state file.
+func backupDir(stateFile string) string {
+	return filepath.Join(filepath.Dir(stateFile), "backups")
+}
+
+// backupPrefix is the file name prefix shared by all backups of the given state file.
+func backupPrefix(stateFile string) string {
+	return strings.TrimSuffix(filepath.Base(stateFile), filepath.Ext(stateFile)) + "-"
+}
+
+// backupDue reports whether the latest backup is at least backupInterval old,
+// so the next save should take another.
+func (s *JSONStore) backupDue() (bool, error) {
+	backups, err := s.ListBackups()
+	if err != nil {
+		return false, err
+	}
+	n := len(backups)
+	return n == 0 || s.clock.Now().Sub(backups[n-1].CreatedAt) >= backupInterval, nil
+}
+
+// writeBackup stores a full copy of state as a new backup and rotates old ones.
+// Empty states are not backed up.
+func (s *JSONStore) writeBackup(stateFile string, state *flowtime.FlowState) error {
+	if isEmptyState(state) {
+		return nil
+	}
+	_, err := s.createBackup(stateFile, state)
+	return err
+}
+
+// CreateBackup stores a full copy of state as a new backup, even if it is empty,
+// and rotates old ones.
+func (s *JSONStore) CreateBackup(state *flowtime.FlowState) (Backup, error) {
+	stateFile, err := s.GetFilePath()
+	if err != nil {
+		return Backup{}, err
+	}
+	return s.createBackup(stateFile, state)
+}
+
+func (s *JSONStore) createBackup(stateFile string, state *flowtime.FlowState) (Backup, error) {
+	dir := backupDir(stateFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Backup{}, fmt.Errorf("creating backup directory %q: %w", dir, err)
+	}
+
+	data, err := json.Marshal(encodeState(state))
+	if err != nil {
+		return Backup{}, fmt.Errorf("marshalling backup: %w", err)
+	}
+
+	createdAt := s.clock.Now()
+	id := createdAt.UTC().Format(backupIDFormat)
+	path := filepath.Join(dir, backupPrefix(stateFile)+id+".json")
+	if err := writeFileAtomic(path, data); err != nil {
+		return Backup{}, fmt.Errorf("writing backup: %w", err)
+	}
+
+	if _, err := s.PruneBackups(DefaultKeepRecent, DefaultKeepDaily); err != nil {
+		return Backup{}, err
+	}
+	return Backup{ID: id, CreatedAt: createdAt, Path: path}, nil
+}
+
+// ListBackups returns the available backups, oldest first.
+func (s *JSONStore) ListBackups() ([]Backup, error) {
+	stateFile, err := s.GetFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := backupDir(stateFile)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading backup directory: %w", err)
+	}
+
+	prefix := backupPrefix(stateFile)
+	var backups []Backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".json")
+		createdAt, err := time.Parse(backupIDFormat, id)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{
+			ID:        id,
+			CreatedAt: createdAt.Local(),
+			Path:      filepath.Join(dir, name),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].ID < backups[j].ID })
+	return backups, nil
+}
+
+// LoadBackup reads the backup whose ID equals or uniquely starts with id.
+func (s *JSONStore) LoadBackup(id string) (*flowtime.FlowState, error) {
+	backups, err := s.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	var match *Backup
+	for i, b := range backups {
+		if b.ID == id {
+			match = &backups[i]
+			break
+		}
+		if strings.HasPrefix(b.ID, id) {
+			if match != nil {
+				return nil, fmt.Errorf("%w: %q", ErrAmbiguousBackup, id)
+			}
+			match = &backups[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w: %q", ErrBackupNotFound, id)
+	}
+
+	data, err := os.ReadFile(match.Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading backup: %w", err)
+	}
+	state, err := unmarshalState(data, s.clock)
+	if err != nil {
+		return nil, fmt.Errorf("reading backup %s: %w", match.ID, err)
+	}
+	return state, nil
+}
+
+// PruneBackups deletes all backups except the keepRecent most recent ones and
+// the newest backup of each of the last keepDaily days on which backups were
+// taken. Returns the deleted backups.
+func (s *JSONStore) PruneBackups(keepRecent, keepDaily int) ([]Backup, error) {
+	backups, err := s.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]bool)
+	for i := len(backups) - 1; i >= 0 && i >= len(backups)-keepRecent; i-- {
+		keep[backups[i].ID] = true
+	}
+
+	days := make(map[string]bool)
+	for i := len(backups) - 1; i >= 0 && len(days) < keepDaily; i-- {
+		day := backups[i].CreatedAt.Format(time.DateOnly)
+		if !days[day] {
+			days[day] = true
+			keep[backups[i].ID] = true
+		}
+	}
+
+	var pruned []Backup
+	for _, b := range backups {
+		if keep[b.ID] {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil {
+			return pruned, fmt.Errorf("removing backup %s: %w", b.ID, err)
+		}
+		pruned = append(pruned, b)
+	}
+	return pruned, nil
+}
+
+// CopyStateFile copies the store file at path to a timestamped backup alongside
+// it, named after reason, and returns the backup's path. It is used to back up
+// stores that do not implement BackupStore.
+func CopyStateFile(path, reason string, now time.Time) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %q for backup: %w", path, err)
+	}
+
+	backupFile := fmt.Sprintf("%s.%s-%s.bak", path, reason, now.Format("20060102T150405"))
+	if err := writeFileAtomic(backupFile, data); err != nil {
+		return "", fmt.Errorf("writing backup: %w", err)
+	}
+	return backupFile, nil
+}
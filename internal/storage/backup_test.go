@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJSONStoreBackups(t *testing.T) {
+	store := newTestStore(t, BackendJSON).(*JSONStore)
+	clock := &steppingClock{now: time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC), step: backupInterval}
+	store.clock = clock
+
+	state, _ := store.Load()
+	_ = state.StartSession("write code")
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := store.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 0 {
+		t.Fatalf("backups = %d, want 0 before any data existed", len(backups))
+	}
+
+	_, _ = state.Stop()
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+	_ = state.DeleteAllSessions()
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err = store.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("backups = %d, want 2", len(backups))
+	}
+
+	// The newest backup holds the state from just before the delete.
+	restored, err := store.LoadBackup(backups[1].ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(restored.ActiveSessions()) != 1 {
+		t.Errorf("restored active sessions = %d, want 1", len(restored.ActiveSessions()))
+	}
+
+	if _, err := store.LoadBackup("1999"); !errors.Is(err, ErrBackupNotFound) {
+		t.Errorf("error = %v, want %v", err, ErrBackupNotFound)
+	}
+	if _, err := store.LoadBackup("2025"); !errors.Is(err, ErrAmbiguousBackup) {
+		t.Errorf("error = %v, want %v", err, ErrAmbiguousBackup)
+	}
+}
+
+func TestJSONStoreBackupInterval(t *testing.T) {
+	store := newTestStore(t, BackendJSON).(*JSONStore)
+	clock := &steppingClock{now: time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC), step: time.Minute}
+	store.clock = clock
+
+	state, _ := store.Load()
+	for range 5 {
+		_ = state.StartSession("write code")
+		if err := store.Save(state); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = state.Stop()
+		if err := store.Save(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := store.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("backups = %d, want 1 within %v", len(backups), backupInterval)
+	}
+
+	clock.now = clock.now.Add(backupInterval)
+	_ = state.StartSession("write more code")
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ = store.ListBackups(); len(backups) != 2 {
+		t.Errorf("backups = %d, want 2 once %v has passed", len(backups), backupInterval)
+	}
+
+	// Explicit backups are always taken.
+	if _, err := store.CreateBackup(state); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ = store.ListBackups(); len(backups) != 3 {
+		t.Errorf("backups = %d, want 3 after CreateBackup", len(backups))
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	store := newTestStore(t, BackendJSON).(*JSONStore)
+	stateFile, _ := store.GetFilePath()
+	dir := backupDir(stateFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Three backups on each of three days.
+	start := time.Date(2025, 6, 13, 9, 0, 0, 0, time.Local)
+	for day := 0; day < 3; day++ {
+		for hour := 0; hour < 3; hour++ {
+			at := start.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+			name := backupPrefix(stateFile) + at.UTC().Format(backupIDFormat) + ".json"
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(`{"version":1}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	pruned, err := store.PruneBackups(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 5 {
+		t.Errorf("pruned = %d, want 5", len(pruned))
+	}
+
+	backups, _ := store.ListBackups()
+	var kept []string
+	for _, b := range backups {
+		kept = append(kept, b.CreatedAt.Format("Jan 2 15:04"))
+	}
+	want := []string{"Jun 13 11:00", "Jun 14 11:00", "Jun 15 10:00", "Jun 15 11:00"}
+	if len(kept) != len(want) {
+		t.Fatalf("kept = %v, want %v", kept, want)
+	}
+	for i := range want {
+		if kept[i] != want[i] {
+			t.Errorf("kept = %v, want %v", kept, want)
+			break
+		}
+	}
+}
+
+// steppingClock advances by step every time it is read.
+type steppingClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+// EventLog is implemented by stores that keep the full history of transitions.
+type EventLog interface {
+	Events() ([]flowtime.Event, error)
+}
+
+// jsonEvent is the serialized form of a flowtime.Event: one line of the JSON
+// store's event log, or the data column of the SQLite events table. Seq is the
+// event's sequence number: events are numbered from 1, and a log compacted by a
+// purge starts at its baseline's number.
+type jsonEvent struct {
+	Seq            uint64                `json:"seq"`
+	Kind           string                `json:"kind"`
+	At             time.Time             `json:"at"`
+	Task           string                `json:"task,omitempty"`
+	Project        string                `json:"project,omitempty"`
+	Tags           []string              `json:"tags,omitempty"`
+	SuggestedBreak time.Duration         `json:"suggested_break,omitempty"`
+	Text           string                `json:"text,omitempty"`
+	Interruption   string                `json:"interruption,omitempty"`
+	SessionID      string                `json:"session_id,omitempty"`
+	Index          *int                  `json:"index,omitempty"`
+	Since          *time.Time            `json:"since,omitempty"`
+	Before         *time.Time            `json:"before,omitempty"`
+	State          json.RawMessage       `json:"state,omitempty"`
+	Session        *jsonCompletedSession `json:"session,omitempty"`
+	Undone         string                `json:"undone,omitempty"`
+	Change         *jsonChange           `json:"change,omitempty"`
+}
+
+// encodeEvent converts an event to its serialized form.
+func encodeEvent(seq uint64, e flowtime.Event) (jsonEvent, error) {
+	je := encodeEventFields(seq, e)
+	if e.Snapshot != nil {
+		data, err := json.Marshal(encodeState(e.Snapshot))
+		if err != nil {
+			return jsonEvent{}, fmt.Errorf("marshalling event %d snapshot: %w", seq, err)
+		}
+		je.State = data
+	}
+	return je, nil
+}
+
+// encodeEventFields converts everything but an event's snapshot to its serialized form.
+func encodeEventFields(seq uint64, e flowtime.Event) jsonEvent {
+	je := jsonEvent{
+		Seq:            seq,
+		Kind:           string(e.Kind),
+		At:             e.At,
+		Task:           e.Task,
+		Project:        e.Project,
+		Tags:           e.Tags,
+		SuggestedBreak: e.SuggestedBreak,
+		Text:           e.Text,
+		Interruption:   string(e.Interruption),
+		SessionID:      e.SessionID,
+		Undone:         string(e.Undone),
+	}
+	if e.Kind == flowtime.EventDelete || e.Kind == flowtime.EventRestore {
+		index := e.Index
+		je.Index = &index
+	}
+	if !e.Since.IsZero() {
+		since := e.Since
+		je.Since = &since
+	}
+	if !e.Before.IsZero() {
+		before := e.Before
+		je.Before = &before
+	}
+	if e.Session != nil {
+		session := encodeCompletedSession(*e.Session)
+		je.Session = &session
+	}
+	if e.Change != nil {
+		change := encodeChange(*e.Change)
+		je.Change = &change
+	}
+	return je
+}
+
+// decodeEvent converts a serialized event back into a flowtime.Event.
+// Snapshots are migrated from the state version they were written with.
+func decodeEvent(je jsonEvent, clock flowtime.Clock) (flowtime.Event, error) {
+	e := decodeEventFields(je)
+	if len(je.State) > 0 {
+		snapshot, err := unmarshalState(je.State, clock)
+		if err != nil {
+			return flowtime.Event{}, fmt.Errorf("decoding event %d snapshot: %w", je.Seq, err)
+		}
+		e.Snapshot = snapshot
+	}
+	return e, nil
+}
+
+// decodeEventFields converts everything but a serialized event's snapshot back
+// into a flowtime.Event.
+func decodeEventFields(je jsonEvent) flowtime.Event {
+	e := flowtime.Event{
+		Kind:           flowtime.EventKind(je.Kind),
+		At:             je.At,
+		Task:           je.Task,
+		Project:        je.Project,
+		Tags:           je.Tags,
+		SuggestedBreak: je.SuggestedBreak,
+		Text:           je.Text,
+		Interruption:   flowtime.InterruptionKind(je.Interruption),
+		SessionID:      je.SessionID,
+		Undone:         flowtime.EventKind(je.Undone),
+	}
+	if je.Index != nil {
+		e.Index = *je.Index
+	}
+	if je.Since != nil {
+		e.Since = *je.Since
+	}
+	if je.Before != nil {
+		e.Before = *je.Before
+	}
+	if je.Session != nil {
+		session := decodeCompletedSession(*je.Session)
+		e.Session = &session
+	}
+	if je.Change != nil {
+		change := decodeChange(*je.Change)
+		e.Change = &change
+	}
+	return e
+}
+
+// logPosition locates an event in the log: its sequence number and the offset
+// of the line it was written on. The zero logPosition is the start of the log.
+type logPosition struct {
+	rev    uint64
+	offset int64
+}
+
+// readEventLog reads the complete events after from in the log at path. A
+// missing log yields no events. Reading starts at from's offset, so only the
+// tail of the log is parsed; if the event at from is not found there, e.g.
+// because the log has been compacted since, the whole log is read instead. last is the position of the final event, and validLen
+// the length of the file up to the end of the last complete line; anything
+// after it is a torn write from an interrupted append.
+func readEventLog(path string, from logPosition) (events []jsonEvent, last logPosition, validLen int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, logPosition{}, 0, nil
+		}
+		return nil, logPosition{}, 0, fmt.Errorf("opening event log: %w", err)
+	}
+	defer f.Close()
+
+	if from.rev > 0 {
+		events, last, validLen, err := scanEventLog(f, from.offset, from.rev)
+		if err == nil && len(events) > 0 {
+			return events[1:], last, validLen, nil
+		}
+	}
+
+	events, last, validLen, err = scanEventLog(f, 0, 0)
+	if err != nil {
+		return nil, logPosition{}, 0, err
+	}
+	i := 0
+	for i < len(events) && events[i].Seq <= from.rev {
+		i++
+	}
+	return events[i:], last, validLen, nil
+}
+
+// scanEventLog parses the log from offset, where the event with sequence number
+// seq is expected to start, to the last complete line. A seq of 0 accepts any
+// first event, as at the start of a compacted log.
+func scanEventLog(f *os.File, offset int64, seq uint64) (events []jsonEvent, last logPosition, validLen int64, err error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, logPosition{}, 0, fmt.Errorf("seeking event log: %w", err)
+	}
+
+	validLen = offset
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A final line without a newline was never fully written.
+			return events, last, validLen, nil
+		}
+		if err != nil {
+			return nil, logPosition{}, 0, fmt.Errorf("reading event log: %w", err)
+		}
+
+		var je jsonEvent
+		if err := json.Unmarshal(bytes.TrimSpace(line), &je); err != nil {
+			return nil, logPosition{}, 0, fmt.Errorf("parsing event log at offset %d: %w", validLen, err)
+		}
+		if seq == 0 {
+			seq = je.Seq
+		}
+		if je.Seq != seq {
+			return nil, logPosition{}, 0, fmt.Errorf("event %d in the event log has sequence number %d", seq, je.Seq)
+		}
+
+		events = append(events, je)
+		last = logPosition{rev: seq, offset: validLen}
+		validLen += int64(len(line))
+		seq++
+	}
+}
+
+// appendEventLog appends events to the log at path, one JSON object per line,
+// first discarding any torn write after validLen. Returns the offset of the last
+// event's line.
+func appendEventLog(path string, events []jsonEvent, validLen int64) (int64, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	var (
+		buf  bytes.Buffer
+		last int64
+	)
+	for _, je := range events {
+		data, err := json.Marshal(je)
+		if err != nil {
+			return 0, fmt.Errorf("marshalling event %d: %w", je.Seq, err)
+		}
+		last = validLen + int64(buf.Len())
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("opening event log: %w", err)
+	}
+	defer f.Close()
+
+	if err := f.Truncate(validLen); err != nil {
+		return 0, fmt.Errorf("truncating event log: %w", err)
+	}
+	if _, err := f.WriteAt(buf.Bytes(), validLen); err != nil {
+		return 0, fmt.Errorf("appending to event log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return 0, fmt.Errorf("syncing event log: %w", err)
+	}
+	return last, nil
+}
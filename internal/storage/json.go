@@ -2,20 +2,33 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/Broderick-Westrope/flower/internal/flowtime"
-	"github.com/adrg/xdg"
 )
 
-const stateVersion = 1
+// stateVersion is the state file format written by Save. Older files are
+// upgraded on Load through jsonMigrations.
+const stateVersion = 3
 
-// JSONStore persists FlowState as JSON to the filesystem.
+// snapshotInterval is how many events may be appended to the log before the
+// state file snapshot is rewritten. Loads and saves read at most this many
+// events from the log.
+const snapshotInterval = 20
+
+// JSONStore persists FlowState to the filesystem as an append-only event log,
+// which is the source of truth, plus a JSON snapshot of the state that is
+// refreshed every snapshotInterval events. The snapshot records where in the
+// log it was taken, so loads seek there and only read the tail of the log.
 type JSONStore struct {
 	clock flowtime.Clock
+	opts  Options
 }
 
 // NewJSONStore creates a new JSONStore that uses the given clock for constructing FlowState.
@@ -25,18 +38,41 @@ func NewJSONStore(clock flowtime.Clock) *JSONStore {
 
 // GetFilePath returns the path to the state file, creating the parent directory if needed.
 func (s *JSONStore) GetFilePath() (string, error) {
-	flowerDir := filepath.Join(xdg.DataHome, "flower")
-	if err := os.MkdirAll(flowerDir, 0755); err != nil {
-		return "", fmt.Errorf("creating flower data directory %q: %w", flowerDir, err)
-	}
-	return filepath.Join(flowerDir, "state.json"), nil
+	return statePath(s.opts, "state.json")
+}
+
+// eventLogPath returns the event log stored alongside the given state file.
+func eventLogPath(stateFile string) string {
+	return strings.TrimSuffix(stateFile, filepath.Ext(stateFile)) + ".events.jsonl"
 }
 
 // JSON serialization types
 
 type jsonSession struct {
-	Task      string    `json:"task"`
-	StartTime time.Time `json:"start_time"`
+	Task          string             `json:"task"`
+	Project       string             `json:"project,omitempty"`
+	Tags          []string           `json:"tags,omitempty"`
+	StartTime     time.Time          `json:"start_time"`
+	Intervals     []jsonInterval     `json:"intervals,omitempty"`
+	Notes         []jsonNote         `json:"notes,omitempty"`
+	Interruptions []jsonInterruption `json:"interruptions,omitempty"`
+}
+
+type jsonInterval struct {
+	Kind  string    `json:"kind"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type jsonNote struct {
+	At   time.Time `json:"at"`
+	Text string    `json:"text"`
+}
+
+type jsonInterruption struct {
+	At     time.Time `json:"at"`
+	Kind   string    `json:"kind,omitempty"`
+	Reason string    `json:"reason,omitempty"`
 }
 
 type jsonBreak struct {
@@ -45,139 +81,703 @@ type jsonBreak struct {
 }
 
 type jsonCompletedSession struct {
-	Task          string         `json:"task"`
-	FlowDuration  time.Duration  `json:"flow_duration"`
-	BreakDuration *time.Duration `json:"break_duration"`
-	CompletedAt   time.Time      `json:"completed_at"`
-	DeletedAt     *time.Time     `json:"deleted_at,omitempty"`
+	ID            string             `json:"id"`
+	Task          string             `json:"task"`
+	Project       string             `json:"project,omitempty"`
+	Tags          []string           `json:"tags,omitempty"`
+	Intervals     []jsonInterval     `json:"intervals"`
+	Notes         []jsonNote         `json:"notes,omitempty"`
+	Interruptions []jsonInterruption `json:"interruptions,omitempty"`
+	FlowDuration  time.Duration      `json:"flow_duration"`
+	BreakDuration *time.Duration     `json:"break_duration"`
+	CompletedAt   time.Time          `json:"completed_at"`
+	DeletedAt     *time.Time         `json:"deleted_at,omitempty"`
 }
 
+// jsonState is the state file format. Revision is the number of events in the
+// log that the snapshot reflects, and LogOffset the offset of the last one's line.
 type jsonState struct {
 	Version           int                    `json:"version"`
+	Revision          uint64                 `json:"revision"`
+	LogOffset         int64                  `json:"log_offset,omitempty"`
 	CurrentSession    *jsonSession           `json:"current_session"`
 	CurrentBreak      *jsonBreak             `json:"current_break"`
 	CompletedSessions []jsonCompletedSession `json:"completed_sessions"`
+	jsonUndoHistory
+}
+
+// jsonUndoHistory is the serialized form of a state's undo and redo stacks.
+type jsonUndoHistory struct {
+	Undo []jsonUndoStep `json:"undo,omitempty"`
+	Redo []jsonUndoStep `json:"redo,omitempty"`
+}
+
+type jsonUndoStep struct {
+	Event  jsonEvent  `json:"event"`
+	Change jsonChange `json:"change"`
+}
+
+// jsonChange is the serialized form of a flowtime.Change.
+type jsonChange struct {
+	CurrentSession *jsonSession           `json:"current_session"`
+	CurrentBreak   *jsonBreak             `json:"current_break"`
+	Sessions       []jsonCompletedSession `json:"sessions,omitempty"`
+	Removed        []string               `json:"removed,omitempty"`
+}
+
+// encodeState converts a FlowState to its serialized form.
+func encodeState(state *flowtime.FlowState) jsonState {
+	js := jsonState{
+		Version:           stateVersion,
+		Revision:          state.Revision(),
+		CurrentSession:    encodeSession(state.CurrentSession),
+		CurrentBreak:      encodeBreak(state.CurrentBreak),
+		CompletedSessions: make([]jsonCompletedSession, 0, len(state.CompletedSessions)),
+		jsonUndoHistory:   encodeUndoHistory(state),
+	}
+
+	for _, cs := range state.CompletedSessions {
+		js.CompletedSessions = append(js.CompletedSessions, encodeCompletedSession(cs))
+	}
+
+	return js
+}
+
+// encodeSession converts the session in progress, if any, to its serialized form.
+func encodeSession(session *flowtime.Session) *jsonSession {
+	if session == nil {
+		return nil
+	}
+	return &jsonSession{
+		Task:          session.Task,
+		Project:       session.Project,
+		Tags:          slices.Clone(session.Tags),
+		StartTime:     session.StartTime,
+		Intervals:     encodeIntervals(session.Intervals),
+		Notes:         encodeNotes(session.Notes),
+		Interruptions: encodeInterruptions(session.Interruptions),
+	}
+}
+
+// encodeBreak converts the break in progress, if any, to its serialized form.
+func encodeBreak(brk *flowtime.Break) *jsonBreak {
+	if brk == nil {
+		return nil
+	}
+	return &jsonBreak{StartTime: brk.StartTime, SuggestedDuration: brk.SuggestedDuration}
+}
+
+// encodeUndoHistory converts the state's undo and redo stacks to their serialized form.
+func encodeUndoHistory(state *flowtime.FlowState) jsonUndoHistory {
+	undo, redo := state.UndoSteps()
+	return jsonUndoHistory{Undo: encodeUndoSteps(undo), Redo: encodeUndoSteps(redo)}
+}
+
+func encodeUndoSteps(steps []flowtime.UndoStep) []jsonUndoStep {
+	if len(steps) == 0 {
+		return nil
+	}
+	encoded := make([]jsonUndoStep, len(steps))
+	for i, step := range steps {
+		encoded[i] = jsonUndoStep{Event: encodeEventFields(0, step.Event), Change: encodeChange(step.Change)}
+	}
+	return encoded
+}
+
+// encodeCompletedSession converts a completed session to its serialized form.
+func encodeCompletedSession(cs flowtime.CompletedSession) jsonCompletedSession {
+	jcs := jsonCompletedSession{
+		ID:            cs.ID,
+		Task:          cs.Task,
+		Project:       cs.Project,
+		Tags:          slices.Clone(cs.Tags),
+		Intervals:     encodeIntervals(cs.Intervals),
+		Notes:         encodeNotes(cs.Notes),
+		Interruptions: encodeInterruptions(cs.Interruptions),
+		FlowDuration:  cs.FlowDuration,
+		CompletedAt:   cs.CompletedAt,
+	}
+	if cs.BreakDuration != nil {
+		bd := *cs.BreakDuration
+		jcs.BreakDuration = &bd
+	}
+	if cs.DeletedAt != nil {
+		t := *cs.DeletedAt
+		jcs.DeletedAt = &t
+	}
+	return jcs
+}
+
+// decodeState converts a serialized state into a FlowState using the given
+// clock, assigning the IDs and intervals missing from older sessions.
+func decodeState(js jsonState, clock flowtime.Clock) *flowtime.FlowState {
+	state := decodeStoredState(js, clock)
+	state.BackfillSessionIDs()
+	state.BackfillIntervals()
+	return state
+}
+
+// decodeStoredState is decodeState without the backfilling, so the state is
+// exactly as stored.
+func decodeStoredState(js jsonState, clock flowtime.Clock) *flowtime.FlowState {
+	state := flowtime.NewFlowState(clock)
+	state.SetRevision(js.Revision)
+	state.CurrentSession = decodeSession(js.CurrentSession)
+	state.CurrentBreak = decodeBreak(js.CurrentBreak)
+
+	for _, cs := range js.CompletedSessions {
+		state.CompletedSessions = append(state.CompletedSessions, decodeCompletedSession(cs))
+	}
+	decodeUndoHistory(js.jsonUndoHistory, state)
+
+	return state
+}
+
+// decodeSession converts a serialized session in progress, if any, back into a
+// flowtime.Session.
+func decodeSession(js *jsonSession) *flowtime.Session {
+	if js == nil {
+		return nil
+	}
+	return &flowtime.Session{
+		Task:          js.Task,
+		Project:       js.Project,
+		Tags:          slices.Clone(js.Tags),
+		StartTime:     js.StartTime,
+		Intervals:     decodeIntervals(js.Intervals),
+		Notes:         decodeNotes(js.Notes),
+		Interruptions: decodeInterruptions(js.Interruptions),
+	}
+}
+
+// decodeBreak converts a serialized break in progress, if any, back into a flowtime.Break.
+func decodeBreak(jb *jsonBreak) *flowtime.Break {
+	if jb == nil {
+		return nil
+	}
+	return &flowtime.Break{StartTime: jb.StartTime, SuggestedDuration: jb.SuggestedDuration}
+}
+
+// decodeUndoHistory restores serialized undo and redo stacks onto state.
+func decodeUndoHistory(h jsonUndoHistory, state *flowtime.FlowState) {
+	state.SetUndoSteps(decodeUndoSteps(h.Undo), decodeUndoSteps(h.Redo))
 }
 
-// Load reads the state file and returns a FlowState. If the file does not exist, returns a new empty FlowState.
+func decodeUndoSteps(encoded []jsonUndoStep) []flowtime.UndoStep {
+	if len(encoded) == 0 {
+		return nil
+	}
+	steps := make([]flowtime.UndoStep, len(encoded))
+	for i, js := range encoded {
+		steps[i] = flowtime.UndoStep{Event: decodeEventFields(js.Event), Change: decodeChange(js.Change)}
+	}
+	return steps
+}
+
+// encodeChange converts a change to its serialized form.
+func encodeChange(c flowtime.Change) jsonChange {
+	jc := jsonChange{
+		CurrentSession: encodeSession(c.CurrentSession),
+		CurrentBreak:   encodeBreak(c.CurrentBreak),
+		Removed:        slices.Clone(c.Removed),
+	}
+	for _, cs := range c.Sessions {
+		jc.Sessions = append(jc.Sessions, encodeCompletedSession(cs))
+	}
+	return jc
+}
+
+// decodeChange converts a serialized change back into a flowtime.Change.
+func decodeChange(jc jsonChange) flowtime.Change {
+	c := flowtime.Change{
+		CurrentSession: decodeSession(jc.CurrentSession),
+		CurrentBreak:   decodeBreak(jc.CurrentBreak),
+		Removed:        slices.Clone(jc.Removed),
+	}
+	for _, cs := range jc.Sessions {
+		c.Sessions = append(c.Sessions, decodeCompletedSession(cs))
+	}
+	return c
+}
+
+// decodeCompletedSession converts a serialized completed session back into a
+// flowtime.CompletedSession.
+func decodeCompletedSession(cs jsonCompletedSession) flowtime.CompletedSession {
+	completed := flowtime.CompletedSession{
+		ID:            cs.ID,
+		Task:          cs.Task,
+		Project:       cs.Project,
+		Tags:          slices.Clone(cs.Tags),
+		Intervals:     decodeIntervals(cs.Intervals),
+		Notes:         decodeNotes(cs.Notes),
+		Interruptions: decodeInterruptions(cs.Interruptions),
+		FlowDuration:  cs.FlowDuration,
+		CompletedAt:   cs.CompletedAt,
+	}
+	if cs.BreakDuration != nil {
+		bd := *cs.BreakDuration
+		completed.BreakDuration = &bd
+	}
+	if cs.DeletedAt != nil {
+		t := *cs.DeletedAt
+		completed.DeletedAt = &t
+	}
+	return completed
+}
+
+// encodeIntervals converts intervals to their serialized form.
+func encodeIntervals(intervals []flowtime.Interval) []jsonInterval {
+	if len(intervals) == 0 {
+		return nil
+	}
+	encoded := make([]jsonInterval, len(intervals))
+	for i, iv := range intervals {
+		encoded[i] = jsonInterval{Kind: string(iv.Kind), Start: iv.Start, End: iv.End}
+	}
+	return encoded
+}
+
+// decodeIntervals converts serialized intervals back into flowtime.Intervals.
+func decodeIntervals(encoded []jsonInterval) []flowtime.Interval {
+	if len(encoded) == 0 {
+		return nil
+	}
+	intervals := make([]flowtime.Interval, len(encoded))
+	for i, ji := range encoded {
+		intervals[i] = flowtime.Interval{Kind: flowtime.IntervalKind(ji.Kind), Start: ji.Start, End: ji.End}
+	}
+	return intervals
+}
+
+// encodeNotes converts notes to their serialized form.
+func encodeNotes(notes []flowtime.Note) []jsonNote {
+	if len(notes) == 0 {
+		return nil
+	}
+	encoded := make([]jsonNote, len(notes))
+	for i, n := range notes {
+		encoded[i] = jsonNote{At: n.At, Text: n.Text}
+	}
+	return encoded
+}
+
+// decodeNotes converts serialized notes back into flowtime.Notes.
+func decodeNotes(encoded []jsonNote) []flowtime.Note {
+	if len(encoded) == 0 {
+		return nil
+	}
+	notes := make([]flowtime.Note, len(encoded))
+	for i, jn := range encoded {
+		notes[i] = flowtime.Note{At: jn.At, Text: jn.Text}
+	}
+	return notes
+}
+
+// encodeInterruptions converts interruptions to their serialized form.
+func encodeInterruptions(interruptions []flowtime.Interruption) []jsonInterruption {
+	if len(interruptions) == 0 {
+		return nil
+	}
+	encoded := make([]jsonInterruption, len(interruptions))
+	for i, in := range interruptions {
+		encoded[i] = jsonInterruption{At: in.At, Kind: string(in.Kind), Reason: in.Reason}
+	}
+	return encoded
+}
+
+// decodeInterruptions converts serialized interruptions back into flowtime.Interruptions.
+func decodeInterruptions(encoded []jsonInterruption) []flowtime.Interruption {
+	if len(encoded) == 0 {
+		return nil
+	}
+	interruptions := make([]flowtime.Interruption, len(encoded))
+	for i, ji := range encoded {
+		interruptions[i] = flowtime.Interruption{At: ji.At, Kind: flowtime.InterruptionKind(ji.Kind), Reason: ji.Reason}
+	}
+	return interruptions
+}
+
+// unmarshalState migrates a serialized state document to stateVersion and decodes it.
+func unmarshalState(data []byte, clock flowtime.Clock) (*flowtime.FlowState, error) {
+	data, _, err := migrateJSON(data, stateVersion, jsonMigrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var js jsonState
+	if err := json.Unmarshal(data, &js); err != nil {
+		return nil, fmt.Errorf("parsing state: %w", err)
+	}
+	return decodeState(js, clock), nil
+}
+
+// Load returns the FlowState described by the snapshot plus any newer events in
+// the log. If the snapshot is missing or corrupt, the state is rebuilt by
+// replaying the whole log. With neither file present, returns a new empty FlowState.
 func (s *JSONStore) Load() (*flowtime.FlowState, error) {
 	stateFile, err := s.GetFilePath()
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = os.Stat(stateFile)
+	unlock, err := lockFile(stateFile + ".lock")
 	if err != nil {
-		if os.IsNotExist(err) {
-			return flowtime.NewFlowState(s.clock), nil
+		return nil, err
+	}
+	defer unlock()
+
+	state, err := s.load(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	state.SetBreakPolicy(s.opts.BreakPolicy)
+	state.SetLongBreakRule(s.opts.LongBreak)
+	return state, nil
+}
+
+// load materializes the current state from the snapshot and the events in the
+// log after it. The caller must hold the state file lock.
+func (s *JSONStore) load(stateFile string) (*flowtime.FlowState, error) {
+	snapshot, pos, snapshotErr := s.readSnapshot(stateFile)
+	if errors.Is(snapshotErr, ErrNewerVersion) {
+		return nil, snapshotErr
+	}
+	if snapshotErr != nil {
+		// The snapshot is unusable but the log is the source of truth: rebuild.
+		snapshot, pos = nil, logPosition{}
+	}
+
+	events, last, _, err := readEventLog(eventLogPath(stateFile), pos)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotErr != nil && last.rev == 0 {
+		return nil, snapshotErr
+	}
+
+	state := snapshot
+	if state == nil {
+		state = flowtime.NewFlowState(s.clock)
+	}
+	if err := s.replay(state, events); err != nil {
+		return nil, err
+	}
+
+	// A snapshot ahead of the log was written before the event log existed.
+	state.SetRevision(last.rev)
+	return state, nil
+}
+
+// replay applies stored events to state in order.
+func (s *JSONStore) replay(state *flowtime.FlowState, events []jsonEvent) error {
+	for _, je := range events {
+		e, err := decodeEvent(je, s.clock)
+		if err != nil {
+			return err
+		}
+		if err := state.Apply(e); err != nil {
+			return fmt.Errorf("replaying event %d (%s): %w", je.Seq, je.Kind, err)
 		}
-		return nil, fmt.Errorf("checking state file: %w", err)
 	}
+	return nil
+}
 
+// readSnapshot reads the state file, upgrading it in place if it is older than
+// stateVersion, and returns it with the position in the log it was taken at.
+// Returns nil without error if the file does not exist.
+func (s *JSONStore) readSnapshot(stateFile string) (*flowtime.FlowState, logPosition, error) {
 	data, err := os.ReadFile(stateFile)
 	if err != nil {
-		return nil, fmt.Errorf("reading state file: %w", err)
+		if os.IsNotExist(err) {
+			return nil, logPosition{}, nil
+		}
+		return nil, logPosition{}, fmt.Errorf("reading state file: %w", err)
+	}
+
+	data, err = upgradeStateFile(stateFile, data, s.clock.Now())
+	if err != nil {
+		return nil, logPosition{}, err
 	}
 
 	var js jsonState
 	if err := json.Unmarshal(data, &js); err != nil {
-		return nil, fmt.Errorf("parsing state file: %w", err)
+		return nil, logPosition{}, fmt.Errorf("parsing state file: %w", err)
 	}
+	return decodeState(js, s.clock), logPosition{rev: js.Revision, offset: js.LogOffset}, nil
+}
 
-	if js.Version != stateVersion {
-		return nil, fmt.Errorf("unsupported state version %d (expected %d)", js.Version, stateVersion)
+// Inspect returns the snapshot as stored, without upgrading the file or
+// backfilling IDs and intervals, with the log after it replayed up to the first
+// event that fails. A snapshot that cannot be read is skipped, as Load does.
+func (s *JSONStore) Inspect() (*flowtime.FlowState, []flowtime.Problem, error) {
+	stateFile, err := s.GetFilePath()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	state := flowtime.NewFlowState(s.clock)
+	unlock, err := lockFile(stateFile + ".lock")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
 
-	if js.CurrentSession != nil {
-		state.CurrentSession = &flowtime.Session{
-			Task:      js.CurrentSession.Task,
-			StartTime: js.CurrentSession.StartTime,
+	state := flowtime.NewFlowState(s.clock)
+	var pos logPosition
+	data, err := os.ReadFile(stateFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("reading state file: %w", err)
+	}
+	if err == nil {
+		migrated, _, err := migrateJSON(data, stateVersion, jsonMigrations)
+		if errors.Is(err, ErrNewerVersion) {
+			return nil, nil, err
+		}
+		var js jsonState
+		if err == nil && json.Unmarshal(migrated, &js) == nil {
+			state = decodeStoredState(js, s.clock)
+			pos = logPosition{rev: js.Revision, offset: js.LogOffset}
 		}
 	}
 
-	if js.CurrentBreak != nil {
-		state.CurrentBreak = &flowtime.Break{
-			StartTime:         js.CurrentBreak.StartTime,
-			SuggestedDuration: js.CurrentBreak.SuggestedDuration,
-		}
+	events, last, _, err := readEventLog(eventLogPath(stateFile), pos)
+	if err != nil {
+		return state, []flowtime.Problem{replayProblem(err)}, nil
+	}
+	var problems []flowtime.Problem
+	if err := s.replay(state, events); err != nil {
+		problems = append(problems, replayProblem(err))
 	}
+	state.SetRevision(last.rev)
+	return state, problems, nil
+}
 
-	for _, cs := range js.CompletedSessions {
-		completed := flowtime.CompletedSession{
-			Task:         cs.Task,
-			FlowDuration: cs.FlowDuration,
-			CompletedAt:  cs.CompletedAt,
-		}
-		if cs.BreakDuration != nil {
-			bd := *cs.BreakDuration
-			completed.BreakDuration = &bd
-		}
-		if cs.DeletedAt != nil {
-			t := *cs.DeletedAt
-			completed.DeletedAt = &t
-		}
-		state.CompletedSessions = append(state.CompletedSessions, completed)
+// Events returns every event in the log, oldest first.
+func (s *JSONStore) Events() ([]flowtime.Event, error) {
+	stateFile, err := s.GetFilePath()
+	if err != nil {
+		return nil, err
 	}
 
-	return state, nil
+	stored, _, _, err := readEventLog(eventLogPath(stateFile), logPosition{})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]flowtime.Event, 0, len(stored))
+	for _, je := range stored {
+		e, err := decodeEvent(je, s.clock)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
 }
 
-// Save writes the FlowState to the state file using atomic temp-file-then-rename.
+// Save appends the state's pending events to the event log, refreshing the
+// snapshot when it has fallen snapshotInterval events behind. The state as it
+// was before the save is first copied to a rotating backup, at most once every
+// backupInterval. The files are
+// locked for the duration of the save. If another process has saved since the
+// state was loaded, the pending events are rebased onto the latest state;
+// ErrConflict is returned if they no longer apply. Otherwise only the log's
+// tail is read, to find where to append. Saving a purge compacts the log
+// instead of appending to it.
 func (s *JSONStore) Save(state *flowtime.FlowState) error {
 	stateFile, err := s.GetFilePath()
 	if err != nil {
 		return err
 	}
 
-	js := jsonState{
-		Version:           stateVersion,
-		CompletedSessions: make([]jsonCompletedSession, 0, len(state.CompletedSessions)),
+	unlock, err := lockFile(stateFile + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	snapshotPos, snapshotOK, err := readSnapshotPosition(stateFile)
+	if err != nil {
+		return err
 	}
 
-	if state.CurrentSession != nil {
-		js.CurrentSession = &jsonSession{
-			Task:      state.CurrentSession.Task,
-			StartTime: state.CurrentSession.StartTime,
+	logFile := eventLogPath(stateFile)
+	_, last, validLen, err := readEventLog(logFile, snapshotPos)
+	if err != nil {
+		return err
+	}
+	logRev := last.rev
+
+	events := state.PendingEvents()
+	if len(events) == 0 && logRev == state.Revision() {
+		return nil
+	}
+
+	var latest *flowtime.FlowState
+	if logRev != state.Revision() {
+		if latest, err = s.load(stateFile); err != nil {
+			return fmt.Errorf("loading latest state: %w", err)
+		}
+		if err := state.Rebase(latest); err != nil {
+			return fmt.Errorf("%w: %w", ErrConflict, err)
 		}
 	}
 
-	if state.CurrentBreak != nil {
-		js.CurrentBreak = &jsonBreak{
-			StartTime:         state.CurrentBreak.StartTime,
-			SuggestedDuration: state.CurrentBreak.SuggestedDuration,
+	if err := applyRetention(state, s.opts.Retention, s.clock.Now()); err != nil {
+		return err
+	}
+	events = state.PendingEvents()
+
+	due, err := s.backupDue()
+	if err != nil {
+		return err
+	}
+	if due {
+		if latest == nil {
+			if latest, err = s.load(stateFile); err != nil {
+				return fmt.Errorf("loading latest state: %w", err)
+			}
+		}
+		if err := s.writeBackup(stateFile, latest); err != nil {
+			return err
 		}
 	}
 
-	for _, cs := range state.CompletedSessions {
-		jcs := jsonCompletedSession{
-			Task:         cs.Task,
-			FlowDuration: cs.FlowDuration,
-			CompletedAt:  cs.CompletedAt,
+	// A state file written before the event log existed becomes the log's baseline.
+	if logRev == 0 && snapshotOK {
+		existing, _, err := s.readSnapshot(stateFile)
+		if err != nil {
+			return err
 		}
-		if cs.BreakDuration != nil {
-			bd := *cs.BreakDuration
-			jcs.BreakDuration = &bd
+		if !isEmptyState(existing) {
+			events = append([]flowtime.Event{baselineEvent(existing, s.clock.Now())}, events...)
 		}
-		if cs.DeletedAt != nil {
-			t := *cs.DeletedAt
-			jcs.DeletedAt = &t
+	}
+
+	if compactsLog(events) {
+		return s.compact(stateFile, state, logRev+1)
+	}
+
+	encoded := make([]jsonEvent, 0, len(events))
+	for i, e := range events {
+		je, err := encodeEvent(logRev+uint64(i)+1, e)
+		if err != nil {
+			return err
 		}
-		js.CompletedSessions = append(js.CompletedSessions, jcs)
+		encoded = append(encoded, je)
+	}
+	offset, err := appendEventLog(logFile, encoded, validLen)
+	if err != nil {
+		return err
+	}
+	if len(encoded) > 0 {
+		last = logPosition{rev: logRev + uint64(len(encoded)), offset: offset}
+	}
+
+	state.SetRevision(last.rev)
+	state.ClearPendingEvents()
+
+	if !snapshotOK || snapshotPos.rev > logRev || last.rev-snapshotPos.rev >= snapshotInterval {
+		return writeSnapshot(stateFile, state, last)
+	}
+	return nil
+}
+
+// compact replaces the log with a single baseline event holding state, numbered
+// rev, and rewrites the snapshot to match. The log is replaced first: until the
+// snapshot is, loads find its position missing from the log and read the
+// baseline instead.
+func (s *JSONStore) compact(stateFile string, state *flowtime.FlowState, rev uint64) error {
+	je, err := encodeEvent(rev, baselineEvent(state, s.clock.Now()))
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(je)
+	if err != nil {
+		return fmt.Errorf("marshalling event %d: %w", rev, err)
 	}
+	if err := writeFileAtomic(eventLogPath(stateFile), append(data, '\n')); err != nil {
+		return fmt.Errorf("compacting event log: %w", err)
+	}
+
+	state.SetRevision(rev)
+	state.ClearPendingEvents()
+	return writeSnapshot(stateFile, state, logPosition{rev: rev})
+}
 
+// writeSnapshot replaces the state file with state, taken at pos in the log.
+func writeSnapshot(stateFile string, state *flowtime.FlowState, pos logPosition) error {
+	js := encodeState(state)
+	js.LogOffset = pos.offset
 	data, err := json.Marshal(js)
 	if err != nil {
 		return fmt.Errorf("marshalling state: %w", err)
 	}
+	return writeFileAtomic(stateFile, data)
+}
+
+// isEmptyState reports whether state holds no sessions at all.
+func isEmptyState(state *flowtime.FlowState) bool {
+	return state.CurrentSession == nil && len(state.CompletedSessions) == 0
+}
+
+// readSnapshotPosition returns the position in the log recorded in the state
+// file. ok is false if the file is missing or unreadable, meaning it should be
+// rewritten. Files written by a newer version of flower are refused so Save
+// never downgrades them.
+func readSnapshotPosition(path string) (pos logPosition, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return logPosition{}, false, nil
+		}
+		return logPosition{}, false, fmt.Errorf("reading state file: %w", err)
+	}
 
-	tempFile := stateFile + ".tmp"
-	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+	var header struct {
+		Version   int    `json:"version"`
+		Revision  uint64 `json:"revision"`
+		LogOffset int64  `json:"log_offset"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return logPosition{}, false, nil
+	}
+	if header.Version > stateVersion {
+		return logPosition{}, false, fmt.Errorf("%w: version %d (this build supports up to %d)", ErrNewerVersion, header.Version, stateVersion)
+	}
+	return logPosition{rev: header.Revision, offset: header.LogOffset}, true, nil
+}
+
+// writeFileAtomic replaces the file at path with data. The data is written to a
+// uniquely named temp file in the same directory and renamed over path, so readers
+// never observe a partial write and concurrent writers never share a temp file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp state file: %w", err)
+	}
+	tempFile := f.Name()
+
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(tempFile)
+		return fmt.Errorf("setting temp state file permissions: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tempFile)
 		return fmt.Errorf("writing temp state file: %w", err)
 	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tempFile)
+		return fmt.Errorf("syncing temp state file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("closing temp state file: %w", err)
+	}
 
-	if err := os.Rename(tempFile, stateFile); err != nil {
+	if err := os.Rename(tempFile, path); err != nil {
 		os.Remove(tempFile)
 		return fmt.Errorf("replacing state file: %w", err)
 	}
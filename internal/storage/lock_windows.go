@@ -0,0 +1,31 @@
+//go:build windows
+
+package storage
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile takes an exclusive advisory lock on path, creating it if needed and
+// blocking until any other holder releases it. The returned function releases the lock.
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	handle := windows.Handle(f.Fd())
+	overlapped := new(windows.Overlapped)
+	if err := windows.LockFileEx(handle, windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, overlapped); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking state file: %w", err)
+	}
+
+	return func() {
+		windows.UnlockFileEx(handle, 0, 1, 0, overlapped)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+// ErrNewerVersion is returned when the state file was written by a newer version of flower.
+var ErrNewerVersion = errors.New("state file was written by a newer version of flower")
+
+// jsonMigration upgrades a decoded state document by exactly one version, in place.
+type jsonMigration func(doc map[string]any) error
+
+// jsonMigrations holds the upgrade chain: jsonMigrations[i] takes a version i+1
+// document to version i+2. Bumping stateVersion requires appending a step here.
+var jsonMigrations = []jsonMigration{
+	migrateAddSessionIDs,
+	migrateAddIntervals,
+}
+
+// migrateAddSessionIDs (v1 -> v2) gives every completed session a stable ID.
+func migrateAddSessionIDs(doc map[string]any) error {
+	sessions, _ := doc["completed_sessions"].([]any)
+	for i, raw := range sessions {
+		cs, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("completed session %d is not an object", i)
+		}
+		if id, _ := cs["id"].(string); id != "" {
+			continue
+		}
+		task, _ := cs["task"].(string)
+		completedAt, _ := cs["completed_at"].(string)
+		at, err := time.Parse(time.RFC3339Nano, completedAt)
+		if err != nil {
+			return fmt.Errorf("completed session %d: parsing completed_at: %w", i, err)
+		}
+		cs["id"] = flowtime.LegacySessionID(i, task, at)
+	}
+	return nil
+}
+
+// migrateAddIntervals (v2 -> v3) turns every completed session into a work block
+// by reconstructing its flow and break intervals from the recorded durations.
+func migrateAddIntervals(doc map[string]any) error {
+	sessions, _ := doc["completed_sessions"].([]any)
+	for i, raw := range sessions {
+		cs, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("completed session %d is not an object", i)
+		}
+		if _, ok := cs["intervals"]; ok {
+			continue
+		}
+
+		completedAt, _ := cs["completed_at"].(string)
+		at, err := time.Parse(time.RFC3339Nano, completedAt)
+		if err != nil {
+			return fmt.Errorf("completed session %d: parsing completed_at: %w", i, err)
+		}
+		// Durations are serialized as integer nanoseconds.
+		flow, _ := cs["flow_duration"].(float64)
+		var breakDuration *time.Duration
+		if bd, ok := cs["break_duration"].(float64); ok {
+			d := time.Duration(bd)
+			breakDuration = &d
+		}
+
+		var intervals []any
+		for _, iv := range flowtime.LegacyIntervals(time.Duration(flow), breakDuration, at) {
+			intervals = append(intervals, map[string]any{
+				"kind":  string(iv.Kind),
+				"start": iv.Start.Format(time.RFC3339Nano),
+				"end":   iv.End.Format(time.RFC3339Nano),
+			})
+		}
+		cs["intervals"] = intervals
+	}
+	return nil
+}
+
+// migrateJSON upgrades the raw state document in data to the target version by
+// applying steps in order. It returns the upgraded document and the version it
+// started from. Documents already at the target version are returned unchanged.
+func migrateJSON(data []byte, target int, steps []jsonMigration) ([]byte, int, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, fmt.Errorf("parsing state file: %w", err)
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version > target {
+		return nil, version, fmt.Errorf("%w: version %d (this build supports up to %d)", ErrNewerVersion, version, target)
+	}
+	if version == target {
+		return data, version, nil
+	}
+	if target-1 > len(steps) {
+		return nil, version, fmt.Errorf("no migration path from version %d to %d", version, target)
+	}
+
+	for v := version; v < target; v++ {
+		if err := steps[v-1](doc); err != nil {
+			return nil, version, fmt.Errorf("migrating state from version %d to %d: %w", v, v+1, err)
+		}
+		doc["version"] = v + 1
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, version, fmt.Errorf("marshalling migrated state: %w", err)
+	}
+	return migrated, version, nil
+}
+
+// documentVersion reads the version field of a decoded state document.
+func documentVersion(doc map[string]any) (int, error) {
+	raw, ok := doc["version"]
+	if !ok {
+		return 0, errors.New("state file has no version")
+	}
+	// encoding/json decodes all numbers into float64.
+	v, ok := raw.(float64)
+	if !ok || v != float64(int(v)) || v < 1 {
+		return 0, fmt.Errorf("invalid state version %v", raw)
+	}
+	return int(v), nil
+}
+
+// upgradeStateFile migrates the state file at path to stateVersion if it is older.
+// The original file is copied to a timestamped backup alongside it before the
+// upgraded document replaces it. Returns the (possibly upgraded) file contents.
+func upgradeStateFile(path string, data []byte, now time.Time) ([]byte, error) {
+	migrated, from, err := migrateJSON(data, stateVersion, jsonMigrations)
+	if err != nil {
+		return nil, err
+	}
+	if from == stateVersion {
+		return data, nil
+	}
+
+	backupFile := fmt.Sprintf("%s.v%d-%s.bak", path, from, now.Format("20060102T150405"))
+	if err := os.WriteFile(backupFile, data, 0644); err != nil {
+		return nil, fmt.Errorf("backing up state file before migration: %w", err)
+	}
+
+	if err := writeFileAtomic(path, migrated); err != nil {
+		return nil, fmt.Errorf("writing migrated state file: %w", err)
+	}
+
+	return migrated, nil
+}
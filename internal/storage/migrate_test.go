@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+func TestMigrateJSON(t *testing.T) {
+	// Two fake steps: v1->v2 adds a field, v2->v3 renames it.
+	steps := []jsonMigration{
+		func(doc map[string]any) error {
+			doc["added"] = "v2"
+			return nil
+		},
+		func(doc map[string]any) error {
+			doc["renamed"] = doc["added"]
+			delete(doc, "added")
+			return nil
+		},
+	}
+
+	t.Run("applies every step in order", func(t *testing.T) {
+		got, from, err := migrateJSON([]byte(`{"version":1}`), 3, steps)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if from != 1 {
+			t.Errorf("from = %d, want 1", from)
+		}
+
+		var doc map[string]any
+		if err := json.Unmarshal(got, &doc); err != nil {
+			t.Fatalf("unmarshalling result: %v", err)
+		}
+		if doc["version"] != float64(3) {
+			t.Errorf("version = %v, want 3", doc["version"])
+		}
+		if doc["renamed"] != "v2" {
+			t.Errorf("renamed = %v, want %q", doc["renamed"], "v2")
+		}
+		if _, ok := doc["added"]; ok {
+			t.Error("expected added field to be removed by second step")
+		}
+	})
+
+	t.Run("starts from an intermediate version", func(t *testing.T) {
+		got, from, err := migrateJSON([]byte(`{"version":2,"added":"x"}`), 3, steps)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if from != 2 {
+			t.Errorf("from = %d, want 2", from)
+		}
+
+		var doc map[string]any
+		if err := json.Unmarshal(got, &doc); err != nil {
+			t.Fatalf("unmarshalling result: %v", err)
+		}
+		if doc["renamed"] != "x" {
+			t.Errorf("renamed = %v, want %q", doc["renamed"], "x")
+		}
+	})
+
+	t.Run("returns current version unchanged", func(t *testing.T) {
+		data := []byte(`{"version":3,"renamed":"x"}`)
+		got, from, err := migrateJSON(data, 3, steps)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if from != 3 {
+			t.Errorf("from = %d, want 3", from)
+		}
+		if string(got) != string(data) {
+			t.Errorf("data = %s, want %s", got, data)
+		}
+	})
+
+	t.Run("refuses newer version", func(t *testing.T) {
+		_, _, err := migrateJSON([]byte(`{"version":4}`), 3, steps)
+		if !errors.Is(err, ErrNewerVersion) {
+			t.Errorf("error = %v, want %v", err, ErrNewerVersion)
+		}
+	})
+
+	t.Run("errors on missing step", func(t *testing.T) {
+		_, _, err := migrateJSON([]byte(`{"version":1}`), 4, steps)
+		if err == nil {
+			t.Fatal("expected error when a step is missing")
+		}
+	})
+
+	t.Run("errors on missing or invalid version", func(t *testing.T) {
+		for _, data := range []string{`{}`, `{"version":0}`, `{"version":"1"}`, `{"version":1.5}`} {
+			if _, _, err := migrateJSON([]byte(data), 3, steps); err == nil {
+				t.Errorf("migrateJSON(%s): expected error", data)
+			}
+		}
+	})
+
+	t.Run("propagates step errors", func(t *testing.T) {
+		failing := []jsonMigration{func(map[string]any) error { return errors.New("boom") }}
+		if _, _, err := migrateJSON([]byte(`{"version":1}`), 2, failing); err == nil {
+			t.Fatal("expected error from failing step")
+		}
+	})
+}
+
+func TestMigrationChainMatchesStateVersion(t *testing.T) {
+	if len(jsonMigrations) != stateVersion-1 {
+		t.Errorf("have %d migrations, want %d for state version %d",
+			len(jsonMigrations), stateVersion-1, stateVersion)
+	}
+}
+
+func TestUpgradeStateFile(t *testing.T) {
+	now := time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	t.Run("leaves current file untouched", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "state.json")
+		data := []byte(`{"version":` + strconv.Itoa(stateVersion) + `}`)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := upgradeStateFile(path, data, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(got) != string(data) {
+			t.Errorf("data = %s, want %s", got, data)
+		}
+		assertFileCount(t, dir, 1)
+	})
+
+	t.Run("refuses newer file without touching it", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "state.json")
+		data := []byte(`{"version":` + strconv.Itoa(stateVersion+1) + `}`)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := upgradeStateFile(path, data, now)
+		if !errors.Is(err, ErrNewerVersion) {
+			t.Errorf("error = %v, want %v", err, ErrNewerVersion)
+		}
+		assertFileCount(t, dir, 1)
+	})
+
+	t.Run("backs up and upgrades older file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "state.json")
+		data := []byte(`{"version":1,"completed_sessions":[{"task":"a","flow_duration":0,"completed_at":"2025-06-15T09:00:00Z"}]}`)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := upgradeStateFile(path, data, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var js jsonState
+		if err := json.Unmarshal(got, &js); err != nil {
+			t.Fatalf("unmarshalling result: %v", err)
+		}
+		if js.Version != stateVersion {
+			t.Errorf("version = %d, want %d", js.Version, stateVersion)
+		}
+		if len(js.CompletedSessions) != 1 || js.CompletedSessions[0].ID == "" {
+			t.Errorf("expected upgraded session to have an id, got %+v", js.CompletedSessions)
+		}
+
+		backup, err := os.ReadFile(path + ".v1-20250615T100000.bak")
+		if err != nil {
+			t.Fatalf("reading backup: %v", err)
+		}
+		if string(backup) != string(data) {
+			t.Errorf("backup = %s, want original contents", backup)
+		}
+	})
+}
+
+func TestMigrateAddSessionIDs(t *testing.T) {
+	at := time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)
+	doc := map[string]any{
+		"version": float64(1),
+		"completed_sessions": []any{
+			map[string]any{"task": "a", "completed_at": at.Format(time.RFC3339Nano)},
+			map[string]any{"id": "keepme12", "task": "b", "completed_at": at.Format(time.RFC3339Nano)},
+		},
+	}
+
+	if err := migrateAddSessionIDs(doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sessions := doc["completed_sessions"].([]any)
+	first := sessions[0].(map[string]any)["id"]
+	if want := flowtime.LegacySessionID(0, "a", at); first != want {
+		t.Errorf("first id = %v, want %q", first, want)
+	}
+	if second := sessions[1].(map[string]any)["id"]; second != "keepme12" {
+		t.Errorf("second id = %v, want existing id kept", second)
+	}
+}
+
+func TestMigrateAddIntervals(t *testing.T) {
+	at := time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)
+	data := []byte(`{"version":2,"completed_sessions":[` +
+		`{"id":"aaaaaaaa","task":"a","flow_duration":1800000000000,"break_duration":300000000000,"completed_at":"2025-06-15T10:00:00Z"},` +
+		`{"id":"bbbbbbbb","task":"b","flow_duration":600000000000,"break_duration":null,"completed_at":"2025-06-15T10:00:00Z"}]}`)
+
+	migrated, _, err := migrateJSON(data, 3, jsonMigrations)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var js jsonState
+	if err := json.Unmarshal(migrated, &js); err != nil {
+		t.Fatalf("unmarshalling result: %v", err)
+	}
+
+	breakDuration := 5 * time.Minute
+	wants := [][]flowtime.Interval{
+		flowtime.LegacyIntervals(30*time.Minute, &breakDuration, at),
+		flowtime.LegacyIntervals(10*time.Minute, nil, at),
+	}
+	for i, want := range wants {
+		got := decodeIntervals(js.CompletedSessions[i].Intervals)
+		if len(got) != len(want) {
+			t.Fatalf("session %d: intervals = %+v, want %+v", i, got, want)
+		}
+		for j := range want {
+			if got[j].Kind != want[j].Kind || !got[j].Start.Equal(want[j].Start) || !got[j].End.Equal(want[j].End) {
+				t.Errorf("session %d interval %d = %+v, want %+v", i, j, got[j], want[j])
+			}
+		}
+	}
+}
+
+func assertFileCount(t *testing.T, dir string, want int) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != want {
+		t.Errorf("directory has %d files, want %d", len(entries), want)
+	}
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/adrg/xdg"
+)
+
+var ErrInvalidProfile = errors.New("invalid profile name")
+
+// DefaultProfile is the profile used when none is selected. Its state lives
+// directly in the flower data directory, where it was stored before profiles existed.
+const DefaultProfile = "default"
+
+// profileNamePattern restricts profile names to characters that are safe as a
+// single path component.
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Profile is a named, independent set of state files.
+type Profile struct {
+	Name string
+	Dir  string
+}
+
+// dataDir returns the flower data directory.
+func dataDir() string {
+	return filepath.Join(xdg.DataHome, "flower")
+}
+
+// profilesDir returns the directory holding the named profiles other than the default.
+func profilesDir() string {
+	return filepath.Join(dataDir(), "profiles")
+}
+
+// ValidateProfile returns an error if name cannot be used as a profile name.
+func ValidateProfile(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("%w %q: use letters, digits, '.', '_' and '-', starting with a letter or digit", ErrInvalidProfile, name)
+	}
+	return nil
+}
+
+// ProfileDir returns the directory holding the state of the named profile.
+func ProfileDir(name string) (string, error) {
+	if name == "" || name == DefaultProfile {
+		return dataDir(), nil
+	}
+	if err := ValidateProfile(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(profilesDir(), name), nil
+}
+
+// ListProfiles returns the default profile followed by every other profile that
+// has been used, sorted by name.
+func ListProfiles() ([]Profile, error) {
+	profiles := []Profile{{Name: DefaultProfile, Dir: dataDir()}}
+
+	entries, err := os.ReadDir(profilesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return nil, fmt.Errorf("reading profiles directory: %w", err)
+	}
+
+	var named []Profile
+	for _, entry := range entries {
+		if !entry.IsDir() || ValidateProfile(entry.Name()) != nil || entry.Name() == DefaultProfile {
+			continue
+		}
+		named = append(named, Profile{Name: entry.Name(), Dir: filepath.Join(profilesDir(), entry.Name())})
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].Name < named[j].Name })
+
+	return append(profiles, named...), nil
+}
+
+// statePath resolves the file a store keeps its state in: the explicitly
+// configured state file if any, otherwise fileName in the profile's directory.
+// The parent directory is created if needed.
+func statePath(opts Options, fileName string) (string, error) {
+	path := opts.StateFile
+	if path == "" {
+		dir, err := ProfileDir(opts.Profile)
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(dir, fileName)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating flower data directory %q: %w", dir, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+func TestValidateProfile(t *testing.T) {
+	for _, name := range []string{"work", "client-a", "v2.personal", "A_1"} {
+		if err := ValidateProfile(name); err != nil {
+			t.Errorf("ValidateProfile(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", ".", "..", "../x", "a/b", "-x", "with space"} {
+		if err := ValidateProfile(name); !errors.Is(err, ErrInvalidProfile) {
+			t.Errorf("ValidateProfile(%q) = %v, want %v", name, err, ErrInvalidProfile)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dataHome := xdg.DataHome
+	xdg.DataHome = t.TempDir()
+	t.Cleanup(func() { xdg.DataHome = dataHome })
+
+	clock := fixedClock{now: time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)}
+	save := func(opts Options, task string) {
+		t.Helper()
+		store, err := New(BackendJSON, clock, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, err := store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := state.StartSession(task); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Save(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func(opts Options) string {
+		t.Helper()
+		store, err := New(BackendJSON, clock, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, err := store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.CurrentSession == nil {
+			return ""
+		}
+		return state.CurrentSession.Task
+	}
+
+	explicit := filepath.Join(t.TempDir(), "elsewhere.json")
+	save(Options{}, "personal")
+	save(Options{Profile: "work"}, "work")
+	save(Options{StateFile: explicit}, "explicit")
+
+	if got := load(Options{Profile: DefaultProfile}); got != "personal" {
+		t.Errorf("default profile task = %q, want %q", got, "personal")
+	}
+	if got := load(Options{Profile: "work"}); got != "work" {
+		t.Errorf("work profile task = %q, want %q", got, "work")
+	}
+	if got := load(Options{Profile: "work", StateFile: explicit}); got != "explicit" {
+		t.Errorf("explicit state file task = %q, want %q", got, "explicit")
+	}
+
+	profiles, err := ListProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Profile{
+		{Name: DefaultProfile, Dir: filepath.Join(xdg.DataHome, "flower")},
+		{Name: "work", Dir: filepath.Join(xdg.DataHome, "flower", "profiles", "work")},
+	}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("profiles = %+v, want %+v", profiles, want)
+	}
+
+	if _, err := New(BackendJSON, clock, Options{Profile: "../escape"}); !errors.Is(err, ErrInvalidProfile) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidProfile)
+	}
+}
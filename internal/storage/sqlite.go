@@ -0,0 +1,1058 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+	"modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// sqliteMigrations holds the schema, one entry per version. The database's
+// user_version pragma records how many entries have been applied.
+var sqliteMigrations = []string{
+	`CREATE TABLE meta (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	CREATE TABLE events (
+		seq  INTEGER PRIMARY KEY,
+		kind TEXT    NOT NULL,
+		at   INTEGER NOT NULL,
+		data TEXT    NOT NULL
+	);
+	CREATE INDEX events_at ON events (at);
+	CREATE TABLE current_state (
+		id              INTEGER PRIMARY KEY CHECK (id = 1),
+		session_task    TEXT,
+		session_project TEXT,
+		session_tags    TEXT,
+		session_start   INTEGER,
+		break_start     INTEGER,
+		break_suggested INTEGER
+	);
+	INSERT INTO current_state (id) VALUES (1);
+	CREATE TABLE current_intervals (
+		seq   INTEGER PRIMARY KEY,
+		kind  TEXT    NOT NULL,
+		start INTEGER NOT NULL,
+		end   INTEGER NOT NULL
+	);
+	CREATE TABLE current_notes (
+		seq  INTEGER PRIMARY KEY,
+		at   INTEGER NOT NULL,
+		text TEXT    NOT NULL
+	);
+	CREATE TABLE current_interruptions (
+		seq    INTEGER PRIMARY KEY,
+		at     INTEGER NOT NULL,
+		kind   TEXT,
+		reason TEXT
+	);
+	CREATE TABLE sessions (
+		id            INTEGER PRIMARY KEY,
+		uid           TEXT    NOT NULL UNIQUE,
+		task          TEXT    NOT NULL,
+		project       TEXT,
+		tags          TEXT,
+		flow_duration INTEGER NOT NULL,
+		started_at    INTEGER NOT NULL,
+		completed_at  INTEGER NOT NULL,
+		deleted_at    INTEGER
+	);
+	CREATE INDEX sessions_started_at ON sessions (started_at);
+	CREATE INDEX sessions_completed_at ON sessions (completed_at);
+	CREATE INDEX sessions_flow_duration ON sessions (flow_duration);
+	CREATE INDEX sessions_project ON sessions (project);
+	CREATE TABLE breaks (
+		session_id INTEGER PRIMARY KEY REFERENCES sessions (id) ON DELETE CASCADE,
+		duration   INTEGER NOT NULL
+	);
+	CREATE TABLE intervals (
+		session_id INTEGER NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+		seq        INTEGER NOT NULL,
+		kind       TEXT    NOT NULL,
+		start      INTEGER NOT NULL,
+		end        INTEGER NOT NULL,
+		PRIMARY KEY (session_id, seq)
+	);
+	CREATE TABLE notes (
+		session_id INTEGER NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+		seq        INTEGER NOT NULL,
+		at         INTEGER NOT NULL,
+		text       TEXT    NOT NULL,
+		PRIMARY KEY (session_id, seq)
+	);
+	CREATE TABLE interruptions (
+		session_id INTEGER NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+		seq        INTEGER NOT NULL,
+		at         INTEGER NOT NULL,
+		kind       TEXT,
+		reason     TEXT,
+		PRIMARY KEY (session_id, seq)
+	);`,
+}
+
+func init() {
+	// REGEXP matches with Go's syntax, so task filters select the same sessions
+	// in SQL as SessionFilter.Match does.
+	var patterns sync.Map
+	sqlite.MustRegisterDeterministicScalarFunction("regexp", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		pattern, _ := args[0].(string)
+		s, _ := args[1].(string)
+		re, ok := patterns.Load(pattern)
+		if !ok {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			re, _ = patterns.LoadOrStore(pattern, compiled)
+		}
+		return re.(*regexp.Regexp).MatchString(s), nil
+	})
+}
+
+// SQLiteStore persists FlowState in an embedded SQLite database. The events
+// table is the source of truth; the remaining tables are a projection of the
+// state it produces, updated in the same transaction. Completed sessions are
+// stored one row each, keyed by session ID and read back in order of
+// completion, with their break totals, their flow and break intervals, notes
+// and interruptions in separate tables. The in-progress session and break live in a single
+// current_state row, with the session's finished intervals, notes and
+// interruptions in the matching current_ tables. Tags are stored as a
+// space-separated list, since they never contain whitespace.
+type SQLiteStore struct {
+	clock flowtime.Clock
+	opts  Options
+}
+
+// NewSQLiteStore creates a new SQLiteStore that uses the given clock for constructing FlowState.
+func NewSQLiteStore(clock flowtime.Clock) *SQLiteStore {
+	return &SQLiteStore{clock: clock}
+}
+
+// GetFilePath returns the path to the database file, creating the parent directory if needed.
+func (s *SQLiteStore) GetFilePath() (string, error) {
+	return statePath(s.opts, "state.db")
+}
+
+// open opens the database, applies any pending schema migrations and, the first
+// time the database is created, imports the state file written by JSONStore.
+func (s *SQLiteStore) open() (*sql.DB, error) {
+	dbFile, err := s.GetFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	// Immediate transactions take SQLite's write lock up front, so the revision
+	// check in write cannot race another process's save.
+	// Secure delete overwrites the content of deleted rows, so purged sessions
+	// do not linger in free pages of the file.
+	db, err := sql.Open("sqlite", dbFile+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=secure_delete(1)&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	if err := s.migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := s.importJSON(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// migrate brings the schema up to date with sqliteMigrations.
+func (s *SQLiteStore) migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("unsupported database version %d (expected at most %d)", version, len(sqliteMigrations))
+	}
+
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("beginning migration: %w", err)
+		}
+		if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrating database to version %d: %w", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("recording schema version %d: %w", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("committing migration to version %d: %w", version+1, err)
+		}
+	}
+	return nil
+}
+
+// importJSON copies the same profile's JSONStore state file into the database.
+// It runs at most once per database; the import is recorded in the meta table so
+// later loads never overwrite newer data with the stale JSON file. Databases at
+// an explicitly configured path have no JSON counterpart and start empty.
+func (s *SQLiteStore) importJSON(db *sql.DB) error {
+	var imported string
+	err := db.QueryRow("SELECT value FROM meta WHERE key = 'json_import'").Scan(&imported)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("checking json import: %w", err)
+	}
+
+	result := "none"
+	if s.opts.StateFile == "" {
+		if result, err = s.importProfileJSON(db); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Exec("INSERT INTO meta (key, value) VALUES ('json_import', ?)", result); err != nil {
+		return fmt.Errorf("recording json import: %w", err)
+	}
+	return nil
+}
+
+// importProfileJSON imports the profile's JSON state file, if there is one, and
+// returns its path, or "none".
+func (s *SQLiteStore) importProfileJSON(db *sql.DB) (string, error) {
+	jsonStore := NewJSONStore(s.clock)
+	jsonStore.opts = Options{Profile: s.opts.Profile}
+	jsonFile, err := jsonStore.GetFilePath()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(jsonFile); err != nil {
+		if os.IsNotExist(err) {
+			return "none", nil
+		}
+		return "", fmt.Errorf("checking state file: %w", err)
+	}
+
+	imported, err := jsonStore.Load()
+	if err != nil {
+		return "", fmt.Errorf("importing %q: %w", jsonFile, err)
+	}
+	state := flowtime.NewFlowState(s.clock)
+	if err := state.Replace(imported); err != nil {
+		return "", fmt.Errorf("importing %q: %w", jsonFile, err)
+	}
+	if err := s.save(db, state); err != nil {
+		return "", fmt.Errorf("importing %q: %w", jsonFile, err)
+	}
+	return jsonFile, nil
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Load reads the database and returns a FlowState. A new database yields an empty FlowState.
+func (s *SQLiteStore) Load() (*flowtime.FlowState, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := s.read(tx)
+	if err != nil {
+		return nil, err
+	}
+	state.SetBreakPolicy(s.opts.BreakPolicy)
+	state.SetLongBreakRule(s.opts.LongBreak)
+	return state, nil
+}
+
+// read loads the projected state and its revision.
+func (s *SQLiteStore) read(q querier) (*flowtime.FlowState, error) {
+	state := flowtime.NewFlowState(s.clock)
+
+	rev, err := readSQLiteRevision(q)
+	if err != nil {
+		return nil, err
+	}
+	state.SetRevision(rev)
+
+	var (
+		task           sql.NullString
+		project        sql.NullString
+		tags           sql.NullString
+		sessionStart   sql.NullInt64
+		breakStart     sql.NullInt64
+		breakSuggested sql.NullInt64
+	)
+	err = q.QueryRow(
+		"SELECT session_task, session_project, session_tags, session_start, break_start, break_suggested FROM current_state WHERE id = 1",
+	).Scan(&task, &project, &tags, &sessionStart, &breakStart, &breakSuggested)
+	if err != nil {
+		return nil, fmt.Errorf("reading current state: %w", err)
+	}
+
+	if task.Valid {
+		intervals, err := readIntervals(q, "SELECT 0, kind, start, end FROM current_intervals ORDER BY seq")
+		if err != nil {
+			return nil, err
+		}
+		notes, err := readNotes(q, "SELECT 0, at, text FROM current_notes ORDER BY seq")
+		if err != nil {
+			return nil, err
+		}
+		interruptions, err := readInterruptions(q, "SELECT 0, at, kind, reason FROM current_interruptions ORDER BY seq")
+		if err != nil {
+			return nil, err
+		}
+		state.CurrentSession = &flowtime.Session{
+			Task:          task.String,
+			Project:       project.String,
+			Tags:          splitTags(tags.String),
+			StartTime:     fromUnixNano(sessionStart.Int64),
+			Intervals:     intervals[0],
+			Notes:         notes[0],
+			Interruptions: interruptions[0],
+		}
+	}
+	if breakStart.Valid {
+		state.CurrentBreak = &flowtime.Break{
+			StartTime:         fromUnixNano(breakStart.Int64),
+			SuggestedDuration: time.Duration(breakSuggested.Int64),
+		}
+	}
+
+	sessions, err := readSessions(q, "TRUE")
+	if err != nil {
+		return nil, err
+	}
+	state.CompletedSessions = append(state.CompletedSessions, sessions...)
+
+	if err := readUndoHistory(q, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// readUndoHistory restores the undo and redo stacks kept in the meta table.
+func readUndoHistory(q querier, state *flowtime.FlowState) error {
+	var data string
+	err := q.QueryRow("SELECT value FROM meta WHERE key = 'undo'").Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading undo history: %w", err)
+	}
+
+	var h jsonUndoHistory
+	if err := json.Unmarshal([]byte(data), &h); err != nil {
+		return fmt.Errorf("parsing undo history: %w", err)
+	}
+	decodeUndoHistory(h, state)
+	return nil
+}
+
+// writeUndoHistory stores the state's undo and redo stacks in the meta table.
+func writeUndoHistory(tx *sql.Tx, state *flowtime.FlowState) error {
+	data, err := json.Marshal(encodeUndoHistory(state))
+	if err != nil {
+		return fmt.Errorf("marshalling undo history: %w", err)
+	}
+	_, err = tx.Exec("INSERT INTO meta (key, value) VALUES ('undo', ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value", string(data))
+	if err != nil {
+		return fmt.Errorf("writing undo history: %w", err)
+	}
+	return nil
+}
+
+// readSessions reads the completed sessions matching where, a condition on the
+// sessions table s, in order of completion, along with their intervals, notes
+// and interruptions.
+func readSessions(q querier, where string, args ...any) ([]flowtime.CompletedSession, error) {
+	rows, err := q.Query(`
+		SELECT s.id, s.uid, s.task, s.project, s.tags, s.flow_duration, s.completed_at, s.deleted_at, b.duration
+		FROM sessions s
+		LEFT JOIN breaks b ON b.session_id = s.id
+		WHERE `+where+`
+		ORDER BY s.completed_at, s.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("reading sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		sessions []flowtime.CompletedSession
+		rowIDs   []int64
+	)
+	for rows.Next() {
+		var (
+			cs            flowtime.CompletedSession
+			rowID         int64
+			project       sql.NullString
+			tags          sql.NullString
+			flowDuration  int64
+			completedAt   int64
+			deletedAt     sql.NullInt64
+			breakDuration sql.NullInt64
+		)
+		if err := rows.Scan(&rowID, &cs.ID, &cs.Task, &project, &tags, &flowDuration, &completedAt, &deletedAt, &breakDuration); err != nil {
+			return nil, fmt.Errorf("reading session: %w", err)
+		}
+		cs.Project = project.String
+		cs.Tags = splitTags(tags.String)
+		cs.FlowDuration = time.Duration(flowDuration)
+		cs.CompletedAt = fromUnixNano(completedAt)
+		if breakDuration.Valid {
+			bd := time.Duration(breakDuration.Int64)
+			cs.BreakDuration = &bd
+		}
+		if deletedAt.Valid {
+			t := fromUnixNano(deletedAt.Int64)
+			cs.DeletedAt = &t
+		}
+		sessions = append(sessions, cs)
+		rowIDs = append(rowIDs, rowID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading sessions: %w", err)
+	}
+
+	owners := "SELECT s.id FROM sessions s WHERE " + where
+	intervals, err := readIntervals(q, "SELECT session_id, kind, start, end FROM intervals WHERE session_id IN ("+owners+") ORDER BY session_id, seq", args...)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := readNotes(q, "SELECT session_id, at, text FROM notes WHERE session_id IN ("+owners+") ORDER BY session_id, seq", args...)
+	if err != nil {
+		return nil, err
+	}
+	interruptions, err := readInterruptions(q, "SELECT session_id, at, kind, reason FROM interruptions WHERE session_id IN ("+owners+") ORDER BY session_id, seq", args...)
+	if err != nil {
+		return nil, err
+	}
+	for i, rowID := range rowIDs {
+		cs := &sessions[i]
+		cs.Intervals = intervals[rowID]
+		cs.Notes = notes[rowID]
+		cs.Interruptions = interruptions[rowID]
+	}
+	return sessions, nil
+}
+
+// QuerySessions returns the active completed sessions matching filter, in order
+// of completion, without loading the rest of the state. The time, task and flow
+// criteria are evaluated by the database; the indexes on started_at and
+// flow_duration keep it from reading the other rows.
+func (s *SQLiteStore) QuerySessions(filter flowtime.SessionFilter) ([]flowtime.CompletedSession, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	where, args := sessionConditions(filter)
+	sessions, err := readSessions(tx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	// Project and tags are matched here: SQLite only folds the case of ASCII.
+	return filter.Apply(sessions), nil
+}
+
+// sessionConditions translates the time, task and flow criteria of filter into
+// a condition on the sessions table s that also excludes deleted sessions.
+func sessionConditions(filter flowtime.SessionFilter) (string, []any) {
+	conds := []string{"s.deleted_at IS NULL"}
+	var args []any
+	if !filter.Since.IsZero() {
+		conds = append(conds, "s.started_at >= ?")
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		conds = append(conds, "s.started_at < ?")
+		args = append(args, filter.Until.UnixNano())
+	}
+	if filter.Task != nil {
+		conds = append(conds, "s.task REGEXP ?")
+		args = append(args, filter.Task.String())
+	}
+	if filter.MinFlow > 0 {
+		conds = append(conds, "s.flow_duration >= ?")
+		args = append(args, int64(filter.MinFlow))
+	}
+	if filter.MaxFlow > 0 {
+		conds = append(conds, "s.flow_duration <= ?")
+		args = append(args, int64(filter.MaxFlow))
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// readIntervals runs a query selecting (owner, kind, start, end) rows in order
+// and groups the intervals by owner.
+func readIntervals(q querier, query string, args ...any) (map[int64][]flowtime.Interval, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("reading intervals: %w", err)
+	}
+	defer rows.Close()
+
+	intervals := make(map[int64][]flowtime.Interval)
+	for rows.Next() {
+		var (
+			owner      int64
+			kind       string
+			start, end int64
+		)
+		if err := rows.Scan(&owner, &kind, &start, &end); err != nil {
+			return nil, fmt.Errorf("reading interval: %w", err)
+		}
+		intervals[owner] = append(intervals[owner], flowtime.Interval{
+			Kind:  flowtime.IntervalKind(kind),
+			Start: fromUnixNano(start),
+			End:   fromUnixNano(end),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading intervals: %w", err)
+	}
+	return intervals, nil
+}
+
+// readNotes runs a query selecting (owner, at, text) rows in order and groups
+// the notes by owner.
+func readNotes(q querier, query string, args ...any) (map[int64][]flowtime.Note, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("reading notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := make(map[int64][]flowtime.Note)
+	for rows.Next() {
+		var (
+			owner int64
+			at    int64
+			text  string
+		)
+		if err := rows.Scan(&owner, &at, &text); err != nil {
+			return nil, fmt.Errorf("reading note: %w", err)
+		}
+		notes[owner] = append(notes[owner], flowtime.Note{At: fromUnixNano(at), Text: text})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading notes: %w", err)
+	}
+	return notes, nil
+}
+
+// readInterruptions runs a query selecting (owner, at, kind, reason) rows in
+// order and groups the interruptions by owner.
+func readInterruptions(q querier, query string, args ...any) (map[int64][]flowtime.Interruption, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("reading interruptions: %w", err)
+	}
+	defer rows.Close()
+
+	interruptions := make(map[int64][]flowtime.Interruption)
+	for rows.Next() {
+		var (
+			owner  int64
+			at     int64
+			kind   sql.NullString
+			reason sql.NullString
+		)
+		if err := rows.Scan(&owner, &at, &kind, &reason); err != nil {
+			return nil, fmt.Errorf("reading interruption: %w", err)
+		}
+		interruptions[owner] = append(interruptions[owner], flowtime.Interruption{
+			At:     fromUnixNano(at),
+			Kind:   flowtime.InterruptionKind(kind.String),
+			Reason: reason.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading interruptions: %w", err)
+	}
+	return interruptions, nil
+}
+
+// Events returns every event in the log, oldest first.
+func (s *SQLiteStore) Events() ([]flowtime.Event, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return s.readEvents(db)
+}
+
+// readEvents reads every event in the events table, oldest first.
+func (s *SQLiteStore) readEvents(q querier) ([]flowtime.Event, error) {
+	rows, err := q.Query("SELECT data FROM events ORDER BY seq")
+	if err != nil {
+		return nil, fmt.Errorf("reading events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []flowtime.Event
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("reading event: %w", err)
+		}
+		var je jsonEvent
+		if err := json.Unmarshal(data, &je); err != nil {
+			return nil, fmt.Errorf("parsing event: %w", err)
+		}
+		e, err := decodeEvent(je, s.clock)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading events: %w", err)
+	}
+	return events, nil
+}
+
+// Inspect returns the projected state, which Load returns as is. Load never
+// replays the events table, so it is replayed here in full to check that it
+// still produces a state.
+func (s *SQLiteStore) Inspect() (*flowtime.FlowState, []flowtime.Problem, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := s.read(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := s.readEvents(tx)
+	if err == nil {
+		_, err = flowtime.Replay(s.clock, events)
+	}
+	if err != nil {
+		return state, []flowtime.Problem{replayProblem(err)}, nil
+	}
+	return state, nil, nil
+}
+
+// Save appends the state's pending events to the events table and updates the
+// projection in a single transaction. Saving a purge compacts the table to a
+// baseline of the state after it instead. If another process has saved since the
+// state was loaded, the pending events are rebased onto the latest state;
+// ErrConflict is returned if they no longer apply.
+func (s *SQLiteStore) Save(state *flowtime.FlowState) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return s.save(db, state)
+}
+
+func (s *SQLiteStore) save(db *sql.DB, state *flowtime.FlowState) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rev, err := readSQLiteRevision(tx)
+	if err != nil {
+		return err
+	}
+
+	if rev != state.Revision() {
+		latest, err := s.read(tx)
+		if err != nil {
+			return fmt.Errorf("loading latest state: %w", err)
+		}
+		if err := state.Rebase(latest); err != nil {
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		}
+	}
+
+	if err := applyRetention(state, s.opts.Retention, s.clock.Now()); err != nil {
+		return err
+	}
+	// The projection is updated from the pending events even when the log is
+	// compacted, so the untouched sessions keep their rows.
+	pending := state.PendingEvents()
+	events := pending
+	compact := compactsLog(pending)
+	if compact {
+		events = []flowtime.Event{baselineEvent(state, s.clock.Now())}
+	}
+
+	for i, e := range events {
+		seq := rev + uint64(i) + 1
+		je, err := encodeEvent(seq, e)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(je)
+		if err != nil {
+			return fmt.Errorf("marshalling event %d: %w", seq, err)
+		}
+		_, err = tx.Exec("INSERT INTO events (seq, kind, at, data) VALUES (?, ?, ?, ?)",
+			seq, je.Kind, je.At.UnixNano(), string(data))
+		if err != nil {
+			return fmt.Errorf("writing event %d: %w", seq, err)
+		}
+	}
+
+	if compact {
+		// The baseline replaces every earlier event, some of which record the purged sessions.
+		if _, err := tx.Exec("DELETE FROM events WHERE seq <= ?", rev); err != nil {
+			return fmt.Errorf("compacting events: %w", err)
+		}
+	}
+
+	if err := writeProjection(tx, state, pending); err != nil {
+		return err
+	}
+	if err := writeUndoHistory(tx, state); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing state: %w", err)
+	}
+
+	state.SetRevision(rev + uint64(len(events)))
+	state.ClearPendingEvents()
+	return nil
+}
+
+// writeProjection updates the projected tables for events, which have already
+// been applied to state. The in-progress session is small and changes with
+// almost every event, so it is rewritten each time; completed sessions are only
+// written when an event touches them.
+func writeProjection(tx *sql.Tx, state *flowtime.FlowState, events []flowtime.Event) error {
+	if err := writeCurrent(tx, state); err != nil {
+		return err
+	}
+	return writeSessions(tx, state, events)
+}
+
+// writeCurrent replaces the in-progress session and break.
+func writeCurrent(tx *sql.Tx, state *flowtime.FlowState) error {
+	var (
+		task           sql.NullString
+		project        sql.NullString
+		tags           sql.NullString
+		sessionStart   sql.NullInt64
+		breakStart     sql.NullInt64
+		breakSuggested sql.NullInt64
+	)
+	if state.CurrentSession != nil {
+		task = sql.NullString{String: state.CurrentSession.Task, Valid: true}
+		project = nullString(state.CurrentSession.Project)
+		tags = nullString(joinTags(state.CurrentSession.Tags))
+		sessionStart = sql.NullInt64{Int64: state.CurrentSession.StartTime.UnixNano(), Valid: true}
+	}
+	if state.CurrentBreak != nil {
+		breakStart = sql.NullInt64{Int64: state.CurrentBreak.StartTime.UnixNano(), Valid: true}
+		breakSuggested = sql.NullInt64{Int64: int64(state.CurrentBreak.SuggestedDuration), Valid: true}
+	}
+	_, err := tx.Exec(
+		"UPDATE current_state SET session_task = ?, session_project = ?, session_tags = ?, session_start = ?, break_start = ?, break_suggested = ? WHERE id = 1",
+		task, project, tags, sessionStart, breakStart, breakSuggested,
+	)
+	if err != nil {
+		return fmt.Errorf("writing current state: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM current_intervals"); err != nil {
+		return fmt.Errorf("clearing current intervals: %w", err)
+	}
+	if state.CurrentSession != nil {
+		for seq, iv := range state.CurrentSession.Intervals {
+			_, err := tx.Exec("INSERT INTO current_intervals (seq, kind, start, end) VALUES (?, ?, ?, ?)",
+				seq, string(iv.Kind), iv.Start.UnixNano(), iv.End.UnixNano())
+			if err != nil {
+				return fmt.Errorf("writing current interval %d: %w", seq, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM current_notes"); err != nil {
+		return fmt.Errorf("clearing current notes: %w", err)
+	}
+	if state.CurrentSession != nil {
+		for seq, n := range state.CurrentSession.Notes {
+			_, err := tx.Exec("INSERT INTO current_notes (seq, at, text) VALUES (?, ?, ?)",
+				seq, n.At.UnixNano(), n.Text)
+			if err != nil {
+				return fmt.Errorf("writing current note %d: %w", seq, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM current_interruptions"); err != nil {
+		return fmt.Errorf("clearing current interruptions: %w", err)
+	}
+	if state.CurrentSession != nil {
+		for seq, in := range state.CurrentSession.Interruptions {
+			_, err := tx.Exec("INSERT INTO current_interruptions (seq, at, kind, reason) VALUES (?, ?, ?, ?)",
+				seq, in.At.UnixNano(), nullString(string(in.Kind)), nullString(in.Reason))
+			if err != nil {
+				return fmt.Errorf("writing current interruption %d: %w", seq, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeSessions updates the completed sessions for events. The sessions an event
+// names are copied from state, or removed if state no longer has them, while
+// deleting, restoring and purging in bulk take one statement each. Replacing
+// the state rewrites every session.
+func writeSessions(tx *sql.Tx, state *flowtime.FlowState, events []flowtime.Event) error {
+	if slices.ContainsFunc(events, replacesState) {
+		return rewriteSessions(tx, state)
+	}
+
+	var err error
+	touched := make(map[string]bool)
+	for _, e := range events {
+		switch e.Kind {
+		case flowtime.EventStop, flowtime.EventSwitch,
+			flowtime.EventDelete, flowtime.EventRestore, flowtime.EventEdit:
+			if e.SessionID != "" {
+				touched[e.SessionID] = true
+			}
+		case flowtime.EventAdd:
+			touched[e.Session.ID] = true
+		case flowtime.EventUndo, flowtime.EventRedo:
+			for _, cs := range e.Change.Sessions {
+				touched[cs.ID] = true
+			}
+			for _, id := range e.Change.Removed {
+				touched[id] = true
+			}
+		case flowtime.EventDeleteAll:
+			err = execSessions(tx, "UPDATE sessions SET deleted_at = ? WHERE deleted_at IS NULL", e.At.UnixNano())
+		case flowtime.EventRestoreSince:
+			err = execSessions(tx, "UPDATE sessions SET deleted_at = NULL WHERE deleted_at >= ?", e.Since.UnixNano())
+		case flowtime.EventPurge:
+			err = execSessions(tx, "DELETE FROM sessions WHERE deleted_at <= ?", e.Before.UnixNano())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Sessions touched after a bulk statement are written as they ended up.
+	for _, cs := range state.CompletedSessions {
+		if touched[cs.ID] {
+			if err := writeSession(tx, cs); err != nil {
+				return err
+			}
+			delete(touched, cs.ID)
+		}
+	}
+	for id := range touched {
+		if err := execSessions(tx, "DELETE FROM sessions WHERE uid = ?", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replacesState reports whether e replaces the whole state.
+func replacesState(e flowtime.Event) bool {
+	return e.Kind == flowtime.EventReplace
+}
+
+// rewriteSessions replaces every completed session with those in state.
+func rewriteSessions(tx *sql.Tx, state *flowtime.FlowState) error {
+	if err := execSessions(tx, "DELETE FROM sessions"); err != nil {
+		return err
+	}
+	for _, cs := range state.CompletedSessions {
+		if err := writeSession(tx, cs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execSessions runs a statement that changes session rows.
+func execSessions(tx *sql.Tx, query string, args ...any) error {
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("writing sessions: %w", err)
+	}
+	return nil
+}
+
+// writeSession inserts or updates the row of a completed session, matched by
+// its ID, along with its break, intervals, notes and interruptions.
+func writeSession(tx *sql.Tx, cs flowtime.CompletedSession) error {
+	var deletedAt sql.NullInt64
+	if cs.DeletedAt != nil {
+		deletedAt = sql.NullInt64{Int64: cs.DeletedAt.UnixNano(), Valid: true}
+	}
+	var id int64
+	err := tx.QueryRow(`
+		INSERT INTO sessions (uid, task, project, tags, flow_duration, started_at, completed_at, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uid) DO UPDATE SET
+			task = excluded.task,
+			project = excluded.project,
+			tags = excluded.tags,
+			flow_duration = excluded.flow_duration,
+			started_at = excluded.started_at,
+			completed_at = excluded.completed_at,
+			deleted_at = excluded.deleted_at
+		RETURNING id`,
+		cs.ID, cs.Task, nullString(cs.Project), nullString(joinTags(cs.Tags)), int64(cs.FlowDuration),
+		cs.Start().UnixNano(), cs.CompletedAt.UnixNano(), deletedAt,
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("writing session %s: %w", cs.ID, err)
+	}
+
+	if cs.BreakDuration != nil {
+		_, err = tx.Exec(`
+			INSERT INTO breaks (session_id, duration) VALUES (?, ?)
+			ON CONFLICT (session_id) DO UPDATE SET duration = excluded.duration`,
+			id, int64(*cs.BreakDuration),
+		)
+	} else {
+		_, err = tx.Exec("DELETE FROM breaks WHERE session_id = ?", id)
+	}
+	if err != nil {
+		return fmt.Errorf("writing break for session %s: %w", cs.ID, err)
+	}
+
+	if err := writeSessionIntervals(tx, id, cs.Intervals); err != nil {
+		return err
+	}
+	if err := writeSessionNotes(tx, id, cs.Notes); err != nil {
+		return err
+	}
+	return writeSessionInterruptions(tx, id, cs.Interruptions)
+}
+
+// writeSessionIntervals replaces the stored intervals of the session row id.
+func writeSessionIntervals(tx *sql.Tx, id int64, intervals []flowtime.Interval) error {
+	if _, err := tx.Exec("DELETE FROM intervals WHERE session_id = ?", id); err != nil {
+		return fmt.Errorf("clearing intervals for session %d: %w", id, err)
+	}
+	for seq, iv := range intervals {
+		_, err := tx.Exec("INSERT INTO intervals (session_id, seq, kind, start, end) VALUES (?, ?, ?, ?, ?)",
+			id, seq, string(iv.Kind), iv.Start.UnixNano(), iv.End.UnixNano())
+		if err != nil {
+			return fmt.Errorf("writing interval %d for session %d: %w", seq, id, err)
+		}
+	}
+	return nil
+}
+
+// writeSessionNotes replaces the stored notes of the session row id.
+func writeSessionNotes(tx *sql.Tx, id int64, notes []flowtime.Note) error {
+	if _, err := tx.Exec("DELETE FROM notes WHERE session_id = ?", id); err != nil {
+		return fmt.Errorf("clearing notes for session %d: %w", id, err)
+	}
+	for seq, n := range notes {
+		_, err := tx.Exec("INSERT INTO notes (session_id, seq, at, text) VALUES (?, ?, ?, ?)",
+			id, seq, n.At.UnixNano(), n.Text)
+		if err != nil {
+			return fmt.Errorf("writing note %d for session %d: %w", seq, id, err)
+		}
+	}
+	return nil
+}
+
+// writeSessionInterruptions replaces the stored interruptions of the session row id.
+func writeSessionInterruptions(tx *sql.Tx, id int64, interruptions []flowtime.Interruption) error {
+	if _, err := tx.Exec("DELETE FROM interruptions WHERE session_id = ?", id); err != nil {
+		return fmt.Errorf("clearing interruptions for session %d: %w", id, err)
+	}
+	for seq, in := range interruptions {
+		_, err := tx.Exec("INSERT INTO interruptions (session_id, seq, at, kind, reason) VALUES (?, ?, ?, ?, ?)",
+			id, seq, in.At.UnixNano(), nullString(string(in.Kind)), nullString(in.Reason))
+		if err != nil {
+			return fmt.Errorf("writing interruption %d for session %d: %w", seq, id, err)
+		}
+	}
+	return nil
+}
+
+// readSQLiteRevision returns the number of stored events, which is the revision
+// of the projected state.
+func readSQLiteRevision(q querier) (uint64, error) {
+	var rev uint64
+	if err := q.QueryRow("SELECT COALESCE(MAX(seq), 0) FROM events").Scan(&rev); err != nil {
+		return 0, fmt.Errorf("reading revision: %w", err)
+	}
+	return rev, nil
+}
+
+func fromUnixNano(n int64) time.Time {
+	return time.Unix(0, n)
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func joinTags(tags []string) string {
+	return strings.Join(tags, " ")
+}
+
+func splitTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Fields(s)
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"database/sql"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+func TestSQLiteStoreImportsJSON(t *testing.T) {
+	t.Run("imports a version 1 state file", func(t *testing.T) {
+		store := newTestStore(t, BackendSQLite)
+		jsonFile, err := NewJSONStore(fixedClock{}).GetFilePath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := `{
+			"version": 1,
+			"current_session": {"task": "review", "start_time": "2025-06-15T09:40:00Z"},
+			"current_break": null,
+			"completed_sessions": [
+				{"task": "write code", "flow_duration": 3000000000000, "break_duration": 480000000000, "completed_at": "2025-06-15T08:58:00Z"},
+				{"task": "email", "flow_duration": 900000000000, "break_duration": null, "completed_at": "2025-06-15T09:30:00Z"}
+			]
+		}`
+		if err := os.WriteFile(jsonFile, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		state, err := store.Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if state.CurrentSession == nil || state.CurrentSession.Task != "review" {
+			t.Errorf("current session = %+v, want task %q", state.CurrentSession, "review")
+		}
+		if len(state.CompletedSessions) != 2 {
+			t.Fatalf("completed sessions = %d, want 2", len(state.CompletedSessions))
+		}
+		cs := state.CompletedSessions[0]
+		if cs.ID == "" || cs.Task != "write code" || cs.BreakDuration == nil || *cs.BreakDuration != 8*time.Minute {
+			t.Errorf("first session = %+v, want %q with an ID and an 8m break", cs, "write code")
+		}
+		if want := time.Date(2025, 6, 15, 8, 0, 0, 0, time.UTC); !cs.Start().Equal(want) {
+			t.Errorf("first session start = %v, want %v from its durations", cs.Start(), want)
+		}
+
+		events, err := store.(EventLog).Events()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Kind != flowtime.EventReplace {
+			t.Errorf("events = %+v, want the import as a single replace", events)
+		}
+
+		// The import happens once, so the stale file never overwrites newer data.
+		_ = state.CancelSession()
+		if err := store.Save(state); err != nil {
+			t.Fatal(err)
+		}
+		state, err = store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.CurrentSession != nil {
+			t.Errorf("current session = %+v, want the cancel kept over the state file", state.CurrentSession)
+		}
+	})
+
+	t.Run("imports the profile's state file", func(t *testing.T) {
+		store := newTestStoreWithOptions(t, BackendSQLite, Options{Profile: "work"})
+		clock := fixedClock{now: time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)}
+		for profile, task := range map[string]string{DefaultProfile: "personal", "work": "standup"} {
+			jsonStore, err := New(BackendJSON, clock, Options{Profile: profile})
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, _ := jsonStore.Load()
+			if _, err := state.AddSession(task, clock.now.Add(-time.Hour), clock.now.Add(-30*time.Minute), 0); err != nil {
+				t.Fatal(err)
+			}
+			if err := jsonStore.Save(state); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		state, err := store.Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(state.CompletedSessions) != 1 || state.CompletedSessions[0].Task != "standup" {
+			t.Errorf("completed sessions = %+v, want only the work profile's", state.CompletedSessions)
+		}
+	})
+
+	t.Run("starts empty at an explicit path", func(t *testing.T) {
+		dir := t.TempDir()
+		store := newTestStoreWithOptions(t, BackendSQLite, Options{StateFile: dir + "/flower.db"})
+		jsonFile, err := NewJSONStore(fixedClock{}).GetFilePath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(jsonFile, []byte(`{"version":1,"completed_sessions":[{"task":"a","flow_duration":0,"completed_at":"2025-06-15T09:00:00Z"}]}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		state, err := store.Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(state.CompletedSessions) != 0 {
+			t.Errorf("completed sessions = %+v, want none", state.CompletedSessions)
+		}
+	})
+}
+
+func TestSQLiteStoreKeysSessionsByID(t *testing.T) {
+	store := newTestStore(t, BackendSQLite)
+	now := time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)
+	state, _ := store.Load()
+	for i, task := range []string{"first", "second", "third"} {
+		from := now.Add(time.Duration(i-3) * time.Hour)
+		if _, err := state.AddSession(task, from, from.Add(30*time.Minute), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+	before := sessionRows(t, store)
+
+	// Purging the first session leaves the other rows where they were.
+	purged := state.CompletedSessions[0].ID
+	_ = state.DeleteSession(0)
+	if _, err := state.PurgeDeleted(now); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+	delete(before, purged)
+	if after := sessionRows(t, store); !reflect.DeepEqual(after, before) {
+		t.Errorf("rows = %v, want %v", after, before)
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids(got.CompletedSessions), ids(state.CompletedSessions)) {
+		t.Errorf("loaded sessions = %v, want %v", ids(got.CompletedSessions), ids(state.CompletedSessions))
+	}
+}
+
+func TestSQLiteStoreQuerySessions(t *testing.T) {
+	store := newTestStore(t, BackendSQLite)
+	now := time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)
+	state, _ := store.Load()
+	sessions := []struct {
+		task      string
+		hoursAgo  int
+		flow      time.Duration
+		breakTime time.Duration
+	}{
+		{"Fix login +auth @review", 30, 50 * time.Minute, 10 * time.Minute},
+		{"email", 26, 15 * time.Minute, 0},
+		{"fix signup +auth", 5, 90 * time.Minute, 0},
+		{"Write docs @review", 3, 25 * time.Minute, 5 * time.Minute},
+	}
+	for _, s := range sessions {
+		from := now.Add(-time.Duration(s.hoursAgo) * time.Hour)
+		if _, err := state.AddSession(s.task, from, from.Add(s.flow+s.breakTime), s.breakTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = state.DeleteSession(1)
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+
+	fix, _ := flowtime.TaskPattern("fix")
+	anchored, _ := flowtime.TaskPattern("/^Fix/")
+	filters := map[string]flowtime.SessionFilter{
+		"everything":  {},
+		"since":       {Since: now.Add(-24 * time.Hour)},
+		"until":       {Until: now.Add(-4 * time.Hour)},
+		"task":        {Task: fix},
+		"task regexp": {Task: anchored},
+		"min flow":    {MinFlow: 30 * time.Minute},
+		"max flow":    {MaxFlow: 30 * time.Minute},
+		"project":     {Project: "AUTH", Since: now.Add(-24 * time.Hour)},
+		"tags":        {Tags: []string{"review"}, MaxFlow: time.Hour},
+	}
+	querier := store.(SessionQuerier)
+	for name, filter := range filters {
+		t.Run(name, func(t *testing.T) {
+			got, err := querier.QuerySessions(filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := filter.Apply(state.ActiveSessions()); !reflect.DeepEqual(ids(got), ids(want)) {
+				t.Errorf("sessions = %v, want %v", ids(got), ids(want))
+			}
+		})
+	}
+}
+
+// sessionRows returns the row ID of every session in the store's database, by session ID.
+func sessionRows(t *testing.T, store FileStore) map[string]int64 {
+	t.Helper()
+	path, err := store.GetFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, uid FROM sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	ids := make(map[string]int64)
+	for rows.Next() {
+		var (
+			id  int64
+			uid string
+		)
+		if err := rows.Scan(&id, &uid); err != nil {
+			t.Fatal(err)
+		}
+		ids[uid] = id
+	}
+	return ids
+}
+
+func ids(sessions []flowtime.CompletedSession) []string {
+	ids := make([]string, len(sessions))
+	for i, cs := range sessions {
+		ids[i] = cs.ID
+	}
+	return ids
+}
@@ -1,9 +1,125 @@
 package storage
 
-import "github.com/Broderick-Westrope/flower/internal/flowtime"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 
-// Store abstracts state persistence.
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+)
+
+// ErrConflict is returned by Save when the stored state has changed since the
+// FlowState being saved was loaded, e.g. because another flower process saved first.
+var ErrConflict = errors.New("state was changed by another process")
+
+// Store abstracts state persistence. Save fails with ErrConflict rather than
+// overwriting changes it did not load.
 type Store interface {
 	Load() (*flowtime.FlowState, error)
 	Save(state *flowtime.FlowState) error
 }
+
+// SessionQuerier is implemented by stores that can select completed sessions
+// without loading the whole state.
+type SessionQuerier interface {
+	// QuerySessions returns the active completed sessions matching filter, in
+	// the order of FlowState.ActiveSessions.
+	QuerySessions(filter flowtime.SessionFilter) ([]flowtime.CompletedSession, error)
+}
+
+// Inspector is implemented by stores that can return their state as stored,
+// without the normalization Load applies, for flower doctor to check. Failures
+// to read or replay the event log are returned as problems rather than errors,
+// along with the state as far as it could be rebuilt.
+type Inspector interface {
+	Inspect() (*flowtime.FlowState, []flowtime.Problem, error)
+}
+
+// FileStore is a Store persisted to a single file on disk.
+type FileStore interface {
+	Store
+	GetFilePath() (string, error)
+}
+
+// Supported storage backends.
+const (
+	BackendJSON   = "json"
+	BackendSQLite = "sqlite"
+)
+
+// Options configures behaviour shared by all backends.
+type Options struct {
+	// Profile selects the named set of state files to use. Empty means DefaultProfile.
+	Profile string
+	// StateFile, if set, is used as the state file instead of the profile's.
+	StateFile string
+	// Retention is how long deleted sessions are kept; older ones are purged
+	// whenever the state is saved. Zero keeps them forever.
+	Retention time.Duration
+	// BreakPolicy is set on every loaded state to suggest break durations. Nil
+	// means flowtime.DefaultBreakPolicy.
+	BreakPolicy flowtime.BreakPolicy
+	// LongBreak is set on every loaded state to suggest long breaks.
+	LongBreak flowtime.LongBreakRule
+}
+
+// New returns the FileStore for the named backend.
+func New(backend string, clock flowtime.Clock, opts Options) (FileStore, error) {
+	if opts.StateFile == "" {
+		if _, err := ProfileDir(opts.Profile); err != nil {
+			return nil, err
+		}
+	}
+
+	switch backend {
+	case BackendJSON:
+		store := NewJSONStore(clock)
+		store.opts = opts
+		return store, nil
+	case BackendSQLite:
+		store := NewSQLiteStore(clock)
+		store.opts = opts
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// applyRetention purges the sessions that were deleted longer ago than the
+// retention period allows, recording the purge as a pending event.
+func applyRetention(state *flowtime.FlowState, retention time.Duration, now time.Time) error {
+	if retention <= 0 {
+		return nil
+	}
+	cutoff := now.Add(-retention)
+	if len(state.PurgeableSessions(cutoff)) == 0 {
+		return nil
+	}
+	if _, err := state.PurgeDeleted(cutoff); err != nil {
+		return fmt.Errorf("applying retention policy: %w", err)
+	}
+	return nil
+}
+
+// compactsLog reports whether saving events must compact the log: a purge
+// removes sessions for good, so the events that recorded them are replaced by a
+// baseline of the state after the purge.
+func compactsLog(events []flowtime.Event) bool {
+	return slices.ContainsFunc(events, func(e flowtime.Event) bool {
+		return e.Kind == flowtime.EventPurge
+	})
+}
+
+// baselineEvent returns the replace event that a compacted log starts from.
+func baselineEvent(state *flowtime.FlowState, at time.Time) flowtime.Event {
+	return flowtime.Event{Kind: flowtime.EventReplace, At: at, Snapshot: state}
+}
+
+// replayProblem reports an event log that cannot be read or replayed.
+func replayProblem(err error) flowtime.Problem {
+	return flowtime.Problem{
+		Code:    flowtime.ProblemReplayFailed,
+		Message: fmt.Sprintf("the event log cannot be replayed: %v", err),
+	}
+}
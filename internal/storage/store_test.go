@@ -0,0 +1,764 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+	"github.com/adrg/xdg"
+)
+
+type fixedClock struct{ now time.Time }
+
+func (c fixedClock) Now() time.Time { return c.now }
+
+var backends = []string{BackendJSON, BackendSQLite}
+
+// newTestStore returns a store for backend rooted in a fresh data directory.
+func newTestStore(t *testing.T, backend string) FileStore {
+	t.Helper()
+	return newTestStoreWithOptions(t, backend, Options{})
+}
+
+// newTestStoreWithOptions is newTestStore with non-default options.
+func newTestStoreWithOptions(t *testing.T, backend string, opts Options) FileStore {
+	t.Helper()
+
+	dataHome := xdg.DataHome
+	xdg.DataHome = t.TempDir()
+	t.Cleanup(func() { xdg.DataHome = dataHome })
+
+	store, err := New(backend, fixedClock{now: time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestStoreConflict(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			store := newTestStore(t, backend)
+
+			first, err := store.Load()
+			if err != nil {
+				t.Fatalf("loading first state: %v", err)
+			}
+			second, err := store.Load()
+			if err != nil {
+				t.Fatalf("loading second state: %v", err)
+			}
+
+			if err := first.StartSession("first"); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(first); err != nil {
+				t.Fatalf("saving first state: %v", err)
+			}
+
+			if err := second.StartSession("second"); err != nil {
+				t.Fatal(err)
+			}
+			err = store.Save(second)
+			if !errors.Is(err, ErrConflict) {
+				t.Fatalf("error = %v, want %v", err, ErrConflict)
+			}
+
+			// The first save must survive, and saving again from it must still work.
+			got, err := store.Load()
+			if err != nil {
+				t.Fatalf("reloading: %v", err)
+			}
+			if got.CurrentSession == nil || got.CurrentSession.Task != "first" {
+				t.Fatalf("current session = %+v, want task %q", got.CurrentSession, "first")
+			}
+			if _, err := first.Stop(); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(first); err != nil {
+				t.Errorf("saving after own save: %v", err)
+			}
+		})
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			store := newTestStore(t, backend)
+
+			state, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := state.StartSession("write code +flower @deep"); err != nil {
+				t.Fatal(err)
+			}
+			if err := state.Interrupt(flowtime.InterruptionExternal, "call"); err != nil {
+				t.Fatal(err)
+			}
+			if err := state.TakeBreak(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := state.StopWithNote("done"); err != nil {
+				t.Fatal(err)
+			}
+			if err := state.StartSession("review +flower"); err != nil {
+				t.Fatal(err)
+			}
+			if err := state.AddNote("looks good"); err != nil {
+				t.Fatal(err)
+			}
+			if err := state.Interrupt("", ""); err != nil {
+				t.Fatal(err)
+			}
+			if err := state.TakeBreak(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := state.Resume(); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.CurrentSession == nil || got.CurrentSession.Task != "review" {
+				t.Fatalf("current session = %+v, want task %q", got.CurrentSession, "review")
+			}
+			if got.CurrentSession.Project != "flower" || len(got.CurrentSession.Tags) != 0 {
+				t.Errorf("current session labels = %q %v, want project %q and no tags", got.CurrentSession.Project, got.CurrentSession.Tags, "flower")
+			}
+			if len(got.CurrentSession.Notes) != 1 || got.CurrentSession.Notes[0].Text != "looks good" {
+				t.Errorf("current session notes = %+v, want %q", got.CurrentSession.Notes, "looks good")
+			}
+			if in := got.CurrentSession.Interruptions; len(in) != 1 || in[0].Kind != "" || in[0].Reason != "" {
+				t.Errorf("current session interruptions = %+v, want one without kind or reason", got.CurrentSession.Interruptions)
+			}
+			if len(got.CurrentSession.Intervals) != 2 {
+				t.Errorf("current session intervals = %+v, want flow and break", got.CurrentSession.Intervals)
+			}
+			if len(got.CompletedSessions) != 1 {
+				t.Fatalf("completed sessions = %d, want 1", len(got.CompletedSessions))
+			}
+			cs := got.CompletedSessions[0]
+			if cs.Task != "write code" || cs.BreakDuration == nil {
+				t.Errorf("completed session = %+v, want task %q with a break", cs, "write code")
+			}
+			if cs.Project != "flower" || !reflect.DeepEqual(cs.Tags, []string{"deep"}) {
+				t.Errorf("completed session labels = %q %v, want %q [deep]", cs.Project, cs.Tags, "flower")
+			}
+			if len(cs.Notes) != 1 || cs.Notes[0].Text != "done" || !cs.Notes[0].At.Equal(cs.CompletedAt) {
+				t.Errorf("completed session notes = %+v, want %q at completion", cs.Notes, "done")
+			}
+			if len(cs.Interruptions) != 1 || cs.Interruptions[0].Kind != flowtime.InterruptionExternal || cs.Interruptions[0].Reason != "call" {
+				t.Errorf("completed session interruptions = %+v, want an external %q", cs.Interruptions, "call")
+			}
+			if len(cs.Intervals) != 2 || cs.Intervals[1].Kind != flowtime.IntervalBreak {
+				t.Errorf("completed session intervals = %+v, want flow then break", cs.Intervals)
+			}
+			if !cs.CompletedAt.Equal(state.CompletedSessions[0].CompletedAt) {
+				t.Errorf("completed at = %v, want %v", cs.CompletedAt, state.CompletedSessions[0].CompletedAt)
+			}
+		})
+	}
+}
+
+func TestStoreAppliesRetention(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			store := newTestStoreWithOptions(t, backend, Options{Retention: 30 * 24 * time.Hour})
+
+			now := time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)
+			old := now.AddDate(0, 0, -31)
+			recent := now.AddDate(0, 0, -1)
+			snapshot := flowtime.NewFlowState(fixedClock{now: now})
+			snapshot.CompletedSessions = []flowtime.CompletedSession{
+				{ID: "aaaaaaaa", Task: "expired", CompletedAt: old, DeletedAt: &old},
+				{ID: "bbbbbbbb", Task: "active", CompletedAt: old},
+				{ID: "cccccccc", Task: "recently deleted", CompletedAt: old, DeletedAt: &recent},
+			}
+
+			state, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := state.Replace(snapshot); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, cs := range got.CompletedSessions {
+				ids = append(ids, cs.ID)
+			}
+			if want := []string{"bbbbbbbb", "cccccccc"}; !reflect.DeepEqual(ids, want) {
+				t.Errorf("sessions = %v, want %v", ids, want)
+			}
+		})
+	}
+}
+
+func TestStorePurgeCompactsLog(t *testing.T) {
+	now := time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)
+	purges := map[string]func(t *testing.T, backend string, store FileStore){
+		"purge": func(t *testing.T, _ string, store FileStore) {
+			state, _ := store.Load()
+			if _, err := state.PurgeDeleted(now); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"retention": func(t *testing.T, backend string, _ FileStore) {
+			later := fixedClock{now: now.AddDate(0, 0, 31)}
+			store, err := New(backend, later, Options{Retention: 30 * 24 * time.Hour})
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, _ := store.Load()
+			_ = state.StartSession("review")
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+
+	for _, backend := range backends {
+		for name, purge := range purges {
+			t.Run(backend+"/"+name, func(t *testing.T) {
+				store := newTestStore(t, backend)
+				state, _ := store.Load()
+				for i, task := range []string{"secret plans", "write code"} {
+					from := now.Add(time.Duration(i-3) * time.Hour)
+					if _, err := state.AddSession(task, from, from.Add(30*time.Minute), 0); err != nil {
+						t.Fatal(err)
+					}
+				}
+				_ = state.DeleteSession(0)
+				if err := store.Save(state); err != nil {
+					t.Fatal(err)
+				}
+
+				purge(t, backend, store)
+
+				stateFile, _ := store.GetFilePath()
+				for _, path := range []string{stateFile, eventLogPath(stateFile)} {
+					data, err := os.ReadFile(path)
+					if err != nil && !os.IsNotExist(err) {
+						t.Fatal(err)
+					}
+					if strings.Contains(string(data), "secret plans") {
+						t.Errorf("%s still holds the purged session", filepath.Base(path))
+					}
+				}
+
+				got, err := store.Load()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got.CompletedSessions) != 1 || got.CompletedSessions[0].Task != "write code" {
+					t.Errorf("completed sessions = %+v, want only %q", got.CompletedSessions, "write code")
+				}
+				events, err := store.(EventLog).Events()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(events) != 1 || events[0].Kind != flowtime.EventReplace {
+					t.Errorf("events = %+v, want the baseline only", events)
+				}
+			})
+		}
+	}
+}
+
+func TestStoreInspectReportsReplayFailures(t *testing.T) {
+	// A second start while the first session is running cannot be applied.
+	const broken = `{"seq":2,"kind":"start","at":"2025-06-15T10:00:00Z","task":"review"}`
+	corrupt := map[string]func(t *testing.T, path string){
+		BackendJSON: func(t *testing.T, path string) {
+			f, err := os.OpenFile(eventLogPath(path), os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, err := f.WriteString(broken + "\n"); err != nil {
+				t.Fatal(err)
+			}
+		},
+		BackendSQLite: func(t *testing.T, path string) {
+			db, err := sql.Open("sqlite", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if _, err := db.Exec("INSERT INTO events (seq, kind, at, data) VALUES (2, 'start', 0, ?)", broken); err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			store := newTestStore(t, backend)
+			state, _ := store.Load()
+			_ = state.StartSession("write code")
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+			path, _ := store.GetFilePath()
+			corrupt[backend](t, path)
+
+			got, problems, err := store.(Inspector).Inspect()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(problems) != 1 || problems[0].Code != flowtime.ProblemReplayFailed {
+				t.Errorf("problems = %+v, want a replay failure", problems)
+			}
+			if got.CurrentSession == nil || got.CurrentSession.Task != "write code" {
+				t.Errorf("current session = %+v, want the state before the failure", got.CurrentSession)
+			}
+		})
+	}
+}
+
+func TestJSONStoreInspectKeepsStoredState(t *testing.T) {
+	store := newTestStore(t, BackendJSON)
+	stateFile, _ := store.GetFilePath()
+	data := `{"version":3,"revision":0,"current_session":null,"current_break":null,"completed_sessions":[` +
+		`{"task":"write code","intervals":null,"flow_duration":3000000000000,"break_duration":null,"completed_at":"2025-06-15T09:00:00Z"}]}`
+	if err := os.WriteFile(stateFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state, problems, err := store.(Inspector).Inspect()
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Inspect() = %v, %v; want no problems", problems, err)
+	}
+	if cs := state.CompletedSessions[0]; cs.ID != "" || cs.Intervals != nil {
+		t.Errorf("session = %+v, want it without the ID and intervals Load assigns", cs)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs := loaded.CompletedSessions[0]; cs.ID == "" || len(cs.Intervals) == 0 {
+		t.Errorf("loaded session = %+v, want an ID and intervals", cs)
+	}
+}
+
+func TestStoreSetsBreakPolicy(t *testing.T) {
+	policy := flowtime.FixedPolicy{Duration: 12 * time.Minute}
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			store := newTestStoreWithOptions(t, backend, Options{BreakPolicy: policy})
+
+			state, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := state.BreakPolicy(); got != policy {
+				t.Errorf("break policy = %+v, want %+v", got, policy)
+			}
+		})
+	}
+}
+
+func TestStoreMergesConcurrentSaves(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			store := newTestStore(t, backend)
+
+			setup, _ := store.Load()
+			_ = setup.StartSession("old task")
+			_, _ = setup.Stop()
+			_ = setup.StartSession("write code")
+			if err := store.Save(setup); err != nil {
+				t.Fatal(err)
+			}
+
+			first, _ := store.Load()
+			second, _ := store.Load()
+
+			_ = first.DeleteSession(0)
+			if err := store.Save(first); err != nil {
+				t.Fatalf("saving first state: %v", err)
+			}
+
+			_ = second.TakeBreak()
+			if err := store.Save(second); err != nil {
+				t.Fatalf("saving second state: %v", err)
+			}
+			if second.CompletedSessions[0].DeletedAt == nil {
+				t.Error("expected saved state to include the first process's delete")
+			}
+
+			got, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.CurrentBreak == nil || got.CompletedSessions[0].DeletedAt == nil {
+				t.Errorf("loaded state = %+v, want both changes", got)
+			}
+		})
+	}
+}
+
+func TestStoreEvents(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			store := newTestStore(t, backend)
+
+			state, _ := store.Load()
+			_ = state.StartSession("write code")
+			_ = state.TakeBreak()
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+			_, _ = state.Stop()
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+
+			events, err := store.(EventLog).Events()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var kinds []flowtime.EventKind
+			for _, e := range events {
+				kinds = append(kinds, e.Kind)
+			}
+			want := []flowtime.EventKind{flowtime.EventStart, flowtime.EventBreak, flowtime.EventStop}
+			if !reflect.DeepEqual(kinds, want) {
+				t.Errorf("event kinds = %v, want %v", kinds, want)
+			}
+		})
+	}
+}
+
+func TestStoreEventsReplayManualSessions(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			store := newTestStore(t, backend)
+
+			state, _ := store.Load()
+			now := time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)
+			if _, err := state.AddSession("planning +team", now.Add(-3*time.Hour), now.Add(-2*time.Hour), 10*time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			task := "planning @meeting"
+			completedAt := now.Add(-time.Hour)
+			if _, err := state.EditSession(0, flowtime.SessionEdit{Task: &task, CompletedAt: &completedAt}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+
+			events, err := store.(EventLog).Events()
+			if err != nil {
+				t.Fatal(err)
+			}
+			replayed, err := flowtime.Replay(flowtime.RealClock{}, events)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, want := replayed.CompletedSessions, state.CompletedSessions
+			if len(got) != 1 || got[0].ID != want[0].ID || !got[0].CompletedAt.Equal(want[0].CompletedAt) ||
+				!reflect.DeepEqual(got[0].Tags, []string{"meeting"}) || got[0].FlowDuration != 50*time.Minute {
+				t.Errorf("replayed sessions = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestStoreUndoAcrossLoads(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			store := newTestStore(t, backend)
+			state, _ := store.Load()
+			_ = state.StartSession("write code")
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+			_, _ = state.Stop()
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+
+			state, _ = store.Load()
+			if _, err := state.Undo(); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+			state, _ = store.Load()
+			if state.CurrentSession == nil || len(state.CompletedSessions) != 0 {
+				t.Fatalf("after undo: current = %+v, completed = %d; want the session running", state.CurrentSession, len(state.CompletedSessions))
+			}
+
+			if _, err := state.Redo(); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+			state, _ = store.Load()
+			if state.CurrentSession != nil || len(state.CompletedSessions) != 1 {
+				t.Fatalf("after redo: current = %+v, completed = %d; want the session stopped", state.CurrentSession, len(state.CompletedSessions))
+			}
+		})
+	}
+}
+
+func TestStoreUndoConflict(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			store := newTestStore(t, backend)
+			state, _ := store.Load()
+			_ = state.StartSession("write code")
+			if err := store.Save(state); err != nil {
+				t.Fatal(err)
+			}
+
+			ours, _ := store.Load()
+			if _, err := ours.Undo(); err != nil {
+				t.Fatal(err)
+			}
+
+			theirs, _ := store.Load()
+			_ = theirs.AddNote("meanwhile")
+			if err := store.Save(theirs); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := store.Save(ours); !errors.Is(err, ErrConflict) {
+				t.Errorf("error = %v, want %v", err, ErrConflict)
+			}
+		})
+	}
+}
+
+func TestJSONStoreUndoAfterSnapshot(t *testing.T) {
+	store := newTestStore(t, BackendJSON).(*JSONStore)
+	store.clock = &steppingClock{now: time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC), step: time.Minute}
+
+	// Enough transitions to refresh the snapshot, so undoing relies on the
+	// stacks stored in it rather than on replaying the log.
+	state, _ := store.Load()
+	for range snapshotInterval {
+		_ = state.StartSession("write code")
+		_, _ = state.Stop()
+		if err := store.Save(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state, _ = store.Load()
+	if _, err := state.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+	state, _ = store.Load()
+	if state.CurrentSession == nil || len(state.CompletedSessions) != snapshotInterval-1 {
+		t.Errorf("after undo: current = %+v, completed = %d; want the last session running", state.CurrentSession, len(state.CompletedSessions))
+	}
+
+	events, _ := store.Events()
+	undo := events[len(events)-1]
+	if undo.Kind != flowtime.EventUndo || undo.Snapshot != nil || undo.Change == nil || len(undo.Change.Sessions) != 0 {
+		t.Errorf("undo event = %+v, want a change that only removes the stopped session", undo)
+	}
+}
+
+func TestJSONStoreRebuildsCorruptSnapshot(t *testing.T) {
+	store := newTestStore(t, BackendJSON)
+
+	state, _ := store.Load()
+	_ = state.StartSession("write code")
+	_, _ = state.Stop()
+	_ = state.StartSession("review")
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+
+	stateFile, _ := store.GetFilePath()
+	if err := os.WriteFile(stateFile, []byte(`{"version":1,"completed`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.CurrentSession == nil || got.CurrentSession.Task != "review" || len(got.CompletedSessions) != 1 {
+		t.Errorf("rebuilt state = %+v, want one completed session and %q active", got, "review")
+	}
+
+	// The next save rewrites the snapshot.
+	_ = got.TakeBreak()
+	if err := store.Save(got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := readSnapshotPosition(stateFile); !ok {
+		t.Error("expected snapshot to be rewritten")
+	}
+}
+
+func TestJSONStoreReadsLogFromSnapshot(t *testing.T) {
+	store := newTestStore(t, BackendJSON).(*JSONStore)
+	store.clock = &steppingClock{now: time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC), step: time.Minute}
+	state, _ := store.Load()
+	for i := range snapshotInterval {
+		_ = state.StartSession(fmt.Sprintf("task %d", i))
+		_, _ = state.Stop()
+	}
+	_ = state.StartSession("review")
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+	_ = state.TakeBreak()
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+
+	stateFile, _ := store.GetFilePath()
+	logFile := eventLogPath(stateFile)
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLoaded := func(t *testing.T) {
+		t.Helper()
+		got, err := store.Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.CurrentBreak == nil || len(got.CompletedSessions) != snapshotInterval {
+			t.Errorf("loaded state = %+v, want %d completed sessions and a break", got, snapshotInterval)
+		}
+	}
+
+	t.Run("skips the events before the snapshot", func(t *testing.T) {
+		// Blank out the first event: only the tail after the snapshot is read.
+		damaged := slices.Clone(data)
+		for i := 0; damaged[i] != '\n'; i++ {
+			damaged[i] = ' '
+		}
+		if err := os.WriteFile(logFile, damaged, 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.WriteFile(logFile, data, 0644) })
+
+		assertLoaded(t)
+	})
+
+	t.Run("reads the whole log if the offset is stale", func(t *testing.T) {
+		pos, _, err := readSnapshotPosition(stateFile)
+		if err != nil || pos.offset == 0 {
+			t.Fatalf("snapshot position = %+v, %v, want an offset into the log", pos, err)
+		}
+		snapshot, _ := os.ReadFile(stateFile)
+		stale := strings.Replace(string(snapshot), fmt.Sprintf(`"log_offset":%d`, pos.offset), `"log_offset":3`, 1)
+		if err := os.WriteFile(stateFile, []byte(stale), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		assertLoaded(t)
+	})
+}
+
+func TestJSONStoreAdoptsStateFileWithoutEventLog(t *testing.T) {
+	store := newTestStore(t, BackendJSON)
+	stateFile, _ := store.GetFilePath()
+
+	legacy := `{"version":1,"revision":7,"current_session":null,"current_break":null,"completed_sessions":[` +
+		`{"task":"legacy","flow_duration":60000000000,"break_duration":null,"completed_at":"2025-06-14T10:00:00Z"}]}`
+	if err := os.WriteFile(stateFile, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = state.StartSession("new")
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove the snapshot: the log alone must reproduce the legacy data.
+	if err := os.Remove(stateFile); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.CompletedSessions) != 1 || got.CompletedSessions[0].Task != "legacy" {
+		t.Errorf("completed sessions = %+v, want the legacy session", got.CompletedSessions)
+	}
+	if got.CurrentSession == nil || got.CurrentSession.Task != "new" {
+		t.Errorf("current session = %+v, want task %q", got.CurrentSession, "new")
+	}
+}
+
+func TestEventLogDiscardsTornWrite(t *testing.T) {
+	store := newTestStore(t, BackendJSON)
+
+	state, _ := store.Load()
+	_ = state.StartSession("write code")
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+
+	stateFile, _ := store.GetFilePath()
+	logFile := eventLogPath(stateFile)
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":2,"kind":"bre`)
+	f.Close()
+
+	state, err = store.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = state.TakeBreak()
+	if err := store.Save(state); err != nil {
+		t.Fatal(err)
+	}
+
+	events, _, _, err := readEventLog(logFile, logPosition{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[1].Kind != string(flowtime.EventBreak) {
+		t.Errorf("events = %+v, want start then break", events)
+	}
+}
@@ -10,14 +10,24 @@ type (
 	TickMsg                 = msgs.TickMsg
 	StartSessionMsg         = msgs.StartSessionMsg
 	ShowLogMsg              = msgs.ShowLogMsg
+	ShowSessionMsg          = msgs.ShowSessionMsg
+	ShowTrashMsg            = msgs.ShowTrashMsg
 	BackMsg                 = msgs.BackMsg
 	ErrorMsg                = msgs.ErrorMsg
+	StopSessionMsg          = msgs.StopSessionMsg
+	SwitchTaskMsg           = msgs.SwitchTaskMsg
+	AddNoteMsg              = msgs.AddNoteMsg
+	InterruptMsg            = msgs.InterruptMsg
+	UndoMsg                 = msgs.UndoMsg
 	CancelSessionMsg        = msgs.CancelSessionMsg
 	DeleteSessionMsg        = msgs.DeleteSessionMsg
+	RestoreSessionMsg       = msgs.RestoreSessionMsg
 	DeleteAllSessionsMsg    = msgs.DeleteAllSessionsMsg
+	FilterLogMsg            = msgs.FilterLogMsg
 	RequestDeleteSessionMsg = msgs.RequestDeleteSessionMsg
 	RequestConfirmMsg       = msgs.RequestConfirmMsg
 	ConfirmResultMsg        = msgs.ConfirmResultMsg
+	RequestPromptMsg        = msgs.RequestPromptMsg
 )
 
 var Tick = msgs.Tick
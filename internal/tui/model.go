@@ -1,7 +1,9 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Broderick-Westrope/flower/internal/flowtime"
@@ -20,6 +22,8 @@ const (
 	viewFlow
 	viewBreak
 	viewLog
+	viewTrash
+	viewSession
 )
 
 // Model is the top-level Bubble Tea model for the flower TUI.
@@ -32,11 +36,21 @@ type Model struct {
 	flowView  *views.FlowView
 	breakView *views.BreakView
 	logView   *views.LogView
+	trashView *views.TrashView
+
+	// Filter applied to the log view's sessions.
+	logFilter flowtime.SessionFilter
+
+	sessionView *views.SessionView
+	promptView  *views.PromptView
 
 	// Confirmation prompt state.
 	confirming    bool
 	confirmAction msgs.ConfirmAction
 
+	// Text prompt state.
+	prompting bool
+
 	err         error
 	errDeadline time.Time
 	width       int
@@ -61,22 +75,58 @@ func New(store storage.Store) (*Model, error) {
 		flowView:  views.NewFlowView(),
 		breakView: views.NewBreakView(),
 		logView:   views.NewLogView(logPageSize),
+		trashView: views.NewTrashView(logPageSize),
+
+		sessionView: views.NewSessionView(),
+		promptView:  views.NewPromptView(),
 	}
 
 	// Determine initial view from restored state.
+	m.syncViews()
+
+	return m, nil
+}
+
+// syncViews points the views at the current state and switches to the view
+// matching it. The log, trash and session views are kept open (with refreshed
+// data) if active; a session view whose session no longer exists falls back to the log.
+func (m *Model) syncViews() {
+	browsing := m.activeView == viewLog || m.activeView == viewTrash || m.activeView == viewSession
+	if m.activeView == viewSession {
+		if index, err := m.state.SessionIndex(m.sessionView.SessionID()); err == nil {
+			m.sessionView.SetSession(m.state.CompletedSessions[index])
+		} else {
+			m.activeView = viewLog
+		}
+	}
+	switch m.activeView {
+	case viewLog:
+		m.logView.SetSessions(m.logSessions())
+	case viewTrash:
+		m.trashView.SetSessions(m.state.DeletedSessions())
+	}
+
+	cycle := flowtime.FormatCycle(m.state.Cycle(time.Now()), m.state.LongBreakRule().Every)
+	m.flowView.SetCycle(cycle)
+	m.breakView.SetCycle(cycle, m.state.OnLongBreak())
+
 	switch {
-	case state.CurrentSession != nil && state.CurrentBreak != nil:
-		m.activeView = viewBreak
-		m.flowView.SetSession(state.CurrentSession)
-		m.breakView.SetBreak(state.CurrentSession.Task, state.CurrentBreak)
-	case state.CurrentSession != nil:
-		m.activeView = viewFlow
-		m.flowView.SetSession(state.CurrentSession)
+	case m.state.CurrentSession != nil && m.state.CurrentBreak != nil:
+		m.flowView.SetSession(m.state.CurrentSession)
+		m.breakView.SetBreak(m.state.CurrentSession.Task, m.state.CurrentBreak)
+		if !browsing {
+			m.activeView = viewBreak
+		}
+	case m.state.CurrentSession != nil:
+		m.flowView.SetSession(m.state.CurrentSession)
+		if !browsing {
+			m.activeView = viewFlow
+		}
 	default:
-		m.activeView = viewIdle
+		if !browsing {
+			m.activeView = viewIdle
+		}
 	}
-
-	return m, nil
 }
 
 // Init starts the tick loop plus sub-component initialisation.
@@ -100,6 +150,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.confirming {
 			return m.handleConfirmKey(msg)
 		}
+		// When a text prompt is active, it takes all keys.
+		if m.prompting {
+			cmd, closed := m.promptView.Update(msg)
+			m.prompting = !closed
+			return m, cmd
+		}
 		return m.handleKey(msg)
 
 	case tea.WindowSizeMsg:
@@ -121,6 +177,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case ShowLogMsg:
 		return m.handleShowLog()
 
+	case ShowTrashMsg:
+		return m.handleShowTrash()
+
+	case ShowSessionMsg:
+		return m.handleShowSession(msg.ID)
+
 	case BackMsg:
 		return m.handleBack()
 
@@ -129,21 +191,51 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.confirmAction = msg.Action
 		return m, nil
 
+	case RequestPromptMsg:
+		return m.requestPrompt(msg.Action)
+
+	case FilterLogMsg:
+		return m.handleFilterLog(msg.Query)
+
+	case StopSessionMsg:
+		return m.handleStop(msg.Note)
+
+	case SwitchTaskMsg:
+		return m.handleSwitchTask(msg.Task)
+
+	case AddNoteMsg:
+		return m.handleAddNote(msg.Text)
+
+	case InterruptMsg:
+		return m.handleInterrupt(msg.Kind, msg.Reason)
+
+	case UndoMsg:
+		return m.handleUndo()
+
 	case CancelSessionMsg:
 		return m.handleCancelSession()
 
 	case DeleteSessionMsg:
-		return m.handleDeleteSession(msg.Index)
+		return m.handleDeleteSession(msg.ID)
 
 	case DeleteAllSessionsMsg:
 		return m.handleDeleteAllSessions()
 
+	case RestoreSessionMsg:
+		return m.handleRestoreSession(msg.ID)
+
 	case RequestDeleteSessionMsg:
 		return m.handleRequestDeleteSession(msg.ActiveIndex)
 	}
 
 	// Delegate spinner, progress frames, etc. to active view.
-	return m, m.delegateToActiveView(msg)
+	cmd := m.delegateToActiveView(msg)
+	if m.prompting {
+		// Cursor blinks for the text prompt.
+		promptCmd, _ := m.promptView.Update(msg)
+		cmd = tea.Batch(cmd, promptCmd)
+	}
+	return m, cmd
 }
 
 // View renders the active view wrapped in the container style.
@@ -158,6 +250,10 @@ func (m *Model) View() string {
 		content = m.breakView.View()
 	case viewLog:
 		content = m.logView.View()
+	case viewTrash:
+		content = m.trashView.View()
+	case viewSession:
+		content = m.sessionView.View()
 	}
 
 	if m.confirming {
@@ -168,6 +264,14 @@ func (m *Model) View() string {
 		)
 	}
 
+	if m.prompting {
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			content,
+			"",
+			m.promptView.View(lipgloss.Width(content)),
+		)
+	}
+
 	if m.err != nil {
 		content = lipgloss.JoinVertical(lipgloss.Left,
 			content,
@@ -191,16 +295,34 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		cmd := m.logView.Update(msg)
 		return m, cmd
 
+	case viewTrash:
+		cmd := m.trashView.Update(msg)
+		return m, cmd
+
+	case viewSession:
+		cmd := m.sessionView.Update(msg)
+		return m, cmd
+
 	case viewFlow:
 		switch msg.String() {
 		case " ":
 			return m.handleTakeBreak()
+		case "i":
+			return m.requestInterrupt(flowtime.InterruptionInternal)
+		case "e":
+			return m.requestInterrupt(flowtime.InterruptionExternal)
+		case "n":
+			return m.requestNote()
+		case "t":
+			return m.requestSwitchTask()
 		case "s":
-			return m.handleStop()
+			return m.requestStop()
 		case "c":
 			return m.requestConfirm("Cancel session?", CancelSessionMsg{})
 		case "l":
 			return m.handleShowLog()
+		case "u":
+			return m.handleUndo()
 		case "q":
 			return m, tea.Quit
 		}
@@ -209,12 +331,16 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case " ":
 			return m.handleResume()
+		case "n":
+			return m.requestNote()
 		case "s":
-			return m.handleStop()
+			return m.requestStop()
 		case "c":
 			return m.requestConfirm("Cancel session?", CancelSessionMsg{})
 		case "l":
 			return m.handleShowLog()
+		case "u":
+			return m.handleUndo()
 		case "q":
 			return m, tea.Quit
 		}
@@ -237,8 +363,8 @@ func (m *Model) handleStartSession(task string) (tea.Model, tea.Cmd) {
 	if err := m.state.StartSession(task); err != nil {
 		return m, errCmd(fmt.Errorf("starting session: %w", err))
 	}
-	if err := m.store.Save(m.state); err != nil {
-		return m, errCmd(fmt.Errorf("saving: %w", err))
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
 	}
 
 	m.activeView = viewFlow
@@ -250,8 +376,8 @@ func (m *Model) handleTakeBreak() (tea.Model, tea.Cmd) {
 	if err := m.state.TakeBreak(); err != nil {
 		return m, errCmd(err)
 	}
-	if err := m.store.Save(m.state); err != nil {
-		return m, errCmd(fmt.Errorf("saving: %w", err))
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
 	}
 
 	m.activeView = viewBreak
@@ -264,8 +390,8 @@ func (m *Model) handleResume() (tea.Model, tea.Cmd) {
 	if err != nil {
 		return m, errCmd(err)
 	}
-	if err := m.store.Save(m.state); err != nil {
-		return m, errCmd(fmt.Errorf("saving: %w", err))
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
 	}
 
 	m.activeView = viewFlow
@@ -273,13 +399,83 @@ func (m *Model) handleResume() (tea.Model, tea.Cmd) {
 	return m, m.flowView.Init()
 }
 
-func (m *Model) handleStop() (tea.Model, tea.Cmd) {
-	_, err := m.state.Stop()
+// requestStop asks for an optional outcome before stopping the session.
+func (m *Model) requestStop() (tea.Model, tea.Cmd) {
+	return m.requestPrompt(msgs.PromptAction{
+		Prompt:      "Outcome (optional):",
+		Placeholder: "What did you get done?",
+		OnSubmit:    func(text string) tea.Msg { return StopSessionMsg{Note: text} },
+	})
+}
+
+// requestSwitchTask asks for the task to switch to.
+func (m *Model) requestSwitchTask() (tea.Model, tea.Cmd) {
+	return m.requestPrompt(msgs.PromptAction{
+		Prompt:      "Switch to:",
+		Placeholder: "Task name... (+project @tag)",
+		OnSubmit:    func(text string) tea.Msg { return SwitchTaskMsg{Task: text} },
+	})
+}
+
+// requestNote asks for a note to attach to the current session.
+func (m *Model) requestNote() (tea.Model, tea.Cmd) {
+	return m.requestPrompt(msgs.PromptAction{
+		Prompt:      "Note:",
+		Placeholder: "Found the root cause...",
+		OnSubmit:    func(text string) tea.Msg { return AddNoteMsg{Text: text} },
+	})
+}
+
+// requestInterrupt asks for an optional reason before recording an interruption
+// of the given kind.
+func (m *Model) requestInterrupt(kind flowtime.InterruptionKind) (tea.Model, tea.Cmd) {
+	return m.requestPrompt(msgs.PromptAction{
+		Prompt:      fmt.Sprintf("Interrupted (%s), reason (optional):", kind),
+		Placeholder: "What interrupted you?",
+		OnSubmit:    func(text string) tea.Msg { return InterruptMsg{Kind: kind, Reason: text} },
+	})
+}
+
+func (m *Model) handleInterrupt(kind flowtime.InterruptionKind, reason string) (tea.Model, tea.Cmd) {
+	if err := m.state.Interrupt(kind, reason); err != nil {
+		return m, errCmd(fmt.Errorf("recording interruption: %w", err))
+	}
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
+	}
+	return m, nil
+}
+
+func (m *Model) handleSwitchTask(task string) (tea.Model, tea.Cmd) {
+	if _, err := m.state.Switch(task); err != nil {
+		return m, errCmd(fmt.Errorf("switching task: %w", err))
+	}
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
+	}
+
+	m.activeView = viewFlow
+	m.flowView.SetSession(m.state.CurrentSession)
+	return m, m.flowView.Init()
+}
+
+func (m *Model) handleAddNote(text string) (tea.Model, tea.Cmd) {
+	if err := m.state.AddNote(text); err != nil {
+		return m, errCmd(fmt.Errorf("adding note: %w", err))
+	}
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
+	}
+	return m, nil
+}
+
+func (m *Model) handleStop(note string) (tea.Model, tea.Cmd) {
+	_, err := m.state.StopWithNote(note)
 	if err != nil {
 		return m, errCmd(err)
 	}
-	if err := m.store.Save(m.state); err != nil {
-		return m, errCmd(fmt.Errorf("saving: %w", err))
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
 	}
 
 	m.activeView = viewIdle
@@ -288,11 +484,45 @@ func (m *Model) handleStop() (tea.Model, tea.Cmd) {
 }
 
 func (m *Model) handleShowLog() (tea.Model, tea.Cmd) {
-	m.logView.SetSessions(m.state.ActiveSessions())
+	m.logView.SetSessions(m.logSessions())
 	m.activeView = viewLog
 	return m, nil
 }
 
+// handleFilterLog shows only the logged sessions matching query. An invalid
+// query is reported and leaves the current filter in place.
+func (m *Model) handleFilterLog(query string) (tea.Model, tea.Cmd) {
+	filter, err := flowtime.ParseFilter(query, time.Now())
+	if err != nil {
+		return m, errCmd(err)
+	}
+	m.logFilter = filter
+	m.logView.SetQuery(strings.TrimSpace(query))
+	m.logView.SetSessions(m.logSessions())
+	return m, nil
+}
+
+// logSessions returns the active sessions matching the log filter.
+func (m *Model) logSessions() []flowtime.CompletedSession {
+	return m.logFilter.Apply(m.state.ActiveSessions())
+}
+
+func (m *Model) handleShowTrash() (tea.Model, tea.Cmd) {
+	m.trashView.SetSessions(m.state.DeletedSessions())
+	m.activeView = viewTrash
+	return m, nil
+}
+
+func (m *Model) handleShowSession(id string) (tea.Model, tea.Cmd) {
+	index, err := m.state.SessionIndex(id)
+	if err != nil {
+		return m, errCmd(err)
+	}
+	m.sessionView.SetSession(m.state.CompletedSessions[index])
+	m.activeView = viewSession
+	return m, nil
+}
+
 func (m *Model) handleBack() (tea.Model, tea.Cmd) {
 	switch {
 	case m.state.CurrentSession != nil && m.state.CurrentBreak != nil:
@@ -317,6 +547,10 @@ func (m *Model) delegateToActiveView(msg tea.Msg) tea.Cmd {
 		return m.breakView.Update(msg)
 	case viewLog:
 		return m.logView.Update(msg)
+	case viewTrash:
+		return m.trashView.Update(msg)
+	case viewSession:
+		return m.sessionView.Update(msg)
 	}
 	return nil
 }
@@ -327,6 +561,11 @@ func (m *Model) requestConfirm(prompt string, onYes tea.Msg) (tea.Model, tea.Cmd
 	return m, nil
 }
 
+func (m *Model) requestPrompt(action msgs.PromptAction) (tea.Model, tea.Cmd) {
+	m.prompting = true
+	return m, m.promptView.Open(action)
+}
+
 func (m *Model) handleConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "y", "Y":
@@ -346,8 +585,8 @@ func (m *Model) handleCancelSession() (tea.Model, tea.Cmd) {
 	if err := m.state.CancelSession(); err != nil {
 		return m, errCmd(err)
 	}
-	if err := m.store.Save(m.state); err != nil {
-		return m, errCmd(fmt.Errorf("saving: %w", err))
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
 	}
 
 	m.activeView = viewIdle
@@ -355,58 +594,108 @@ func (m *Model) handleCancelSession() (tea.Model, tea.Cmd) {
 	return m, m.idleView.Init()
 }
 
-func (m *Model) handleDeleteSession(index int) (tea.Model, tea.Cmd) {
+// handleUndo undoes the last state transition and shows the view matching the
+// restored state.
+func (m *Model) handleUndo() (tea.Model, tea.Cmd) {
+	if _, err := m.state.Undo(); err != nil {
+		return m, errCmd(fmt.Errorf("undoing: %w", err))
+	}
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
+	}
+
+	switch m.activeView {
+	case viewFlow:
+		return m, m.flowView.Init()
+	case viewIdle:
+		m.idleView.Reset()
+		return m, m.idleView.Init()
+	}
+	return m, nil
+}
+
+func (m *Model) handleDeleteSession(id string) (tea.Model, tea.Cmd) {
+	index, err := m.state.SessionIndex(id)
+	if err != nil {
+		return m, errCmd(err)
+	}
 	if err := m.state.DeleteSession(index); err != nil {
 		return m, errCmd(err)
 	}
-	if err := m.store.Save(m.state); err != nil {
-		return m, errCmd(fmt.Errorf("saving: %w", err))
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
 	}
 
 	// Refresh the log view with updated active sessions.
-	m.logView.SetSessions(m.state.ActiveSessions())
+	m.logView.SetSessions(m.logSessions())
 	return m, nil
 }
 
 func (m *Model) handleRequestDeleteSession(activeIndex int) (tea.Model, tea.Cmd) {
-	active := m.state.ActiveSessions()
+	active := m.logSessions()
 	if activeIndex < 0 || activeIndex >= len(active) {
 		return m, errCmd(fmt.Errorf("session index %d out of range", activeIndex))
 	}
 
 	target := active[activeIndex]
+	return m.requestConfirm(
+		fmt.Sprintf("Delete %q?", target.Task),
+		DeleteSessionMsg{ID: target.ID},
+	)
+}
 
-	// Find the matching entry in the full slice.
-	fullIndex := -1
-	for i, cs := range m.state.CompletedSessions {
-		if cs.CompletedAt.Equal(target.CompletedAt) && cs.Task == target.Task && cs.DeletedAt == nil {
-			fullIndex = i
-			break
-		}
+func (m *Model) handleRestoreSession(id string) (tea.Model, tea.Cmd) {
+	index, err := m.state.SessionIndex(id)
+	if err != nil {
+		return m, errCmd(err)
 	}
-	if fullIndex == -1 {
-		return m, errCmd(fmt.Errorf("session not found"))
+	if err := m.state.RestoreSession(index); err != nil {
+		return m, errCmd(err)
+	}
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
 	}
 
-	return m.requestConfirm(
-		fmt.Sprintf("Delete %q?", target.Task),
-		DeleteSessionMsg{Index: fullIndex},
-	)
+	// Refresh the trash view with the remaining deleted sessions.
+	m.trashView.SetSessions(m.state.DeletedSessions())
+	return m, nil
 }
 
 func (m *Model) handleDeleteAllSessions() (tea.Model, tea.Cmd) {
 	if err := m.state.DeleteAllSessions(); err != nil {
 		return m, errCmd(err)
 	}
-	if err := m.store.Save(m.state); err != nil {
-		return m, errCmd(fmt.Errorf("saving: %w", err))
+	if err := m.save(); err != nil {
+		return m, errCmd(err)
 	}
 
 	// Refresh the log view with updated active sessions.
-	m.logView.SetSessions(m.state.ActiveSessions())
+	m.logView.SetSessions(m.logSessions())
 	return m, nil
 }
 
+// save persists the state. Changes saved by another process in the meantime are
+// merged in by the store; if they conflict, the local change is discarded and the
+// latest state is reloaded so the user can retry.
+func (m *Model) save() error {
+	err := m.store.Save(m.state)
+	if err == nil {
+		// A merge may have replaced the state's contents.
+		m.syncViews()
+		return nil
+	}
+	if errors.Is(err, storage.ErrConflict) {
+		state, loadErr := m.store.Load()
+		if loadErr != nil {
+			return fmt.Errorf("reloading after conflict: %w", loadErr)
+		}
+		m.state = state
+		m.syncViews()
+		return fmt.Errorf("%w; reloaded the latest state, please try again", err)
+	}
+	return fmt.Errorf("saving: %w", err)
+}
+
 func errCmd(err error) tea.Cmd {
 	return func() tea.Msg { return ErrorMsg{Err: err} }
 }
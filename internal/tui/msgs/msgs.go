@@ -3,6 +3,8 @@ package msgs
 import (
 	"time"
 
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -22,24 +24,56 @@ type StartSessionMsg struct{ Task string }
 // ShowLogMsg requests switching to the session log view.
 type ShowLogMsg struct{}
 
+// ShowSessionMsg requests showing the completed session with the given ID and its intervals.
+type ShowSessionMsg struct{ ID string }
+
+// ShowTrashMsg requests switching to the deleted sessions view.
+type ShowTrashMsg struct{}
+
 // BackMsg requests returning to the previous view.
 type BackMsg struct{}
 
 // ErrorMsg carries an error to display to the user.
 type ErrorMsg struct{ Err error }
 
+// StopSessionMsg requests stopping the current session, recording Note as its
+// outcome if it is not empty.
+type StopSessionMsg struct{ Note string }
+
+// SwitchTaskMsg requests ending the current session and starting a new one
+// with the given task.
+type SwitchTaskMsg struct{ Task string }
+
+// AddNoteMsg requests attaching a note to the current session.
+type AddNoteMsg struct{ Text string }
+
+// InterruptMsg requests recording an interruption of the current flow.
+type InterruptMsg struct {
+	Kind   flowtime.InterruptionKind
+	Reason string
+}
+
+// UndoMsg requests undoing the last state transition.
+type UndoMsg struct{}
+
 // CancelSessionMsg requests cancelling the current session.
 type CancelSessionMsg struct{}
 
-// DeleteSessionMsg requests soft-deleting a specific completed session.
-// Index is into the full CompletedSessions slice (not the active-only list).
-type DeleteSessionMsg struct{ Index int }
+// DeleteSessionMsg requests soft-deleting the completed session with the given ID.
+type DeleteSessionMsg struct{ ID string }
+
+// RestoreSessionMsg requests undeleting the completed session with the given ID.
+type RestoreSessionMsg struct{ ID string }
 
 // DeleteAllSessionsMsg requests soft-deleting all completed sessions.
 type DeleteAllSessionsMsg struct{}
 
+// FilterLogMsg requests showing only the logged sessions matching Query, in the
+// syntax of flowtime.ParseFilter. An empty query shows every session.
+type FilterLogMsg struct{ Query string }
+
 // RequestDeleteSessionMsg is emitted by the log view with the active-list index.
-// The model maps this to the full-slice index before creating a DeleteSessionMsg.
+// The model maps this to the session's ID before creating a DeleteSessionMsg.
 type RequestDeleteSessionMsg struct{ ActiveIndex int }
 
 // ConfirmAction represents a pending action that requires user confirmation.
@@ -53,3 +87,16 @@ type RequestConfirmMsg struct{ Action ConfirmAction }
 
 // ConfirmResultMsg carries the user's yes/no answer.
 type ConfirmResultMsg struct{ Confirmed bool }
+
+// PromptAction represents a pending action that needs a line of text from the
+// user. Value prefills the input, and OnSubmit builds the message to send with
+// the entered text.
+type PromptAction struct {
+	Prompt      string
+	Placeholder string
+	Value       string
+	OnSubmit    func(text string) tea.Msg
+}
+
+// RequestPromptMsg asks the model to show a text prompt.
+type RequestPromptMsg struct{ Action PromptAction }
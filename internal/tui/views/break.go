@@ -16,6 +16,8 @@ import (
 type BreakView struct {
 	taskName string
 	brk      *flowtime.Break
+	cycle    string
+	long     bool
 	progress progress.Model
 }
 
@@ -32,6 +34,13 @@ func (v *BreakView) SetBreak(taskName string, b *flowtime.Break) {
 	v.brk = b
 }
 
+// SetCycle updates the position in the flow/break rhythm shown by this view and
+// whether the break is a long one.
+func (v *BreakView) SetCycle(cycle string, long bool) {
+	v.cycle = cycle
+	v.long = long
+}
+
 // Update handles tick and progress-frame messages.
 func (v *BreakView) Update(msg tea.Msg) tea.Cmd {
 	if v.brk == nil {
@@ -81,11 +90,18 @@ func (v *BreakView) View() string {
 		"  " + statusStr
 	taskLine := styles.TaskName.Render(v.taskName)
 	progressLine := v.progress.View()
+	cycleLine := styles.HelpBar.Render(v.cycle)
+	title := "🧘 Break"
+	if v.long {
+		title = "🧘 Long Break"
+	}
 	helpBar := RenderHelpBar([]KeyBinding{
 		{Key: "space", Description: "resume"},
+		{Key: "n", Description: "note"},
 		{Key: "s", Description: "stop"},
 		{Key: "c", Description: "cancel"},
 		{Key: "l", Description: "log"},
+		{Key: "u", Description: "undo"},
 		{Key: "q", Description: "quit"},
 	})
 
@@ -93,15 +109,17 @@ func (v *BreakView) View() string {
 		lipgloss.Width(timerLine),
 		lipgloss.Width(taskLine),
 		lipgloss.Width(progressLine),
+		lipgloss.Width(cycleLine),
 		lipgloss.Width(helpBar),
 	)
 
 	return lipgloss.JoinVertical(lipgloss.Left,
-		styles.Title.Render("🧘 Break"),
+		styles.Title.Render(title),
 		"",
 		taskLine,
 		timerLine,
 		progressLine,
+		cycleLine,
 		"",
 		styles.Separator(contentWidth),
 		helpBar,
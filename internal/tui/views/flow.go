@@ -13,6 +13,7 @@ import (
 // FlowView displays the running flow timer with a spinner.
 type FlowView struct {
 	session *flowtime.Session
+	cycle   string
 	spinner spinner.Model
 }
 
@@ -28,6 +29,11 @@ func (v *FlowView) SetSession(s *flowtime.Session) {
 	v.session = s
 }
 
+// SetCycle updates the position in the flow/break rhythm shown by this view.
+func (v *FlowView) SetCycle(cycle string) {
+	v.cycle = cycle
+}
+
 // Init returns the spinner tick command.
 func (v *FlowView) Init() tea.Cmd {
 	return v.spinner.Tick
@@ -46,20 +52,33 @@ func (v *FlowView) View() string {
 		return ""
 	}
 
-	elapsed := time.Since(v.session.StartTime)
+	elapsed := time.Since(v.session.FlowStart())
 	timerLine := styles.Timer.Render(flowtime.FormatDuration(elapsed)) + " " + v.spinner.View()
 	taskLine := styles.TaskName.Render(v.session.Task)
+	if labels := flowtime.FormatLabels(v.session.Project, v.session.Tags); labels != "" {
+		taskLine += " " + styles.HelpBar.Render(labels)
+	}
+	cycle := v.cycle
+	if interruptions := v.session.Interruptions; len(interruptions) > 0 {
+		cycle += " · interruptions: " + flowtime.FormatInterruptions(interruptions)
+	}
+	cycleLine := styles.HelpBar.Render(cycle)
 	helpBar := RenderHelpBar([]KeyBinding{
 		{Key: "space", Description: "break"},
+		{Key: "i/e", Description: "interrupted"},
+		{Key: "n", Description: "note"},
+		{Key: "t", Description: "switch task"},
 		{Key: "s", Description: "stop"},
 		{Key: "c", Description: "cancel"},
 		{Key: "l", Description: "log"},
+		{Key: "u", Description: "undo"},
 		{Key: "q", Description: "quit"},
 	})
 
 	contentWidth := max(
 		lipgloss.Width(timerLine),
 		lipgloss.Width(taskLine),
+		lipgloss.Width(cycleLine),
 		lipgloss.Width(helpBar),
 	)
 
@@ -68,6 +87,7 @@ func (v *FlowView) View() string {
 		"",
 		taskLine,
 		timerLine,
+		cycleLine,
 		"",
 		styles.Separator(contentWidth),
 		helpBar,
@@ -16,8 +16,9 @@ type IdleView struct {
 // NewIdleView creates a focused text input with placeholder and char limit.
 func NewIdleView() *IdleView {
 	ti := textinput.New()
-	ti.Placeholder = "Task name..."
-	ti.CharLimit = 100
+	ti.Placeholder = "Task name... (+project @tag)"
+	// The task itself is limited to 100 characters; leave room for labels.
+	ti.CharLimit = 200
 	ti.Focus()
 	return &IdleView{input: ti}
 }
@@ -46,6 +47,8 @@ func (v *IdleView) Update(msg tea.Msg) tea.Cmd {
 				return nil // ignore enter on empty input
 			case "l":
 				return func() tea.Msg { return msgs.ShowLogMsg{} }
+			case "u":
+				return func() tea.Msg { return msgs.UndoMsg{} }
 			case "q":
 				return tea.Quit
 			}
@@ -70,6 +73,7 @@ func (v *IdleView) View() string {
 	helpBar := RenderHelpBar([]KeyBinding{
 		{Key: "enter", Description: "start"},
 		{Key: "l", Description: "log"},
+		{Key: "u", Description: "undo"},
 		{Key: "q", Description: "quit"},
 	})
 
@@ -2,6 +2,8 @@ package views
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Broderick-Westrope/flower/internal/flowtime"
@@ -14,9 +16,11 @@ import (
 )
 
 // LogView displays a paginated table of completed sessions (newest first)
-// with cursor-based row selection for deletion.
+// with cursor-based row selection for deletion. The sessions may be narrowed
+// by a filter query, which the model applies.
 type LogView struct {
 	sessions []flowtime.CompletedSession
+	query    string
 	page     int
 	pageSize int
 	cursor   int // selected row on the current page (0-indexed)
@@ -37,6 +41,11 @@ func (v *LogView) SetSessions(sessions []flowtime.CompletedSession) {
 	v.cursor = 0
 }
 
+// SetQuery updates the filter query shown above the table.
+func (v *LogView) SetQuery(query string) {
+	v.query = query
+}
+
 // totalPages returns the number of pages needed for all sessions.
 func (v *LogView) totalPages() int {
 	if len(v.sessions) == 0 {
@@ -60,7 +69,8 @@ func (v *LogView) activeIndex() int {
 	return len(v.sessions) - (v.page-1)*v.pageSize - v.cursor - 1
 }
 
-// Update handles cursor movement, pagination, and delete keys.
+// Update handles cursor movement, pagination, filtering, delete keys and opening
+// a session or the trash. Esc clears the filter before leaving the log.
 func (v *LogView) Update(msg tea.Msg) tea.Cmd {
 	if msg, ok := msg.(tea.KeyMsg); ok {
 		switch msg.String() {
@@ -78,13 +88,19 @@ func (v *LogView) Update(msg tea.Msg) tea.Cmd {
 				v.page++
 				v.cursor = 0
 			}
+		case "enter":
+			if len(v.sessions) > 0 {
+				id := v.sessions[v.activeIndex()].ID
+				return func() tea.Msg { return msgs.ShowSessionMsg{ID: id} }
+			}
 		case "d":
 			if len(v.sessions) > 0 {
 				idx := v.activeIndex()
 				return func() tea.Msg { return msgs.RequestDeleteSessionMsg{ActiveIndex: idx} }
 			}
 		case "D":
-			if len(v.sessions) > 0 {
+			// Deleting all sessions would include those hidden by the filter.
+			if len(v.sessions) > 0 && v.query == "" {
 				return func() tea.Msg {
 					return msgs.RequestConfirmMsg{
 						Action: msgs.ConfirmAction{
@@ -94,7 +110,25 @@ func (v *LogView) Update(msg tea.Msg) tea.Cmd {
 					}
 				}
 			}
+		case "/":
+			return func() tea.Msg {
+				return msgs.RequestPromptMsg{
+					Action: msgs.PromptAction{
+						Prompt:      "Filter:",
+						Placeholder: `fix login +project @tag since:"last week" min:25m`,
+						Value:       v.query,
+						OnSubmit:    func(text string) tea.Msg { return msgs.FilterLogMsg{Query: text} },
+					},
+				}
+			}
+		case "t":
+			return func() tea.Msg { return msgs.ShowTrashMsg{} }
+		case "u":
+			return func() tea.Msg { return msgs.UndoMsg{} }
 		case "esc":
+			if v.query != "" {
+				return func() tea.Msg { return msgs.FilterLogMsg{} }
+			}
 			return func() tea.Msg { return msgs.BackMsg{} }
 		case "q":
 			return tea.Quit
@@ -106,11 +140,22 @@ func (v *LogView) Update(msg tea.Msg) tea.Cmd {
 // View renders the session log table with cursor highlighting.
 func (v *LogView) View() string {
 	title := styles.Title.Render("📜 Session Log")
+	back := KeyBinding{Key: "esc", Description: "back"}
+	if v.query != "" {
+		title += " " + styles.HelpBar.Render("/ "+v.query)
+		back.Description = "clear filter"
+	}
 
 	if len(v.sessions) == 0 {
 		emptyMsg := "No completed sessions yet."
+		if v.query != "" {
+			emptyMsg = "No sessions match the filter."
+		}
 		helpBar := RenderHelpBar([]KeyBinding{
-			{Key: "esc", Description: "back"},
+			back,
+			{Key: "/", Description: "filter"},
+			{Key: "t", Description: "trash"},
+			{Key: "u", Description: "undo"},
 			{Key: "q", Description: "quit"},
 		})
 		contentWidth := max(
@@ -145,8 +190,11 @@ func (v *LogView) View() string {
 		rows[i] = []string{
 			flowtime.FormatHumanDateTime(s.CompletedAt, now),
 			s.Task,
+			s.Project,
+			strings.Join(s.Tags, ", "),
 			flowtime.FormatDuration(s.FlowDuration),
 			breakStr,
+			strconv.Itoa(len(s.Interruptions)),
 		}
 	}
 
@@ -154,7 +202,7 @@ func (v *LogView) View() string {
 
 	t := table.New().
 		Border(lipgloss.NormalBorder()).
-		Headers("COMPLETED AT", "TASK", "FLOW", "BREAK").
+		Headers("COMPLETED AT", "TASK", "PROJECT", "TAGS", "FLOW", "BREAK", "INT").
 		Rows(rows...).
 		StyleFunc(func(row, col int) lipgloss.Style {
 			if row == table.HeaderRow {
@@ -169,13 +217,21 @@ func (v *LogView) View() string {
 
 	pageInfo := fmt.Sprintf("Page %d of %d", v.page, v.totalPages())
 	tableRendered := t.Render()
-	helpBar := RenderHelpBar([]KeyBinding{
-		{Key: "esc", Description: "back"},
+	keys := []KeyBinding{
+		back,
 		{Key: "j/k", Description: "navigate"},
+		{Key: "enter", Description: "details"},
+		{Key: "/", Description: "filter"},
 		{Key: "d", Description: "delete"},
-		{Key: "D", Description: "delete all"},
-		{Key: "q", Description: "quit"},
-	})
+	}
+	if v.query == "" {
+		keys = append(keys, KeyBinding{Key: "D", Description: "delete all"})
+	}
+	helpBar := RenderHelpBar(append(keys,
+		KeyBinding{Key: "t", Description: "trash"},
+		KeyBinding{Key: "u", Description: "undo"},
+		KeyBinding{Key: "q", Description: "quit"},
+	))
 
 	contentWidth := max(
 		lipgloss.Width(title),
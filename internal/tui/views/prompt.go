@@ -0,0 +1,68 @@
+package views
+
+import (
+	"github.com/Broderick-Westrope/flower/internal/tui/msgs"
+	"github.com/Broderick-Westrope/flower/internal/tui/styles"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// PromptView asks the user for a line of free text, such as a note, below the
+// active view.
+type PromptView struct {
+	action msgs.PromptAction
+	input  textinput.Model
+}
+
+// NewPromptView creates an empty PromptView.
+func NewPromptView() *PromptView {
+	ti := textinput.New()
+	ti.CharLimit = 500
+	return &PromptView{input: ti}
+}
+
+// Open resets the input to the action's value and shows the prompt for it.
+func (v *PromptView) Open(action msgs.PromptAction) tea.Cmd {
+	v.action = action
+	v.input.Reset()
+	v.input.SetValue(action.Value)
+	v.input.CursorEnd()
+	v.input.Placeholder = action.Placeholder
+	return v.input.Focus()
+}
+
+// Update handles key events: enter submits the text and esc dismisses the prompt.
+// The returned bool reports whether the prompt has closed.
+func (v *PromptView) Update(msg tea.Msg) (tea.Cmd, bool) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "enter":
+			v.input.Blur()
+			text := v.input.Value()
+			onSubmit := v.action.OnSubmit
+			return func() tea.Msg { return onSubmit(text) }, true
+		case "esc":
+			v.input.Blur()
+			return nil, true
+		}
+	}
+
+	var cmd tea.Cmd
+	v.input, cmd = v.input.Update(msg)
+	return cmd, false
+}
+
+// View renders the prompt label and text input, sized to width.
+func (v *PromptView) View(width int) string {
+	label := styles.ConfirmPrompt.Render(v.action.Prompt)
+	v.input.Width = max(width-lipgloss.Width(v.input.Prompt), 20)
+	return lipgloss.JoinVertical(lipgloss.Left,
+		label,
+		v.input.View(),
+		RenderHelpBar([]KeyBinding{
+			{Key: "enter", Description: "save"},
+			{Key: "esc", Description: "dismiss"},
+		}),
+	)
+}
@@ -0,0 +1,123 @@
+package views
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+	"github.com/Broderick-Westrope/flower/internal/tui/msgs"
+	"github.com/Broderick-Westrope/flower/internal/tui/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// SessionView displays a single completed work block with its flow and break
+// intervals and any notes.
+type SessionView struct {
+	session flowtime.CompletedSession
+}
+
+// NewSessionView creates an empty SessionView.
+func NewSessionView() *SessionView {
+	return &SessionView{}
+}
+
+// SetSession updates the session displayed by this view.
+func (v *SessionView) SetSession(s flowtime.CompletedSession) {
+	v.session = s
+}
+
+// SessionID returns the ID of the displayed session.
+func (v *SessionView) SessionID() string {
+	return v.session.ID
+}
+
+// Update handles the undo key and returning to the log.
+func (v *SessionView) Update(msg tea.Msg) tea.Cmd {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "u":
+			return func() tea.Msg { return msgs.UndoMsg{} }
+		case "esc":
+			return func() tea.Msg { return msgs.ShowLogMsg{} }
+		case "q":
+			return tea.Quit
+		}
+	}
+	return nil
+}
+
+// View renders the session summary and its intervals table.
+func (v *SessionView) View() string {
+	s := v.session
+	now := time.Now()
+
+	breakStr := "-"
+	if s.BreakDuration != nil {
+		breakStr = flowtime.FormatDuration(*s.BreakDuration)
+	}
+	taskLine := styles.TaskName.Render(s.Task)
+	if labels := flowtime.FormatLabels(s.Project, s.Tags); labels != "" {
+		taskLine += " " + styles.HelpBar.Render(labels)
+	}
+	summary := lipgloss.JoinVertical(lipgloss.Left,
+		taskLine,
+		"Completed "+flowtime.FormatHumanDateTime(s.CompletedAt, now),
+		"Flow "+flowtime.FormatDuration(s.FlowDuration)+" · Break "+breakStr,
+		"Interruptions "+flowtime.FormatInterruptions(s.Interruptions),
+	)
+
+	rows := make([][]string, len(s.Intervals))
+	for i, iv := range s.Intervals {
+		rows[i] = []string{
+			strconv.Itoa(i + 1),
+			string(iv.Kind),
+			iv.Start.Format("15:04:05"),
+			iv.End.Format("15:04:05"),
+			flowtime.FormatDuration(iv.Duration()),
+		}
+	}
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers("#", "KIND", "START", "END", "DURATION").
+		Rows(rows...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return styles.TableHeader
+			}
+			return lipgloss.NewStyle()
+		})
+
+	title := styles.Title.Render("🔍 Session " + s.ID)
+	tableRendered := t.Render()
+	notes := make([]string, 0, len(s.Notes)+1)
+	if len(s.Notes) > 0 {
+		notes = append(notes, styles.TableHeader.Render("Notes"))
+	}
+	for _, n := range s.Notes {
+		notes = append(notes, styles.HelpBar.Render(n.At.Format("15:04"))+" "+n.Text)
+	}
+	notesRendered := lipgloss.JoinVertical(lipgloss.Left, notes...)
+	helpBar := RenderHelpBar([]KeyBinding{
+		{Key: "esc", Description: "back"},
+		{Key: "u", Description: "undo"},
+		{Key: "q", Description: "quit"},
+	})
+
+	contentWidth := max(
+		lipgloss.Width(title),
+		lipgloss.Width(summary),
+		lipgloss.Width(tableRendered),
+		lipgloss.Width(helpBar),
+	)
+
+	sections := []string{title, "", summary, "", tableRendered}
+	if len(s.Notes) > 0 {
+		// Long notes wrap to the width of the rest of the view.
+		sections = append(sections, "", lipgloss.NewStyle().Width(contentWidth).Render(notesRendered))
+	}
+	sections = append(sections, "", styles.Separator(contentWidth), helpBar)
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
@@ -0,0 +1,182 @@
+package views
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Broderick-Westrope/flower/internal/flowtime"
+	"github.com/Broderick-Westrope/flower/internal/paginate"
+	"github.com/Broderick-Westrope/flower/internal/tui/msgs"
+	"github.com/Broderick-Westrope/flower/internal/tui/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// TrashView displays a paginated table of deleted sessions (newest first)
+// with cursor-based row selection for restoring.
+type TrashView struct {
+	sessions []flowtime.CompletedSession
+	page     int
+	pageSize int
+	cursor   int // selected row on the current page (0-indexed)
+}
+
+// NewTrashView creates a TrashView with the given page size.
+func NewTrashView(pageSize int) *TrashView {
+	return &TrashView{
+		page:     1,
+		pageSize: pageSize,
+	}
+}
+
+// SetSessions updates the deleted session data and resets to page 1 with cursor at top.
+func (v *TrashView) SetSessions(sessions []flowtime.CompletedSession) {
+	v.sessions = sessions
+	v.page = 1
+	v.cursor = 0
+}
+
+// totalPages returns the number of pages needed for all sessions.
+func (v *TrashView) totalPages() int {
+	if len(v.sessions) == 0 {
+		return 1
+	}
+	pages := len(v.sessions) / v.pageSize
+	if len(v.sessions)%v.pageSize != 0 {
+		pages++
+	}
+	return pages
+}
+
+// pageLen returns the number of items on the current page.
+func (v *TrashView) pageLen() int {
+	return len(paginate.ReversePaginate(v.sessions, v.page, v.pageSize))
+}
+
+// selected returns the session under the cursor.
+func (v *TrashView) selected() flowtime.CompletedSession {
+	return v.sessions[len(v.sessions)-(v.page-1)*v.pageSize-v.cursor-1]
+}
+
+// Update handles cursor movement, pagination, and the restore and undo keys.
+func (v *TrashView) Update(msg tea.Msg) tea.Cmd {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "up", "k":
+			if v.cursor > 0 {
+				v.cursor--
+			} else if v.page > 1 {
+				v.page--
+				v.cursor = v.pageLen() - 1
+			}
+		case "down", "j":
+			if v.cursor < v.pageLen()-1 {
+				v.cursor++
+			} else if v.page < v.totalPages() {
+				v.page++
+				v.cursor = 0
+			}
+		case "r":
+			if len(v.sessions) > 0 {
+				id := v.selected().ID
+				return func() tea.Msg { return msgs.RestoreSessionMsg{ID: id} }
+			}
+		case "u":
+			return func() tea.Msg { return msgs.UndoMsg{} }
+		case "esc":
+			return func() tea.Msg { return msgs.ShowLogMsg{} }
+		case "q":
+			return tea.Quit
+		}
+	}
+	return nil
+}
+
+// View renders the deleted sessions table with cursor highlighting.
+func (v *TrashView) View() string {
+	title := styles.Title.Render("🗑 Trash")
+
+	if len(v.sessions) == 0 {
+		emptyMsg := "No deleted sessions."
+		helpBar := RenderHelpBar([]KeyBinding{
+			{Key: "esc", Description: "back"},
+			{Key: "u", Description: "undo"},
+			{Key: "q", Description: "quit"},
+		})
+		contentWidth := max(
+			lipgloss.Width(title),
+			lipgloss.Width(emptyMsg),
+			lipgloss.Width(helpBar),
+		)
+		return lipgloss.JoinVertical(lipgloss.Left,
+			title,
+			"",
+			emptyMsg,
+			"",
+			styles.Separator(contentWidth),
+			helpBar,
+		)
+	}
+
+	now := time.Now()
+	page := paginate.ReversePaginate(v.sessions, v.page, v.pageSize)
+
+	// Clamp cursor if sessions were restored and page shrunk.
+	if v.cursor >= len(page) {
+		v.cursor = max(0, len(page)-1)
+	}
+
+	rows := make([][]string, len(page))
+	for i, s := range page {
+		rows[i] = []string{
+			flowtime.FormatHumanDateTime(s.CompletedAt, now),
+			s.Task,
+			flowtime.FormatDuration(s.FlowDuration),
+			flowtime.FormatHumanDateTime(*s.DeletedAt, now),
+		}
+	}
+
+	selectedRow := v.cursor
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers("COMPLETED AT", "TASK", "FLOW", "DELETED AT").
+		Rows(rows...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return styles.TableHeader
+			}
+			if row == selectedRow {
+				return styles.SelectedRow
+			}
+			return lipgloss.NewStyle()
+		})
+
+	pageInfo := fmt.Sprintf("Page %d of %d", v.page, v.totalPages())
+	tableRendered := t.Render()
+	helpBar := RenderHelpBar([]KeyBinding{
+		{Key: "esc", Description: "back"},
+		{Key: "j/k", Description: "navigate"},
+		{Key: "r", Description: "restore"},
+		{Key: "u", Description: "undo"},
+		{Key: "q", Description: "quit"},
+	})
+
+	contentWidth := max(
+		lipgloss.Width(title),
+		lipgloss.Width(tableRendered),
+		lipgloss.Width(helpBar),
+	)
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		"",
+		tableRendered,
+		"",
+		pageInfo,
+		"",
+		styles.Separator(contentWidth),
+		helpBar,
+	)
+}
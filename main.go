@@ -2,9 +2,10 @@ package main
 
 import (
 	"fmt"
-	"os"
+	"time"
 
 	"github.com/Broderick-Westrope/flower/internal/cli"
+	"github.com/Broderick-Westrope/flower/internal/config"
 	"github.com/Broderick-Westrope/flower/internal/flowtime"
 	"github.com/Broderick-Westrope/flower/internal/storage"
 	"github.com/Broderick-Westrope/flower/internal/tui"
@@ -13,28 +14,37 @@ import (
 )
 
 func main() {
-	clock := flowtime.RealClock{}
-	jsonStore := storage.NewJSONStore(clock)
-
-	if len(os.Args) == 1 {
-		if err := runTUI(jsonStore); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-		return
-	}
-
-	ctx := &cli.Context{
-		Store:       jsonStore,
-		RunTUI:      runTUI,
-		LocateStore: jsonStore.GetFilePath,
-	}
 	var c cli.CLI
 	kongCtx := kong.Parse(&c,
 		kong.Name("flower"),
 		kong.Description("A minimal Flowtime Technique CLI tool"),
 	)
-	err := kongCtx.Run(ctx)
+
+	cfg, err := config.Load()
+	kongCtx.FatalIfErrorf(err)
+	breakPolicy, err := cfg.Break.BreakPolicy()
+	kongCtx.FatalIfErrorf(err)
+	longBreak, err := cfg.Break.LongBreakRule()
+	kongCtx.FatalIfErrorf(err)
+
+	clock := flowtime.RealClock{}
+	store, err := storage.New(c.Store, clock, storage.Options{
+		Profile:     c.Profile,
+		StateFile:   c.StateFile,
+		Retention:   time.Duration(cfg.Retention),
+		BreakPolicy: breakPolicy,
+		LongBreak:   longBreak,
+	})
+	kongCtx.FatalIfErrorf(err)
+
+	ctx := &cli.Context{
+		Profile:     c.Profile,
+		Output:      c.Output,
+		Store:       store,
+		RunTUI:      runTUI,
+		LocateStore: store.GetFilePath,
+	}
+	err = kongCtx.Run(ctx)
 	kongCtx.FatalIfErrorf(err)
 }
 